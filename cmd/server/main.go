@@ -9,11 +9,14 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata" // 内置时区数据，保证精简镜像中也能加载活码时区
 	"wechat-active-qrcode/internal/api"
 	"wechat-active-qrcode/internal/auth"
 	"wechat-active-qrcode/internal/config"
 	"wechat-active-qrcode/internal/database"
 	"wechat-active-qrcode/internal/services"
+	"wechat-active-qrcode/pkg/geoip"
+	"wechat-active-qrcode/pkg/notify"
 	"wechat-active-qrcode/pkg/qrcode"
 
 	"github.com/gin-gonic/gin"
@@ -43,11 +46,50 @@ func main() {
 	}
 	log.Println("Database initialized successfully")
 
+	// 后台回填旧扫描记录的User-Agent解析字段
+	go func() {
+		count, err := database.BackfillScanRecordUserAgents(db)
+		if err != nil {
+			log.Printf("Failed to backfill scan record user agents: %v", err)
+			return
+		}
+		if count > 0 {
+			log.Printf("Backfilled user agent fields for %d scan records", count)
+		}
+	}()
+
 	// 初始化二维码生成器
 	log.Println("Initializing QR code generator...")
 	qrGenerator := qrcode.NewGenerator("./data/qrcodes")
+	qrDefaults, err := qrcode.DefaultOptions().Override(cfg.QRCode.Size, cfg.QRCode.Margin, cfg.QRCode.Level)
+	if err == nil {
+		err = qrGenerator.Configure(qrDefaults, cfg.QRCode.CacheSize)
+	}
+	if err != nil {
+		log.Fatalf("Invalid qrcode config: %v", err)
+	}
+	if cfg.QRCode.FontPath != "" {
+		font, err := qrcode.LoadFontFile(cfg.QRCode.FontPath)
+		if err != nil {
+			log.Fatalf("Failed to load qrcode font %s: %v", cfg.QRCode.FontPath, err)
+		}
+		qrGenerator.SetFont(font)
+	}
 	log.Println("QR code generator initialized")
 
+	// 初始化IP地区解析器
+	log.Println("Initializing GeoIP resolver...")
+	regionResolver, err := geoip.NewResolver(cfg.GeoIP.Provider, cfg.GeoIP.DBPath, cfg.GeoIP.Language)
+	if err != nil {
+		log.Printf("Failed to initialize GeoIP resolver, region detection disabled: %v", err)
+		regionResolver = geoip.NopResolver{}
+	}
+	defer regionResolver.Close()
+	log.Printf("GeoIP resolver initialized: provider=%s", cfg.GeoIP.Provider)
+	if cfg.GeoIP.Provider == "ip2region" {
+		log.Println("ip2region only supports IPv4, scans from IPv6 addresses will have no region")
+	}
+
 	// 初始化JWT服务
 	log.Println("Initializing JWT service...")
 	jwtService := auth.NewJWTService(cfg.JWT.Secret, cfg.JWT.Expire)
@@ -56,11 +98,32 @@ func main() {
 	// 初始化服务
 	log.Println("Initializing services...")
 	qrCodeService := services.NewQRCodeService(db, qrGenerator)
-	activeQRCodeService := services.NewActiveQRCodeService(db, qrGenerator, cfg)
+	activeQRCodeService := services.NewActiveQRCodeService(db, qrGenerator, cfg, regionResolver)
 	statisticsService := services.NewStatisticsService(db)
 	authService := services.NewAuthService(db, jwtService)
 	log.Println("Services initialized")
 
+	// 初始化通知渠道（日志 + 可选Webhook）
+	notifiers := notify.MultiNotifier{notify.LogNotifier{}}
+	if cfg.Notification.WebhookURL != "" {
+		webhook, err := notify.NewWebhookNotifier(cfg.Notification.WebhookURL, cfg.Notification.WebhookFormat)
+		if err != nil {
+			log.Printf("Failed to initialize webhook notifier: %v", err)
+		} else {
+			notifiers = append(notifiers, webhook)
+		}
+	}
+
+	// 启动后台任务
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	services.NewExpiryMonitor(activeQRCodeService, notifiers, cfg.Expiry).Start(jobCtx)
+	services.NewExperimentMonitor(activeQRCodeService, cfg.Experiment).Start(jobCtx)
+	if cfg.HealthCheck.Enabled {
+		services.NewHealthMonitor(activeQRCodeService, notifiers, cfg.HealthCheck, nil).Start(jobCtx)
+	}
+	log.Println("Background jobs started")
+
 	// 初始化路由
 	log.Println("Setting up routes...")
 	router := api.NewRouter(qrCodeService, activeQRCodeService, statisticsService, authService, cfg)
@@ -3,6 +3,7 @@ package handlers
 import (
 	"fmt"
 	"net/http"
+	"os"
 	"strconv"
 	"wechat-active-qrcode/internal/models"
 	"wechat-active-qrcode/internal/services"
@@ -11,6 +12,12 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// 访客ID Cookie，用于粘性分配
+const (
+	visitorCookieName   = "aqr_vid"
+	visitorCookieMaxAge = 365 * 24 * 3600
+)
+
 type ActiveQRCodeHandler struct {
 	activeQRCodeService *services.ActiveQRCodeService
 }
@@ -66,6 +73,13 @@ func (h *ActiveQRCodeHandler) CreateActiveQRCode(c *gin.Context) {
 
 	activeQRCode, err := h.activeQRCodeService.CreateActiveQRCode(&req)
 	if err != nil {
+		if appErr, ok := err.(*models.AppError); ok {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: appErr.Message,
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success: false,
 			Message: err.Error(),
@@ -80,6 +94,14 @@ func (h *ActiveQRCodeHandler) CreateActiveQRCode(c *gin.Context) {
 	})
 }
 
+// ListStrategies 获取可用的切换规则
+func (h *ActiveQRCodeHandler) ListStrategies(c *gin.Context) {
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    services.ListStrategies(),
+	})
+}
+
 // GetActiveQRCode 获取单个活码
 func (h *ActiveQRCodeHandler) GetActiveQRCode(c *gin.Context) {
 	idParam := c.Param("id")
@@ -130,6 +152,13 @@ func (h *ActiveQRCodeHandler) UpdateActiveQRCode(c *gin.Context) {
 
 	activeQRCode, err := h.activeQRCodeService.UpdateActiveQRCode(uint(id), &req)
 	if err != nil {
+		if appErr, ok := err.(*models.AppError); ok {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: appErr.Message,
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success: false,
 			Message: err.Error(),
@@ -172,7 +201,19 @@ func (h *ActiveQRCodeHandler) DeleteActiveQRCode(c *gin.Context) {
 }
 
 // GetActiveQRCodeImage 获取活码二维码图片
+//
+// 可选参数：format（png、svg、pdf、eps）、size（像素）、margin（静区模块数）、level（纠错等级 L/M/Q/H）
+// 海报边框：frame=true 时加边框并绘制活码名称，caption（说明文字）、position（bottom/top）、padding（留白像素）未指定时使用样式模板
 func (h *ActiveQRCodeHandler) GetActiveQRCodeImage(c *gin.Context) {
+	h.getActiveQRCodeImage(c, false)
+}
+
+// GetPublicActiveQRCodeImage 获取活码二维码图片（公开），只接受 format、frame、position 参数，其他参数使用保存的设置
+func (h *ActiveQRCodeHandler) GetPublicActiveQRCodeImage(c *gin.Context) {
+	h.getActiveQRCodeImage(c, true)
+}
+
+func (h *ActiveQRCodeHandler) getActiveQRCodeImage(c *gin.Context, public bool) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
@@ -183,8 +224,27 @@ func (h *ActiveQRCodeHandler) GetActiveQRCodeImage(c *gin.Context) {
 		return
 	}
 
-	imageData, err := h.activeQRCodeService.GetActiveQRCodeImage(uint(id))
+	var req models.QRImageRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid image parameters",
+		})
+		return
+	}
+	if public {
+		restrictPublicImageRequest(&req)
+	}
+
+	imageData, format, err := h.activeQRCodeService.GetActiveQRCodeImage(uint(id), &req)
 	if err != nil {
+		if appErr, ok := err.(*models.AppError); ok {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: appErr.Message,
+			})
+			return
+		}
 		c.JSON(http.StatusNotFound, models.APIResponse{
 			Success: false,
 			Message: "QR code image not found",
@@ -196,8 +256,18 @@ func (h *ActiveQRCodeHandler) GetActiveQRCodeImage(c *gin.Context) {
 	c.Header("Cache-Control", "no-cache, no-store, must-revalidate")
 	c.Header("Pragma", "no-cache")
 	c.Header("Expires", "0")
-	c.Header("Content-Type", "image/png")
-	c.Data(http.StatusOK, "image/png", imageData)
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="active_%d.%s"`, id, format.Extension()))
+	c.Data(http.StatusOK, format.ContentType(), imageData)
+}
+
+// restrictPublicImageRequest 公开接口只接受取值有限的格式和边框参数，尺寸、静区、纠错等级使用保存的设置，
+// 避免通过不断变化的参数绕过缓存消耗CPU；忽略说明文字和留白，避免被用来在本站域名下生成任意文字的海报
+func restrictPublicImageRequest(req *models.QRImageRequest) {
+	req.Size = 0
+	req.Margin = nil
+	req.Level = ""
+	req.Caption = ""
+	req.Padding = 0
 }
 
 // AddStaticQRCode 为活码添加静态码
@@ -223,6 +293,13 @@ func (h *ActiveQRCodeHandler) AddStaticQRCode(c *gin.Context) {
 
 	staticQRCode, err := h.activeQRCodeService.AddStaticQRCode(uint(id), &req)
 	if err != nil {
+		if appErr, ok := err.(*models.AppError); ok {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: appErr.Message,
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success: false,
 			Message: err.Error(),
@@ -247,125 +324,20 @@ func (h *ActiveQRCodeHandler) RedirectByShortCode(c *gin.Context) {
 	c.Header("Expires", "0")
 
 	// 获取用户信息
-	userAgent := c.GetHeader("User-Agent")
-	ipAddress := c.ClientIP()
-	region := "" // 可以根据IP获取地区信息
+	scan := &services.ScanContext{
+		UserAgent:      c.GetHeader("User-Agent"),
+		AcceptLanguage: c.GetHeader("Accept-Language"),
+		IPAddress:      c.ClientIP(),
+		VisitorID:      visitorID(c),
+		Query:          c.Request.URL.Query(),
+		Cookies:        requestCookies(c),
+	}
 
-	targetURL, err := h.activeQRCodeService.GetTargetURL(shortCode, userAgent, ipAddress, region)
+	result, err := h.activeQRCodeService.ResolveScan(shortCode, scan)
 	if err != nil {
 		// 检查是否为自定义的QRCodeError
-		if _, ok := err.(*services.QRCodeError); ok {
-			// 对于二维码相关错误，返回友好的HTML页面
-			c.Header("Content-Type", "text/html; charset=utf-8")
-			c.Status(http.StatusOK) // 返回200状态码
-
-			// 读取错误页面模板
-			errorHTML := `<!DOCTYPE html>
-<html lang="zh-CN">
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>二维码无效 - 活码管理系统</title>
-    <link href="https://cdn.jsdelivr.net/npm/bootstrap@5.1.3/dist/css/bootstrap.min.css" rel="stylesheet">
-    <link href="https://cdn.jsdelivr.net/npm/bootstrap-icons@1.8.1/font/bootstrap-icons.css" rel="stylesheet">
-    <style>
-        body {
-            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
-            min-height: 100vh;
-            display: flex;
-            align-items: center;
-            justify-content: center;
-            font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif;
-        }
-        .error-container {
-            background: white;
-            border-radius: 20px;
-            box-shadow: 0 20px 60px rgba(0, 0, 0, 0.1);
-            padding: 40px;
-            text-align: center;
-            max-width: 500px;
-            width: 90%;
-        }
-        .error-icon {
-            font-size: 4rem;
-            color: #dc3545;
-            margin-bottom: 20px;
-        }
-        .error-title {
-            color: #2c3e50;
-            font-size: 1.8rem;
-            font-weight: 600;
-            margin-bottom: 15px;
-        }
-        .error-message {
-            color: #6c757d;
-            font-size: 1.1rem;
-            line-height: 1.6;
-            margin-bottom: 30px;
-        }
-        .contact-info {
-            background: #f8f9fa;
-            border-radius: 10px;
-            padding: 20px;
-            margin-top: 20px;
-        }
-        .contact-title {
-            color: #495057;
-            font-weight: 600;
-            margin-bottom: 10px;
-        }
-        .contact-text {
-            color: #6c757d;
-            font-size: 0.95rem;
-        }
-        @media (max-width: 576px) {
-            .error-container {
-                padding: 30px 20px;
-            }
-            .error-title {
-                font-size: 1.5rem;
-            }
-            .error-message {
-                font-size: 1rem;
-            }
-        }
-    </style>
-</head>
-<body>
-    <div class="error-container">
-        <div class="error-icon">
-            <i class="bi bi-exclamation-triangle-fill"></i>
-        </div>
-        
-        <h1 class="error-title">二维码已过期或不存在</h1>
-        
-        <p class="error-message">
-            抱歉，您扫描的二维码可能已过期、被禁用或不存在。<br>
-            请确认二维码是否有效，或联系管理员获取帮助。
-        </p>
-        
-        <div class="contact-info">
-            <div class="contact-title">
-                <i class="bi bi-person-lines-fill me-2"></i>
-                需要帮助？
-            </div>
-            <div class="contact-text">
-                如有疑问，请联系系统管理员<br>
-                或重新获取有效的二维码
-            </div>
-        </div>
-        
-        <div class="mt-4">
-            <small class="text-muted">
-                <i class="bi bi-shield-check me-1"></i>
-                活码管理系统 - 安全可靠
-            </small>
-        </div>
-    </div>
-</body>
-</html>`
-
-			c.String(http.StatusOK, errorHTML)
+		if qrErr, ok := err.(*services.QRCodeError); ok {
+			h.renderQRCodeError(c, qrErr)
 			return
 		}
 
@@ -377,7 +349,114 @@ func (h *ActiveQRCodeHandler) RedirectByShortCode(c *gin.Context) {
 		return
 	}
 
-	c.Redirect(http.StatusMovedPermanently, targetURL)
+	// 仅粘性活码需要访客ID Cookie
+	setVisitorID(c, result.VisitorID)
+
+	// 图片类型显示落地页，由用户长按识别二维码
+	if result.StaticQR.IsImage() {
+		h.renderLandingPage(c, result)
+		return
+	}
+
+	// 目标链接会被微信拦截时，引导用户在系统浏览器中打开
+	if result.OpenInBrowser {
+		renderPage(c, http.StatusOK, "open_in_browser.html", RedirectPageData{
+			Title:     result.ActiveQR.Name,
+			TargetURL: result.TargetURL,
+		})
+		return
+	}
+
+	h.redirect(c, result.RedirectMode, result.TargetURL)
+}
+
+// redirect 按活码配置的跳转方式跳转到目标链接
+func (h *ActiveQRCodeHandler) redirect(c *gin.Context, mode, targetURL string) {
+	switch mode {
+	case services.RedirectModePermanent:
+		c.Redirect(http.StatusMovedPermanently, targetURL)
+	case services.RedirectModeTemporary:
+		c.Redirect(http.StatusTemporaryRedirect, targetURL)
+	case services.RedirectModeHTML:
+		renderPage(c, http.StatusOK, "redirect.html", RedirectPageData{TargetURL: targetURL})
+	default:
+		c.Redirect(http.StatusFound, targetURL)
+	}
+}
+
+// renderLandingPage 渲染图片类型静态码的落地页
+func (h *ActiveQRCodeHandler) renderLandingPage(c *gin.Context, result *services.ScanResult) {
+	staticQR := result.StaticQR
+	data := LandingPageData{
+		Title:    staticQR.LandingTitle,
+		Text:     staticQR.LandingText,
+		Hint:     staticQR.LandingHint,
+		ImageURL: fmt.Sprintf("/api/public/static-qrcodes/%d/image?v=%d", staticQR.ID, staticQR.UpdatedAt.Unix()),
+	}
+	if data.Title == "" {
+		data.Title = result.ActiveQR.Name
+	}
+	if data.Text == "" {
+		data.Text = services.DefaultLandingText
+	}
+	if data.Hint == "" {
+		data.Hint = services.DefaultLandingHint
+	}
+
+	renderPage(c, http.StatusOK, "qr_landing.html", data)
+}
+
+// renderQRCodeError 按活码配置的兜底行为处理二维码相关错误，默认返回友好的HTML页面
+func (h *ActiveQRCodeHandler) renderQRCodeError(c *gin.Context, qrErr *services.QRCodeError) {
+	fallback := qrErr.Fallback
+	if fallback == nil {
+		fallback = &services.FallbackAction{Action: services.FallbackActionPage}
+	}
+
+	data := ErrorPageData{
+		Title:   fallback.Title,
+		Message: fallback.Message,
+	}
+
+	switch fallback.Action {
+	case services.FallbackActionRedirect:
+		c.Redirect(http.StatusFound, fallback.URL)
+	case services.FallbackActionNotFound:
+		renderPage(c, http.StatusNotFound, "qr_error.html", data)
+	case services.FallbackActionGone:
+		renderPage(c, http.StatusGone, "qr_error.html", data)
+	default:
+		renderPage(c, http.StatusOK, "qr_error.html", data)
+	}
+}
+
+// requestCookies 读取请求携带的Cookie，同名时取第一个
+func requestCookies(c *gin.Context) map[string]string {
+	cookies := make(map[string]string)
+	for _, cookie := range c.Request.Cookies() {
+		if _, exists := cookies[cookie.Name]; !exists {
+			cookies[cookie.Name] = cookie.Value
+		}
+	}
+	return cookies
+}
+
+// visitorID 读取访客ID Cookie，用于粘性分配
+func visitorID(c *gin.Context) string {
+	visitorID, _ := c.Cookie(visitorCookieName)
+	return visitorID
+}
+
+// setVisitorID 写入粘性活码分配的访客ID Cookie，已是同一ID时不重复写入
+func setVisitorID(c *gin.Context, visitorID string) {
+	if visitorID == "" {
+		return
+	}
+	if current, err := c.Cookie(visitorCookieName); err == nil && current == visitorID {
+		return
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(visitorCookieName, visitorID, visitorCookieMaxAge, "/r/", "", false, true)
 }
 
 // ListStaticQRCodes 获取静态码列表
@@ -448,6 +527,13 @@ func (h *ActiveQRCodeHandler) CreateStaticQRCode(c *gin.Context) {
 	// 调用服务层创建静态码
 	staticQR, err := h.activeQRCodeService.AddStaticQRCode(req.ActiveQRCodeID, &req)
 	if err != nil {
+		if appErr, ok := err.(*models.AppError); ok {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: "创建失败: " + appErr.Message,
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success: false,
 			Message: "创建失败: " + err.Error(),
@@ -462,6 +548,116 @@ func (h *ActiveQRCodeHandler) CreateStaticQRCode(c *gin.Context) {
 	})
 }
 
+// SimulateScan 模拟扫码，返回每个静态码的筛选结果、切换规则和选中概率，不写入扫描记录
+func (h *ActiveQRCodeHandler) SimulateScan(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid ID parameter",
+		})
+		return
+	}
+
+	var req models.SimulateScanRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: "请求参数错误: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	result, err := h.activeQRCodeService.SimulateScan(uint(id), &req)
+	if err != nil {
+		if qrErr, ok := err.(*services.QRCodeError); ok {
+			c.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Message: qrErr.Message,
+			})
+			return
+		}
+		if appErr, ok := err.(*models.AppError); ok {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: appErr.Message,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "模拟失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    result,
+	})
+}
+
+// ListExpiringStaticQRCodes 获取即将过期的静态码（如7天有效期的微信群二维码）
+func (h *ActiveQRCodeHandler) ListExpiringStaticQRCodes(c *gin.Context) {
+	hours := 0
+	if hoursStr := c.Query("hours"); hoursStr != "" {
+		if n, err := strconv.Atoi(hoursStr); err == nil && n > 0 {
+			hours = n
+		}
+	}
+
+	staticQRs, err := h.activeQRCodeService.ListExpiringStaticQRCodes(hours)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "查询失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "获取成功",
+		Data:    staticQRs,
+	})
+}
+
+// ListTargetHealthChecks 获取静态码目标链接的健康检查记录
+func (h *ActiveQRCodeHandler) ListTargetHealthChecks(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "无效的ID",
+		})
+		return
+	}
+
+	limit := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if n, err := strconv.Atoi(limitStr); err == nil && n > 0 && n <= 1000 {
+			limit = n
+		}
+	}
+
+	checks, err := h.activeQRCodeService.ListTargetHealthChecks(uint(id), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "查询失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "获取成功",
+		Data:    checks,
+	})
+}
+
 // GetStaticQRCode 获取静态码详情
 func (h *ActiveQRCodeHandler) GetStaticQRCode(c *gin.Context) {
 	idStr := c.Param("id")
@@ -519,44 +715,19 @@ func (h *ActiveQRCodeHandler) UpdateStaticQRCode(c *gin.Context) {
 		return
 	}
 
-	db := h.activeQRCodeService.GetDB()
-	var staticQR models.StaticQRCode
-	if err := db.First(&staticQR, uint(id)).Error; err != nil {
-		if err.Error() == "record not found" {
-			c.JSON(http.StatusNotFound, models.APIResponse{
+	staticQR, err := h.activeQRCodeService.UpdateStaticQRCode(uint(id), &req)
+	if err != nil {
+		if appErr, ok := err.(*models.AppError); ok {
+			status := http.StatusBadRequest
+			if appErr.Code == "STATIC_QR_NOT_FOUND" {
+				status = http.StatusNotFound
+			}
+			c.JSON(status, models.APIResponse{
 				Success: false,
-				Message: "静态码不存在",
-			})
-		} else {
-			c.JSON(http.StatusInternalServerError, models.APIResponse{
-				Success: false,
-				Message: "查询失败",
+				Message: appErr.Message,
 			})
+			return
 		}
-		return
-	}
-
-	// 更新字段
-	if req.Name != nil {
-		staticQR.Name = *req.Name
-	}
-	if req.TargetURL != nil {
-		staticQR.TargetURL = *req.TargetURL
-	}
-	if req.Weight != nil {
-		staticQR.Weight = *req.Weight
-	}
-	if req.Status != nil {
-		staticQR.Status = *req.Status
-	}
-	if req.StartTime != nil {
-		staticQR.StartTime = req.StartTime
-	}
-	if req.EndTime != nil {
-		staticQR.EndTime = req.EndTime
-	}
-
-	if err := db.Save(&staticQR).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success: false,
 			Message: "更新失败",
@@ -564,15 +735,6 @@ func (h *ActiveQRCodeHandler) UpdateStaticQRCode(c *gin.Context) {
 		return
 	}
 
-	// 重新加载关联数据
-	if err := db.Preload("ActiveQRCode").First(&staticQR, staticQR.ID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Message: "重新加载失败",
-		})
-		return
-	}
-
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
 		Message: "更新成功",
@@ -617,6 +779,14 @@ func (h *ActiveQRCodeHandler) DeleteStaticQRCode(c *gin.Context) {
 		return
 	}
 
+	// 删除上传的图片
+	if staticQR.ImagePath != "" {
+		os.Remove(staticQR.ImagePath)
+	}
+
+	// 删除健康检查记录
+	db.Where("static_qr_code_id = ?", staticQR.ID).Delete(&models.TargetHealthCheck{})
+
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
 		Message: "删除成功",
@@ -731,6 +901,156 @@ func (h *ActiveQRCodeHandler) ToggleStaticQRStatus(c *gin.Context) {
 	})
 }
 
+// UploadStaticQRCodeImage 上传图片类型静态码的二维码图片
+func (h *ActiveQRCodeHandler) UploadStaticQRCodeImage(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "无效的ID",
+		})
+		return
+	}
+
+	_, header, err := c.Request.FormFile("image")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "请选择要上传的二维码图片",
+		})
+		return
+	}
+
+	staticQR, err := h.activeQRCodeService.UploadStaticQRCodeImage(uint(id), header)
+	if err != nil {
+		if appErr, ok := err.(*models.AppError); ok {
+			status := http.StatusBadRequest
+			if appErr.Code == "STATIC_QR_NOT_FOUND" {
+				status = http.StatusNotFound
+			}
+			c.JSON(status, models.APIResponse{
+				Success: false,
+				Message: appErr.Message,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "上传失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "上传成功",
+		Data:    staticQR,
+	})
+}
+
+// UploadActiveQRLogo 上传活码二维码中心的Logo
+func (h *ActiveQRCodeHandler) UploadActiveQRLogo(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "无效的ID",
+		})
+		return
+	}
+
+	_, header, err := c.Request.FormFile("logo")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "请选择要上传的Logo图片",
+		})
+		return
+	}
+
+	activeQR, err := h.activeQRCodeService.UploadActiveQRLogo(uint(id), header)
+	if err != nil {
+		respondLogoError(c, err, "上传失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "上传成功",
+		Data:    activeQR,
+	})
+}
+
+// DeleteActiveQRLogo 删除活码二维码中心的Logo
+func (h *ActiveQRCodeHandler) DeleteActiveQRLogo(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "无效的ID",
+		})
+		return
+	}
+
+	activeQR, err := h.activeQRCodeService.DeleteActiveQRLogo(uint(id))
+	if err != nil {
+		respondLogoError(c, err, "删除失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "删除成功",
+		Data:    activeQR,
+	})
+}
+
+// respondLogoError 返回Logo操作的错误响应
+func respondLogoError(c *gin.Context, err error, message string) {
+	if appErr, ok := err.(*models.AppError); ok {
+		status := http.StatusBadRequest
+		if appErr.Code == "ACTIVE_QR_NOT_FOUND" {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, models.APIResponse{
+			Success: false,
+			Message: appErr.Message,
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, models.APIResponse{
+		Success: false,
+		Message: message,
+	})
+}
+
+// GetStaticQRCodeImage 获取图片类型静态码的图片
+func (h *ActiveQRCodeHandler) GetStaticQRCodeImage(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "无效的ID",
+		})
+		return
+	}
+
+	imageData, err := h.activeQRCodeService.GetStaticQRCodeImage(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: "图片不存在",
+		})
+		return
+	}
+
+	c.Data(http.StatusOK, http.DetectContentType(imageData), imageData)
+}
+
 // ParseQRCode 解析上传的二维码图片
 func (h *ActiveQRCodeHandler) ParseQRCode(c *gin.Context) {
 	// 获取上传的文件
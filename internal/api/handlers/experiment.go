@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"wechat-active-qrcode/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListExperiments 获取活码的A/B实验列表
+func (h *ActiveQRCodeHandler) ListExperiments(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "无效的ID",
+		})
+		return
+	}
+
+	experiments, err := h.activeQRCodeService.ListExperiments(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "查询失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "获取成功",
+		Data:    experiments,
+	})
+}
+
+// CreateExperiment 在活码上创建A/B实验
+func (h *ActiveQRCodeHandler) CreateExperiment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "无效的ID",
+		})
+		return
+	}
+
+	var req models.ExperimentCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	experiment, err := h.activeQRCodeService.CreateExperiment(uint(id), &req)
+	if err != nil {
+		respondExperimentError(c, err, "创建实验失败")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "实验已开始",
+		Data:    experiment,
+	})
+}
+
+// GetExperiment 获取实验详情
+func (h *ActiveQRCodeHandler) GetExperiment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "无效的ID",
+		})
+		return
+	}
+
+	experiment, err := h.activeQRCodeService.GetExperiment(uint(id))
+	if err != nil {
+		respondExperimentError(c, err, "查询失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "获取成功",
+		Data:    experiment,
+	})
+}
+
+// StopExperiment 手动停止实验
+func (h *ActiveQRCodeHandler) StopExperiment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "无效的ID",
+		})
+		return
+	}
+
+	experiment, err := h.activeQRCodeService.StopExperiment(uint(id))
+	if err != nil {
+		respondExperimentError(c, err, "停止实验失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "实验已停止",
+		Data:    experiment,
+	})
+}
+
+// DeleteExperiment 删除实验
+func (h *ActiveQRCodeHandler) DeleteExperiment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "无效的ID",
+		})
+		return
+	}
+
+	if err := h.activeQRCodeService.DeleteExperiment(uint(id)); err != nil {
+		respondExperimentError(c, err, "删除实验失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "实验已删除",
+	})
+}
+
+// GetExperimentReport 获取实验报告，可通过 ?event= 只统计指定的转化事件
+func (h *ActiveQRCodeHandler) GetExperimentReport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "无效的ID",
+		})
+		return
+	}
+
+	report, err := h.activeQRCodeService.GetExperimentReport(uint(id), c.Query("event"))
+	if err != nil {
+		respondExperimentError(c, err, "生成报告失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "获取成功",
+		Data:    report,
+	})
+}
+
+// conversionPixel 1×1透明GIF，图片像素方式回传转化时返回
+var conversionPixel = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+// RecordConversion 转化回传（公开），落地页通过目标链接中的 {conversion_token} 占位符获得签名凭证
+//
+// 支持 POST（JSON或表单，适用于 navigator.sendBeacon），成功时返回204；
+// GET 用于图片像素，成功时返回1×1透明GIF。
+func (h *ActiveQRCodeHandler) RecordConversion(c *gin.Context) {
+	var req models.ConversionRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if err := h.activeQRCodeService.RecordConversion(&req); err != nil {
+		respondExperimentError(c, err, "记录转化失败")
+		return
+	}
+
+	if c.Request.Method == http.MethodGet {
+		c.Header("Cache-Control", "no-store")
+		c.Data(http.StatusOK, "image/gif", conversionPixel)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// respondExperimentError 输出实验相关错误，记录不存在返回404，参数错误返回400
+func respondExperimentError(c *gin.Context, err error, message string) {
+	if appErr, ok := err.(*models.AppError); ok {
+		status := http.StatusBadRequest
+		switch appErr.Code {
+		case "ACTIVE_QR_NOT_FOUND", "EXPERIMENT_NOT_FOUND", "SCAN_NOT_FOUND":
+			status = http.StatusNotFound
+		case "EXPERIMENT_RUNNING", "EXPERIMENT_NOT_RUNNING":
+			status = http.StatusConflict
+		}
+		c.JSON(status, models.APIResponse{
+			Success: false,
+			Message: appErr.Message,
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, models.APIResponse{
+		Success: false,
+		Message: message + ": " + err.Error(),
+	})
+}
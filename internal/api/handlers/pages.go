@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// pageTemplates 扫码跳转相关的HTML页面模板
+var pageTemplates = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+
+// ErrorPageData 错误页面数据，字段为空时使用默认文案
+type ErrorPageData struct {
+	Title   string
+	Message string
+}
+
+// LandingPageData 图片类型静态码的落地页数据
+type LandingPageData struct {
+	Title    string
+	Text     string
+	Hint     string
+	ImageURL string
+}
+
+// RedirectPageData 页面内跳转及"在浏览器打开"引导页数据
+type RedirectPageData struct {
+	Title     string
+	TargetURL string
+}
+
+// renderPage 渲染HTML页面
+func renderPage(c *gin.Context, status int, name string, data interface{}) {
+	var buf bytes.Buffer
+	if err := pageTemplates.ExecuteTemplate(&buf, name, data); err != nil {
+		c.String(http.StatusInternalServerError, "页面渲染失败")
+		return
+	}
+	c.Data(status, "text/html; charset=utf-8", buf.Bytes())
+}
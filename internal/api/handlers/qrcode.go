@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 	"wechat-active-qrcode/internal/models"
@@ -33,6 +34,13 @@ func (h *QRCodeHandler) CreateQRCode(c *gin.Context) {
 
 	qrCode, err := h.qrCodeService.CreateQRCode(&req)
 	if err != nil {
+		if appErr, ok := err.(*models.AppError); ok {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: appErr.Message,
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success: false,
 			Message: err.Error(),
@@ -98,6 +106,13 @@ func (h *QRCodeHandler) UpdateQRCode(c *gin.Context) {
 
 	qrCode, err := h.qrCodeService.UpdateQRCode(uint(id), &req)
 	if err != nil {
+		if appErr, ok := err.(*models.AppError); ok {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: appErr.Message,
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success: false,
 			Message: err.Error(),
@@ -163,7 +178,18 @@ func (h *QRCodeHandler) ListQRCodes(c *gin.Context) {
 }
 
 // GetQRCodeImage 获取二维码图片
+//
+// 可选参数：format（png、svg、pdf、eps）、size（像素）、margin（静区模块数）、level（纠错等级 L/M/Q/H）
 func (h *QRCodeHandler) GetQRCodeImage(c *gin.Context) {
+	h.getQRCodeImage(c, false)
+}
+
+// GetPublicQRCodeImage 获取二维码图片（公开），只接受 format、frame、position 参数，其他参数使用保存的设置
+func (h *QRCodeHandler) GetPublicQRCodeImage(c *gin.Context) {
+	h.getQRCodeImage(c, true)
+}
+
+func (h *QRCodeHandler) getQRCodeImage(c *gin.Context, public bool) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
@@ -174,8 +200,27 @@ func (h *QRCodeHandler) GetQRCodeImage(c *gin.Context) {
 		return
 	}
 
-	imageData, err := h.qrCodeService.GetQRCodeImage(uint(id))
+	var req models.QRImageRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid image parameters",
+		})
+		return
+	}
+	if public {
+		restrictPublicImageRequest(&req)
+	}
+
+	imageData, format, err := h.qrCodeService.GetQRCodeImage(uint(id), &req)
 	if err != nil {
+		if appErr, ok := err.(*models.AppError); ok {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: appErr.Message,
+			})
+			return
+		}
 		c.JSON(http.StatusNotFound, models.APIResponse{
 			Success: false,
 			Message: "QR code image not found",
@@ -187,8 +232,8 @@ func (h *QRCodeHandler) GetQRCodeImage(c *gin.Context) {
 	c.Header("Cache-Control", "no-cache, no-store, must-revalidate")
 	c.Header("Pragma", "no-cache")
 	c.Header("Expires", "0")
-	c.Header("Content-Type", "image/png")
-	c.Data(http.StatusOK, "image/png", imageData)
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="qrcode_%d.%s"`, id, format.Extension()))
+	c.Data(http.StatusOK, format.ContentType(), imageData)
 }
 
 // RecordScan 记录扫描（公开接口）
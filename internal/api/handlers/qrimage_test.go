@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/logger"
+
+	"wechat-active-qrcode/internal/config"
+	"wechat-active-qrcode/internal/database"
+	"wechat-active-qrcode/internal/models"
+	"wechat-active-qrcode/internal/services"
+	"wechat-active-qrcode/pkg/qrcode"
+)
+
+func TestPublicImageIgnoresImageOptions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := database.NewSQLiteConnection(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	db.Logger = logger.Default.LogMode(logger.Silent)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	generator := qrcode.NewGenerator(t.TempDir())
+	cfg := &config.Config{Server: config.ServerConfig{BaseURL: "http://localhost"}}
+	activeHandler := NewActiveQRCodeHandler(services.NewActiveQRCodeService(db, generator, cfg, nil))
+	qrHandler := NewQRCodeHandler(services.NewQRCodeService(db, generator))
+
+	activeQR := &models.ActiveQRCode{Name: "poster", ShortCode: "poster"}
+	if err := db.Create(activeQR).Error; err != nil {
+		t.Fatalf("create active qr: %v", err)
+	}
+	qrCode := &models.QRCode{Name: "legacy", OriginalURL: "https://example.com"}
+	if err := db.Create(qrCode).Error; err != nil {
+		t.Fatalf("create qr code: %v", err)
+	}
+
+	router := gin.New()
+	router.GET("/api/active-qrcodes/:id/image", activeHandler.GetActiveQRCodeImage)
+	router.GET("/api/qrcodes/:id/image", qrHandler.GetQRCodeImage)
+	router.GET("/api/public/active-qrcodes/:id/image", activeHandler.GetPublicActiveQRCodeImage)
+	router.GET("/api/public/qrcodes/:id/image", qrHandler.GetPublicQRCodeImage)
+
+	imageWidth := func(path string) int {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: status %d, body %s", path, w.Code, w.Body.String())
+		}
+		img, err := png.Decode(bytes.NewReader(w.Body.Bytes()))
+		if err != nil {
+			t.Fatalf("GET %s: decode png: %v", path, err)
+		}
+		return img.Bounds().Dx()
+	}
+
+	defaultSize := generator.Options().Size
+	tests := []struct {
+		path string
+		want int
+	}{
+		// 公开接口忽略尺寸等参数，使用保存的设置
+		{fmt.Sprintf("/api/public/active-qrcodes/%d/image?size=4096&margin=16&level=H", activeQR.ID), defaultSize},
+		{fmt.Sprintf("/api/public/qrcodes/%d/image?size=4096", qrCode.ID), defaultSize},
+		// 需要登录的接口仍然接受
+		{fmt.Sprintf("/api/active-qrcodes/%d/image?size=512", activeQR.ID), 512},
+		{fmt.Sprintf("/api/qrcodes/%d/image?size=512", qrCode.ID), 512},
+	}
+	for _, tt := range tests {
+		if got := imageWidth(tt.path); got != tt.want {
+			t.Errorf("GET %s: width = %d, want %d", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"wechat-active-qrcode/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListQRStyles 获取样式模板列表
+func (h *ActiveQRCodeHandler) ListQRStyles(c *gin.Context) {
+	styles, err := h.activeQRCodeService.ListQRStyles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "查询失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "获取成功",
+		Data:    styles,
+	})
+}
+
+// CreateQRStyle 创建样式模板
+func (h *ActiveQRCodeHandler) CreateQRStyle(c *gin.Context) {
+	var req models.QRStyleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	style, err := h.activeQRCodeService.CreateQRStyle(&req)
+	if err != nil {
+		respondQRStyleError(c, err, "创建样式模板失败")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "创建成功",
+		Data:    style,
+	})
+}
+
+// GetQRStyle 获取样式模板详情
+func (h *ActiveQRCodeHandler) GetQRStyle(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "无效的ID",
+		})
+		return
+	}
+
+	style, err := h.activeQRCodeService.GetQRStyle(uint(id))
+	if err != nil {
+		respondQRStyleError(c, err, "查询失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "获取成功",
+		Data:    style,
+	})
+}
+
+// UpdateQRStyle 更新样式模板，引用它的二维码图片会重新生成
+func (h *ActiveQRCodeHandler) UpdateQRStyle(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "无效的ID",
+		})
+		return
+	}
+
+	var req models.QRStyleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	style, err := h.activeQRCodeService.UpdateQRStyle(uint(id), &req)
+	if err != nil {
+		respondQRStyleError(c, err, "更新样式模板失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "更新成功",
+		Data:    style,
+	})
+}
+
+// DeleteQRStyle 删除样式模板
+func (h *ActiveQRCodeHandler) DeleteQRStyle(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "无效的ID",
+		})
+		return
+	}
+
+	if err := h.activeQRCodeService.DeleteQRStyle(uint(id)); err != nil {
+		respondQRStyleError(c, err, "删除样式模板失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "删除成功",
+	})
+}
+
+// UploadQRStyleLogo 上传样式模板的Logo
+func (h *ActiveQRCodeHandler) UploadQRStyleLogo(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "无效的ID",
+		})
+		return
+	}
+
+	_, header, err := c.Request.FormFile("logo")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "请选择要上传的Logo图片",
+		})
+		return
+	}
+
+	style, err := h.activeQRCodeService.UploadQRStyleLogo(uint(id), header)
+	if err != nil {
+		respondQRStyleError(c, err, "上传失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "上传成功",
+		Data:    style,
+	})
+}
+
+// DeleteQRStyleLogo 删除样式模板的Logo
+func (h *ActiveQRCodeHandler) DeleteQRStyleLogo(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "无效的ID",
+		})
+		return
+	}
+
+	style, err := h.activeQRCodeService.DeleteQRStyleLogo(uint(id))
+	if err != nil {
+		respondQRStyleError(c, err, "删除失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "删除成功",
+		Data:    style,
+	})
+}
+
+// respondQRStyleError 返回样式模板操作的错误响应
+func respondQRStyleError(c *gin.Context, err error, message string) {
+	if appErr, ok := err.(*models.AppError); ok {
+		status := http.StatusBadRequest
+		switch appErr.Code {
+		case "QR_STYLE_NOT_FOUND":
+			status = http.StatusNotFound
+		case "QR_STYLE_IN_USE":
+			status = http.StatusConflict
+		}
+		c.JSON(status, models.APIResponse{
+			Success: false,
+			Message: appErr.Message,
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, models.APIResponse{
+		Success: false,
+		Message: message + ": " + err.Error(),
+	})
+}
@@ -206,3 +206,110 @@ func (h *StatisticsHandler) GetRegionStats(c *gin.Context) {
 		Data:    regionStats,
 	})
 }
+
+// GetOSStats 获取操作系统统计
+func (h *StatisticsHandler) GetOSStats(c *gin.Context) {
+	h.respondDimensionStats(c, h.statisticsService.GetOSStats, "OS statistics retrieved successfully")
+}
+
+// GetOSVersionStats 获取操作系统版本统计
+func (h *StatisticsHandler) GetOSVersionStats(c *gin.Context) {
+	h.respondDimensionStats(c, h.statisticsService.GetOSVersionStats, "OS version statistics retrieved successfully")
+}
+
+// GetBrowserStats 获取浏览器统计
+func (h *StatisticsHandler) GetBrowserStats(c *gin.Context) {
+	h.respondDimensionStats(c, h.statisticsService.GetBrowserStats, "Browser statistics retrieved successfully")
+}
+
+// GetClientStats 获取App内置浏览器统计
+func (h *StatisticsHandler) GetClientStats(c *gin.Context) {
+	h.respondDimensionStats(c, h.statisticsService.GetClientStats, "Client statistics retrieved successfully")
+}
+
+// GetClientVersionStats 获取App版本统计
+func (h *StatisticsHandler) GetClientVersionStats(c *gin.Context) {
+	h.respondDimensionStats(c, h.statisticsService.GetClientVersionStats, "Client version statistics retrieved successfully")
+}
+
+// respondDimensionStats 返回按单一维度分组的扫描统计
+func (h *StatisticsHandler) respondDimensionStats(c *gin.Context, query func() (map[string]int64, error), message string) {
+	stats, err := query()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: message,
+		Data:    stats,
+	})
+}
+
+// GetFailedScanStats 获取跳转失败的扫描统计
+func (h *StatisticsHandler) GetFailedScanStats(c *gin.Context) {
+	activeQRCodeID, ok := parseActiveQRCodeIDQuery(c)
+	if !ok {
+		return
+	}
+
+	failedStats, err := h.statisticsService.GetFailedScanStats(activeQRCodeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Failed scan statistics retrieved successfully",
+		Data:    failedStats,
+	})
+}
+
+// GetChannelStats 获取活码投放渠道统计
+func (h *StatisticsHandler) GetChannelStats(c *gin.Context) {
+	activeQRCodeID, ok := parseActiveQRCodeIDQuery(c)
+	if !ok {
+		return
+	}
+
+	channelStats, err := h.statisticsService.GetChannelStats(activeQRCodeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Channel statistics retrieved successfully",
+		Data:    channelStats,
+	})
+}
+
+// parseActiveQRCodeIDQuery 解析可选的active_qr_code_id查询参数，无效时返回400
+func parseActiveQRCodeIDQuery(c *gin.Context) (uint, bool) {
+	idStr := c.Query("active_qr_code_id")
+	if idStr == "" {
+		return 0, true
+	}
+
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid active QR code ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
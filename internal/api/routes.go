@@ -110,6 +110,7 @@ func (r *Router) SetupRoutes() *gin.Engine {
 		{
 			activeQRCodes.GET("", r.activeQRCodeHandler.ListActiveQRCodes)
 			activeQRCodes.POST("", r.activeQRCodeHandler.CreateActiveQRCode)
+			activeQRCodes.GET("/strategies", r.activeQRCodeHandler.ListStrategies) // 可用的切换规则
 			activeQRCodes.GET("/:id", r.activeQRCodeHandler.GetActiveQRCode)
 			activeQRCodes.PUT("/:id", r.activeQRCodeHandler.UpdateActiveQRCode)
 			activeQRCodes.DELETE("/:id", r.activeQRCodeHandler.DeleteActiveQRCode)
@@ -117,6 +118,24 @@ func (r *Router) SetupRoutes() *gin.Engine {
 			activeQRCodes.GET("/:id/qrcode", r.activeQRCodeHandler.GetActiveQRCodeImage) // 别名
 			activeQRCodes.POST("/:id/static-qrcodes", r.activeQRCodeHandler.AddStaticQRCode)
 			activeQRCodes.PATCH("/:id/toggle-status", r.activeQRCodeHandler.ToggleActiveQRStatus) // 切换状态
+			activeQRCodes.POST("/:id/simulate", r.activeQRCodeHandler.SimulateScan)               // 模拟扫码，排查跳转决策
+			activeQRCodes.GET("/:id/experiments", r.activeQRCodeHandler.ListExperiments)          // A/B实验列表
+			activeQRCodes.POST("/:id/experiments", r.activeQRCodeHandler.CreateExperiment)        // 创建A/B实验
+			activeQRCodes.POST("/:id/logo", r.activeQRCodeHandler.UploadActiveQRLogo)             // 上传二维码中心Logo
+			activeQRCodes.DELETE("/:id/logo", r.activeQRCodeHandler.DeleteActiveQRLogo)           // 删除二维码中心Logo
+		}
+
+		// 二维码样式模板路由（需要认证）
+		qrStyles := api.Group("/qr-styles")
+		qrStyles.Use(r.authMiddleware.AuthRequired())
+		{
+			qrStyles.GET("", r.activeQRCodeHandler.ListQRStyles)
+			qrStyles.POST("", r.activeQRCodeHandler.CreateQRStyle)
+			qrStyles.GET("/:id", r.activeQRCodeHandler.GetQRStyle)
+			qrStyles.PUT("/:id", r.activeQRCodeHandler.UpdateQRStyle) // 修改后重新生成引用它的二维码图片
+			qrStyles.DELETE("/:id", r.activeQRCodeHandler.DeleteQRStyle)
+			qrStyles.POST("/:id/logo", r.activeQRCodeHandler.UploadQRStyleLogo)
+			qrStyles.DELETE("/:id/logo", r.activeQRCodeHandler.DeleteQRStyleLogo)
 		}
 
 		// 静态码管理路由（需要认证）
@@ -125,10 +144,23 @@ func (r *Router) SetupRoutes() *gin.Engine {
 		{
 			staticQRCodes.GET("", r.activeQRCodeHandler.ListStaticQRCodes)
 			staticQRCodes.POST("", r.activeQRCodeHandler.CreateStaticQRCode)
+			staticQRCodes.GET("/expiring", r.activeQRCodeHandler.ListExpiringStaticQRCodes) // 即将过期的静态码
 			staticQRCodes.GET("/:id", r.activeQRCodeHandler.GetStaticQRCode)
 			staticQRCodes.PUT("/:id", r.activeQRCodeHandler.UpdateStaticQRCode)
 			staticQRCodes.DELETE("/:id", r.activeQRCodeHandler.DeleteStaticQRCode)
 			staticQRCodes.PATCH("/:id/toggle-status", r.activeQRCodeHandler.ToggleStaticQRStatus) // 切换状态
+			staticQRCodes.POST("/:id/image", r.activeQRCodeHandler.UploadStaticQRCodeImage)       // 上传图片类型的二维码图片
+			staticQRCodes.GET("/:id/health-checks", r.activeQRCodeHandler.ListTargetHealthChecks) // 目标链接健康检查记录
+		}
+
+		// A/B实验路由（需要认证）
+		experiments := api.Group("/experiments")
+		experiments.Use(r.authMiddleware.AuthRequired())
+		{
+			experiments.GET("/:id", r.activeQRCodeHandler.GetExperiment)
+			experiments.DELETE("/:id", r.activeQRCodeHandler.DeleteExperiment)
+			experiments.POST("/:id/stop", r.activeQRCodeHandler.StopExperiment)
+			experiments.GET("/:id/report", r.activeQRCodeHandler.GetExperimentReport) // 各版本转化率及置信区间
 		}
 
 		// 统计相关路由（需要认证）
@@ -142,6 +174,13 @@ func (r *Router) SetupRoutes() *gin.Engine {
 			statistics.GET("/scan-records", r.statisticsHandler.GetRecentScanRecords)
 			statistics.GET("/device-stats", r.statisticsHandler.GetDeviceStats)
 			statistics.GET("/region-stats", r.statisticsHandler.GetRegionStats)
+			statistics.GET("/os-stats", r.statisticsHandler.GetOSStats)
+			statistics.GET("/os-version-stats", r.statisticsHandler.GetOSVersionStats)
+			statistics.GET("/browser-stats", r.statisticsHandler.GetBrowserStats)
+			statistics.GET("/client-stats", r.statisticsHandler.GetClientStats)
+			statistics.GET("/client-version-stats", r.statisticsHandler.GetClientVersionStats)
+			statistics.GET("/failed-scans", r.statisticsHandler.GetFailedScanStats)
+			statistics.GET("/channel-stats", r.statisticsHandler.GetChannelStats)
 			statistics.GET("/qrcodes/:id/stats", r.statisticsHandler.GetScanStatistics)
 			statistics.GET("/qrcodes/:id/records", r.statisticsHandler.GetScanRecords)
 		}
@@ -161,9 +200,14 @@ func (r *Router) SetupRoutes() *gin.Engine {
 			public.POST("/scan/:id", r.qrCodeHandler.RecordScan)
 
 			// 二维码图片访问（公开）
-			public.GET("/qrcodes/:id/image", r.qrCodeHandler.GetQRCodeImage)
-			public.GET("/active-qrcodes/:id/image", r.activeQRCodeHandler.GetActiveQRCodeImage)
-			public.GET("/active-qrcodes/:id/qrcode", r.activeQRCodeHandler.GetActiveQRCodeImage)
+			public.GET("/qrcodes/:id/image", r.qrCodeHandler.GetPublicQRCodeImage)
+			public.GET("/active-qrcodes/:id/image", r.activeQRCodeHandler.GetPublicActiveQRCodeImage)
+			public.GET("/active-qrcodes/:id/qrcode", r.activeQRCodeHandler.GetPublicActiveQRCodeImage)
+			public.GET("/static-qrcodes/:id/image", r.activeQRCodeHandler.GetStaticQRCodeImage) // 落地页图片
+
+			// 落地页转化回传（公开）
+			public.POST("/conversions", r.activeQRCodeHandler.RecordConversion)
+			public.GET("/conversions", r.activeQRCodeHandler.RecordConversion) // 图片像素方式
 		}
 	}
 
@@ -5,16 +5,25 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
-	CORS     CORSConfig     `mapstructure:"cors"`
+	Server       ServerConfig       `mapstructure:"server"`
+	Database     DatabaseConfig     `mapstructure:"database"`
+	JWT          JWTConfig          `mapstructure:"jwt"`
+	CORS         CORSConfig         `mapstructure:"cors"`
+	GeoIP        GeoIPConfig        `mapstructure:"geoip"`
+	Expiry       ExpiryConfig       `mapstructure:"expiry"`
+	Notification NotificationConfig `mapstructure:"notification"`
+	HealthCheck  HealthCheckConfig  `mapstructure:"health_check"`
+	QRCode       QRCodeConfig       `mapstructure:"qrcode"`
+	Experiment   ExperimentConfig   `mapstructure:"experiment"`
 }
 
 type ServerConfig struct {
 	Port    string `mapstructure:"port"`
 	Mode    string `mapstructure:"mode"`
 	BaseURL string `mapstructure:"base_url"`
+
+	// 活码跳转方式: 302（默认）, 307, 301（会被浏览器和微信缓存，慎用）, html（页面内跳转）
+	RedirectMode string `mapstructure:"redirect_mode"`
 }
 
 type DatabaseConfig struct {
@@ -32,6 +41,51 @@ type CORSConfig struct {
 	AllowedHeaders []string `mapstructure:"allowed_headers"`
 }
 
+// GeoIPConfig IP地区解析配置
+type GeoIPConfig struct {
+	Provider string `mapstructure:"provider"` // mmdb, ip2region，留空则不解析
+	DBPath   string `mapstructure:"db_path"`
+	Language string `mapstructure:"language"` // mmdb地区名称语言，默认zh-CN
+}
+
+// ExpiryConfig 静态码（微信群二维码）过期提醒配置
+type ExpiryConfig struct {
+	CheckInterval int `mapstructure:"check_interval"` // 检查间隔（分钟），默认60
+	WarnHours     int `mapstructure:"warn_hours"`     // 提前提醒的小时数，默认48
+}
+
+// NotificationConfig 通知渠道配置，未配置Webhook时仅输出到日志
+type NotificationConfig struct {
+	WebhookURL    string `mapstructure:"webhook_url"`
+	WebhookFormat string `mapstructure:"webhook_format"` // json, wecom（企业微信群机器人）
+}
+
+// HealthCheckConfig 静态码目标链接健康检查配置
+type HealthCheckConfig struct {
+	Enabled          bool   `mapstructure:"enabled"`
+	Interval         int    `mapstructure:"interval"`          // 检查间隔（分钟），默认10
+	Method           string `mapstructure:"method"`            // GET（默认）或 HEAD
+	Timeout          int    `mapstructure:"timeout"`           // 单次探测超时（秒），默认10
+	ExpectedStatus   []int  `mapstructure:"expected_status"`   // 视为正常的状态码，为空时2xx和3xx均视为正常
+	FailureThreshold int    `mapstructure:"failure_threshold"` // 连续失败多少次判定为异常，默认3
+	RetentionDays    int    `mapstructure:"retention_days"`    // 检查记录保留天数，默认30
+}
+
+// QRCodeConfig 二维码图片默认参数，可在活码上单独设置或在请求图片时覆盖
+type QRCodeConfig struct {
+	Size      int    `mapstructure:"size"`       // 图片尺寸（像素），默认256，范围64-4096
+	Margin    *int   `mapstructure:"margin"`     // 静区宽度（模块数），默认4，范围0-16
+	Level     string `mapstructure:"level"`      // 纠错等级: L, M（默认）, Q, H
+	CacheSize int    `mapstructure:"cache_size"` // 缓存的图片数量，默认256，负数表示不缓存
+	FontPath  string `mapstructure:"font_path"`  // 边框文字字体（TTF/OTF/TTC），为空使用只含西文字形的内置字体
+}
+
+// ExperimentConfig A/B实验配置
+type ExperimentConfig struct {
+	CheckInterval    int    `mapstructure:"check_interval"`    // 自动切换检查间隔（分钟），默认5
+	ConversionSecret string `mapstructure:"conversion_secret"` // 转化凭证签名密钥，为空时使用jwt.secret
+}
+
 func LoadConfig(configPath string) (*Config, error) {
 	viper.SetConfigFile(configPath)
 	viper.SetConfigType("yaml")
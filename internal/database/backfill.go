@@ -0,0 +1,59 @@
+package database
+
+import (
+	"wechat-active-qrcode/internal/models"
+	"wechat-active-qrcode/pkg/useragent"
+
+	"gorm.io/gorm"
+)
+
+// backfillBatchSize 每批回填的扫描记录数
+const backfillBatchSize = 500
+
+// BackfillScanRecordUserAgents 为旧的扫描记录解析User-Agent，回填系统、浏览器及App版本字段
+//
+// 只处理 os 为空且 user_agent 不为空的记录，可重复执行，返回回填的记录数。
+func BackfillScanRecordUserAgents(db *gorm.DB) (int64, error) {
+	var total int64
+	var lastID uint
+
+	for {
+		var records []models.ScanRecord
+		err := db.Select("id", "user_agent").
+			Where("id > ? AND (os = '' OR os IS NULL) AND user_agent <> ''", lastID).
+			Order("id ASC").
+			Limit(backfillBatchSize).
+			Find(&records).Error
+		if err != nil {
+			return total, err
+		}
+		if len(records) == 0 {
+			return total, nil
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			for _, record := range records {
+				info := useragent.Parse(record.UserAgent)
+				err := tx.Model(&models.ScanRecord{}).
+					Where("id = ?", record.ID).
+					Updates(map[string]interface{}{
+						"os":             info.OS,
+						"os_version":     info.OSVersion,
+						"browser":        info.Browser,
+						"client":         info.Client,
+						"client_version": info.ClientVersion,
+					}).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return total, err
+		}
+
+		total += int64(len(records))
+		lastID = records[len(records)-1].ID
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"wechat-active-qrcode/internal/models"
 
 	"gorm.io/driver/sqlite"
@@ -18,7 +19,13 @@ func NewSQLiteConnection(dbPath string) (*gorm.DB, error) {
 		return nil, err
 	}
 
-	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{
+	// 设置忙等待超时，避免并发扫码写入时直接返回 database is locked
+	dsn := dbPath
+	if !strings.Contains(dsn, "?") {
+		dsn += "?_busy_timeout=5000"
+	}
+
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
 	})
 
@@ -29,9 +36,16 @@ func NewSQLiteConnection(dbPath string) (*gorm.DB, error) {
 	// 自动迁移表结构
 	err = db.AutoMigrate(
 		&models.QRCode{},
+		&models.QRStyle{},
 		&models.ActiveQRCode{},
 		&models.StaticQRCode{},
 		&models.ScanRecord{},
+		&models.RoundRobinCursor{},
+		&models.StickyAssignment{},
+		&models.TargetHealthCheck{},
+		&models.Experiment{},
+		&models.ExperimentVariant{},
+		&models.Conversion{},
 		&models.User{},
 	)
 
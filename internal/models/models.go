@@ -2,38 +2,108 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // ActiveQRCode 活码模型 - 主二维码
 type ActiveQRCode struct {
-	ID            uint           `json:"id" gorm:"primaryKey"`
-	Name          string         `json:"name" gorm:"not null"`
-	ShortCode     string         `json:"short_code" gorm:"unique;not null"` // 短码，用于生成活码URL
-	QRCodePath    string         `json:"qr_code_path"`                      // 活码二维码图片路径
-	Status        int            `json:"status" gorm:"default:1"`           // 1: 启用, 0: 禁用
-	SwitchRule    string         `json:"switch_rule" gorm:"default:'time'"` // 切换规则: time, random, weight, geo
-	Description   string         `json:"description"`
-	CreatedAt     time.Time      `json:"created_at"`
-	UpdatedAt     time.Time      `json:"updated_at"`
-	StaticQRCodes []StaticQRCode `json:"static_qr_codes,omitempty" gorm:"foreignKey:ActiveQRCodeID"`
-	ScanRecords   []ScanRecord   `json:"scan_records,omitempty" gorm:"foreignKey:ActiveQRCodeID"`
+	ID              uint           `json:"id" gorm:"primaryKey"`
+	Name            string         `json:"name" gorm:"not null"`
+	ShortCode       string         `json:"short_code" gorm:"unique;not null"`        // 短码，用于生成活码URL
+	QRCodePath      string         `json:"qr_code_path"`                             // 活码二维码图片路径
+	Status          int            `json:"status" gorm:"default:1"`                  // 1: 启用, 0: 禁用
+	SwitchRule      string         `json:"switch_rule" gorm:"default:'time'"`        // 切换规则: time, random, weight, geo
+	TimeZone        string         `json:"time_zone" gorm:"default:'Asia/Shanghai'"` // IANA时区，用于静态码每周时段判断
+	Sticky          bool           `json:"sticky" gorm:"default:false"`              // 粘性分配：同一访客始终跳转到同一静态码
+	StickyDays      int            `json:"sticky_days"`                              // 粘性分配有效天数，为0时使用默认的30天
+	PassQuery       bool           `json:"pass_query" gorm:"default:false"`          // 是否将扫码链接的查询参数透传到目标链接
+	RedirectMode    string         `json:"redirect_mode"`                            // 跳转方式: 301, 302, 307, html，为空使用全局配置
+	FallbackURL     string         `json:"fallback_url"`                             // 无可用静态码时的兜底链接
+	FallbackTitle   string         `json:"fallback_title"`                           // 兜底错误页面标题，为空使用默认文案
+	FallbackMessage string         `json:"fallback_message"`                         // 兜底错误页面内容，为空使用默认文案
+	FallbackActions string         `json:"fallback_actions"`                         // 各错误代码的兜底行为，JSON格式，如{"NO_MATCHING_QR":"redirect","DISABLED":"410"}
+	QRSize          int            `json:"qr_size"`                                  // 二维码图片尺寸（像素），为0使用全局配置
+	QRMargin        *int           `json:"qr_margin"`                                // 静区宽度（模块数），为空使用全局配置
+	QRLevel         string         `json:"qr_level"`                                 // 纠错等级: L, M, Q, H，为空使用全局配置
+	QRDesign        string         `json:"qr_design"`                                // 品牌样式，JSON格式，如{"foreground":"#1A73E8","module_shape":"dot"}
+	QRLogoPath      string         `json:"qr_logo_path"`                             // 二维码中心Logo的图片路径
+	QRStyleID       *uint          `json:"qr_style_id" gorm:"index"`                 // 引用的样式模板，活码自身的图片参数和品牌样式优先
+	Description     string         `json:"description"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	StaticQRCodes   []StaticQRCode `json:"static_qr_codes,omitempty" gorm:"foreignKey:ActiveQRCodeID"`
+	ScanRecords     []ScanRecord   `json:"scan_records,omitempty" gorm:"foreignKey:ActiveQRCodeID"`
 }
 
 // StaticQRCode 静态二维码模型 - 活码对应的多个静态码
 type StaticQRCode struct {
-	ID             uint         `json:"id" gorm:"primaryKey"`
-	ActiveQRCodeID uint         `json:"active_qr_code_id" gorm:"not null"`
-	Name           string       `json:"name" gorm:"not null"`
-	TargetURL      string       `json:"target_url" gorm:"not null"` // 实际跳转的目标URL
-	Weight         int          `json:"weight" gorm:"default:1"`    // 权重，用于按权重分配
-	Status         int          `json:"status" gorm:"default:1"`    // 1: 启用, 0: 禁用
-	StartTime      *time.Time   `json:"start_time"`                 // 生效开始时间
-	EndTime        *time.Time   `json:"end_time"`                   // 生效结束时间
-	AllowedRegions string       `json:"allowed_regions"`            // 允许的地区，JSON格式
-	AllowedDevices string       `json:"allowed_devices"`            // 允许的设备类型，JSON格式
-	CreatedAt      time.Time    `json:"created_at"`
-	UpdatedAt      time.Time    `json:"updated_at"`
-	ActiveQRCode   ActiveQRCode `json:"active_qr_code,omitempty" gorm:"foreignKey:ActiveQRCodeID"`
+	ID               uint         `json:"id" gorm:"primaryKey"`
+	ActiveQRCodeID   uint         `json:"active_qr_code_id" gorm:"not null"`
+	Name             string       `json:"name" gorm:"not null"`
+	TargetURL        string       `json:"target_url" gorm:"not null"`        // 实际跳转的目标URL（图片类型为图片中二维码的内容）
+	Type             string       `json:"type" gorm:"default:'url'"`         // 类型: url 跳转链接, image 显示图片落地页
+	ImagePath        string       `json:"image_path"`                        // 图片类型上传的二维码图片路径
+	LandingTitle     string       `json:"landing_title"`                     // 落地页标题，为空使用活码名称
+	LandingText      string       `json:"landing_text"`                      // 落地页说明文字
+	LandingHint      string       `json:"landing_hint"`                      // 落地页长按提示，为空使用默认文案
+	UTMParams        string       `json:"utm_params"`                        // 跳转时固定追加的UTM参数，JSON格式，如{"utm_source":"wechat"}
+	ExpiresAt        *time.Time   `json:"expires_at"`                        // 过期时间（微信群二维码上传后7天），过期后不再分配
+	ExpiryNotifiedAt *time.Time   `json:"expiry_notified_at"`                // 已发送过期提醒的时间，修改过期时间后重置
+	Weight           int          `json:"weight" gorm:"default:1"`           // 权重，用于按权重分配
+	Priority         int          `json:"priority" gorm:"default:0"`         // 优先级，数值越大越优先；只在可用的最高优先级中分配，全部不可用时使用下一级（主备切换）
+	Status           int          `json:"status" gorm:"default:1"`           // 1: 启用, 0: 禁用
+	StartTime        *time.Time   `json:"start_time"`                        // 生效开始时间
+	EndTime          *time.Time   `json:"end_time"`                          // 生效结束时间
+	AllowedRegions   string       `json:"allowed_regions"`                   // 允许的地区，JSON格式
+	AllowedDevices   string       `json:"allowed_devices"`                   // 允许的设备类型，JSON格式
+	AllowedClients   string       `json:"allowed_clients"`                   // 允许的客户端（wechat, wecom, alipay, qq, douyin, other），JSON格式
+	PreferredClients string       `json:"preferred_clients"`                 // 优先分配的客户端，JSON格式，匹配时优先于其他静态码
+	Condition        string       `json:"condition"`                         // 定向条件表达式，如 (os == "iOS" && city contains "上海") || device == "tablet"，为空表示不限
+	AllowedLanguages string       `json:"allowed_languages"`                 // 允许的语言（如zh-CN, en），JSON格式，按Accept-Language协商；未限制的静态码作为其他语言的默认目标
+	OpenInBrowser    bool         `json:"open_in_browser"`                   // 在微信/企业微信中显示"在浏览器打开"引导页（如App下载链接）
+	Schedule         string       `json:"schedule"`                          // 每周生效时段及例外日期，JSON格式
+	ExcludeUnhealthy bool         `json:"exclude_unhealthy"`                 // 健康检查判定目标链接异常时暂停分配，恢复后自动继续
+	HealthStatus     string       `json:"health_status"`                     // 目标链接健康状态：healthy, unhealthy，未检查为空
+	HealthFailures   int          `json:"health_failures" gorm:"default:0"`  // 连续检查失败次数
+	HealthCheckedAt  *time.Time   `json:"health_checked_at"`                 // 最近一次健康检查时间
+	MaxScans         int          `json:"max_scans" gorm:"default:0"`        // 累计扫码上限，0表示不限
+	MaxDailyScans    int          `json:"max_daily_scans" gorm:"default:0"`  // 每日扫码上限，0表示不限
+	ScanCount        int          `json:"scan_count" gorm:"default:0"`       // 累计已分配扫码次数
+	DailyScanCount   int          `json:"daily_scan_count" gorm:"default:0"` // DailyScanDate 当日已分配扫码次数
+	DailyScanDate    string       `json:"daily_scan_date"`                   // 每日计数对应的日期（YYYY-MM-DD，活码时区）
+	DailyResetAt     *time.Time   `json:"daily_reset_at"`                    // 每日计数清零的时间，即活码时区中 DailyScanDate 次日零点
+	IsFull           bool         `json:"is_full" gorm:"default:false"`      // 是否已达到累计扫码上限
+	FullAt           *time.Time   `json:"full_at"`                           // 达到累计上限的时间
+	DailyFull        bool         `json:"daily_full" gorm:"-"`               // 今日是否已达到每日上限（查询时计算）
+	CreatedAt        time.Time    `json:"created_at"`
+	UpdatedAt        time.Time    `json:"updated_at"`
+	ActiveQRCode     ActiveQRCode `json:"active_qr_code,omitempty" gorm:"foreignKey:ActiveQRCodeID"`
+}
+
+// 静态码类型
+const (
+	StaticQRTypeURL   = "url"   // 跳转到目标链接
+	StaticQRTypeImage = "image" // 显示上传的二维码图片（如微信群二维码）
+)
+
+// IsImage 是否为图片类型的静态码
+func (s *StaticQRCode) IsImage() bool {
+	return s.Type == StaticQRTypeImage
+}
+
+// IsDailyFull 判断指定日期是否已达到每日扫码上限
+func (s *StaticQRCode) IsDailyFull(date string) bool {
+	return s.MaxDailyScans > 0 && s.DailyScanDate == date && s.DailyScanCount >= s.MaxDailyScans
+}
+
+// AfterFind 查询后计算今日是否已满
+//
+// 每日计数按活码时区分日，这里不知道时区，因此用记录的清零时间判断，与扫码时按日期的判断结果一致。
+func (s *StaticQRCode) AfterFind(tx *gorm.DB) error {
+	s.DailyFull = s.MaxDailyScans > 0 && s.DailyScanCount >= s.MaxDailyScans &&
+		s.DailyResetAt != nil && time.Now().Before(*s.DailyResetAt)
+	return nil
 }
 
 // QRCode 保留原有的简单二维码模型（向后兼容）
@@ -42,12 +112,30 @@ type QRCode struct {
 	Name        string       `json:"name" gorm:"not null"`
 	OriginalURL string       `json:"original_url" gorm:"not null"`
 	QRCodePath  string       `json:"qr_code_path"`
-	Status      int          `json:"status" gorm:"default:1"` // 1: 启用, 0: 禁用
+	QRStyleID   *uint        `json:"qr_style_id" gorm:"index"` // 引用的样式模板
+	Status      int          `json:"status" gorm:"default:1"`  // 1: 启用, 0: 禁用
 	CreatedAt   time.Time    `json:"created_at"`
 	UpdatedAt   time.Time    `json:"updated_at"`
 	ScanRecords []ScanRecord `json:"scan_records,omitempty" gorm:"foreignKey:QRCodeID"`
 }
 
+// QRStyle 二维码样式模板，可被多个活码和普通二维码引用，修改后引用它的二维码图片会重新生成
+type QRStyle struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	Name          string    `json:"name" gorm:"not null"`
+	Design        string    `json:"design"`         // 品牌样式，JSON格式，与活码的 qr_design 相同
+	LogoPath      string    `json:"logo_path"`      // 二维码中心Logo的图片路径
+	Size          int       `json:"size"`           // 图片尺寸（像素），为0使用全局配置
+	Margin        *int      `json:"margin"`         // 静区宽度（模块数），为空使用全局配置
+	Level         string    `json:"level"`          // 纠错等级: L, M, Q, H，为空使用全局配置
+	Caption       string    `json:"caption"`        // 海报边框中的说明文字，如"扫码加入会员群"
+	FramePosition string    `json:"frame_position"` // 边框文字位置: bottom（默认）、top
+	FramePadding  int       `json:"frame_padding"`  // 边框留白（像素），为0按图片尺寸计算
+	Description   string    `json:"description"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
 // ScanRecord 扫描记录模型
 type ScanRecord struct {
 	ID             uint          `json:"id" gorm:"primaryKey"`
@@ -58,14 +146,100 @@ type ScanRecord struct {
 	UserAgent      string        `json:"user_agent"`
 	ScanTime       time.Time     `json:"scan_time"`
 	Location       string        `json:"location"`
-	Device         string        `json:"device"`     // 设备类型：mobile, desktop, tablet
-	Region         string        `json:"region"`     // 地区信息
-	TargetURL      string        `json:"target_url"` // 实际跳转的URL
+	Device         string        `json:"device"`               // 设备类型：mobile, desktop, tablet
+	OS             string        `json:"os"`                   // 操作系统：iOS, Android, HarmonyOS, Windows, macOS
+	OSVersion      string        `json:"os_version"`           // 操作系统版本
+	Browser        string        `json:"browser"`              // 浏览器
+	Client         string        `json:"client"`               // App内置浏览器：wechat, wecom, alipay, qq, douyin, other
+	ClientVersion  string        `json:"client_version"`       // App版本
+	Language       string        `json:"language"`             // 按Accept-Language协商出的语言
+	Region         string        `json:"region"`               // 地区信息
+	TargetURL      string        `json:"target_url"`           // 实际跳转的URL
+	StickyHit      bool          `json:"sticky_hit"`           // 是否命中粘性分配
+	ErrorCode      string        `json:"error_code"`           // 跳转失败时的错误代码，成功为空
+	Channel        string        `json:"channel" gorm:"index"` // 投放渠道（扫码链接的src参数，其次utm_source）
+	TrackingParams string        `json:"tracking_params"`      // 扫码链接中的src和utm_*参数，JSON格式
 	QRCode         *QRCode       `json:"qr_code,omitempty" gorm:"foreignKey:QRCodeID"`
 	ActiveQRCode   *ActiveQRCode `json:"active_qr_code,omitempty" gorm:"foreignKey:ActiveQRCodeID"`
 	StaticQRCode   *StaticQRCode `json:"static_qr_code,omitempty" gorm:"foreignKey:StaticQRCodeID"`
 }
 
+// RoundRobinCursor 轮询游标，记录活码上一次跳转的静态码，重启后仍可继续轮询
+type RoundRobinCursor struct {
+	ActiveQRCodeID     uint      `json:"active_qr_code_id" gorm:"primaryKey;autoIncrement:false"`
+	LastStaticQRCodeID uint      `json:"last_static_qr_code_id"` // 上一次跳转的静态码ID
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// TargetHealthCheck 静态码目标链接的健康检查记录
+type TargetHealthCheck struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	StaticQRCodeID uint      `json:"static_qr_code_id" gorm:"index"`
+	URL            string    `json:"url"`
+	Method         string    `json:"method"`
+	StatusCode     int       `json:"status_code"` // 连接失败时为0
+	LatencyMs      int64     `json:"latency_ms"`
+	Healthy        bool      `json:"healthy"`
+	Error          string    `json:"error"`
+	CheckedAt      time.Time `json:"checked_at" gorm:"index"`
+}
+
+// StickyAssignment 粘性分配记录，访客标识为Cookie中的访客ID或IP+User-Agent指纹
+type StickyAssignment struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	ActiveQRCodeID uint      `json:"active_qr_code_id" gorm:"not null;uniqueIndex:idx_sticky_visitor"`
+	VisitorKey     string    `json:"visitor_key" gorm:"not null;uniqueIndex:idx_sticky_visitor"`
+	StaticQRCodeID uint      `json:"static_qr_code_id" gorm:"not null"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// 实验状态
+const (
+	ExperimentStatusRunning   = "running"   // 进行中
+	ExperimentStatusStopped   = "stopped"   // 手动停止
+	ExperimentStatusCompleted = "completed" // 已得出显著结果并自动把权重切换到胜出版本
+)
+
+// Experiment 活码上的A/B实验，每个版本对应一个静态码，按扫码转化率比较效果
+type Experiment struct {
+	ID              uint                `json:"id" gorm:"primaryKey"`
+	ActiveQRCodeID  uint                `json:"active_qr_code_id" gorm:"not null;index"`
+	Name            string              `json:"name" gorm:"not null"`
+	Status          string              `json:"status" gorm:"default:running"`
+	Confidence      float64             `json:"confidence" gorm:"default:0.95"` // 置信水平，用于置信区间和显著性判断
+	MinScans        int                 `json:"min_scans" gorm:"default:100"`   // 每个版本至少达到的扫码次数，未达到时不判定胜出
+	AutoShift       bool                `json:"auto_shift"`                     // 结果显著时自动把权重切换到胜出版本，仅支持按权重分配的活码
+	WinnerVariantID *uint               `json:"winner_variant_id"`
+	StartedAt       time.Time           `json:"started_at"`
+	EndedAt         *time.Time          `json:"ended_at"`
+	Variants        []ExperimentVariant `json:"variants" gorm:"foreignKey:ExperimentID"`
+	CreatedAt       time.Time           `json:"created_at"`
+	UpdatedAt       time.Time           `json:"updated_at"`
+}
+
+// ExperimentVariant 实验版本
+type ExperimentVariant struct {
+	ID             uint          `json:"id" gorm:"primaryKey"`
+	ExperimentID   uint          `json:"experiment_id" gorm:"not null;index"`
+	Name           string        `json:"name" gorm:"not null"`
+	StaticQRCodeID uint          `json:"static_qr_code_id" gorm:"not null"`
+	WeightBefore   *int          `json:"weight_before,omitempty"` // 自动切换前静态码的权重，用于手动恢复；为空表示未调整
+	StaticQRCode   *StaticQRCode `json:"static_qr_code,omitempty" gorm:"foreignKey:StaticQRCodeID"`
+}
+
+// Conversion 落地页通过转化凭证回传的转化，同一扫描记录的同一事件只记录一次
+type Conversion struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	ScanRecordID   uint      `json:"scan_record_id" gorm:"not null;uniqueIndex:idx_conversion_scan_event"`
+	Event          string    `json:"event" gorm:"not null;uniqueIndex:idx_conversion_scan_event"` // 转化事件，默认conversion
+	ActiveQRCodeID *uint     `json:"active_qr_code_id" gorm:"index"`
+	StaticQRCodeID *uint     `json:"static_qr_code_id"`
+	Value          float64   `json:"value"` // 转化价值（如订单金额），可选
+	CreatedAt      time.Time `json:"created_at"`
+}
+
 // User 用户模型
 type User struct {
 	ID           uint      `json:"id" gorm:"primaryKey"`
@@ -87,48 +261,161 @@ type ScanStats struct {
 type ActiveQRCodeCreateRequest struct {
 	Name        string `json:"name" binding:"required"`
 	SwitchRule  string `json:"switch_rule"` // time, random, weight, geo
+	TimeZone    string `json:"time_zone"`   // IANA时区，如Asia/Shanghai
+	Sticky      *bool  `json:"sticky"`      // 为空时保持不变
+	StickyDays  *int   `json:"sticky_days"` // 为空时保持不变
 	Description string `json:"description"`
+
+	RedirectMode *string `json:"redirect_mode"` // 为空时保持不变，空字符串表示使用全局配置
+	PassQuery    *bool   `json:"pass_query"`    // 为空时保持不变
+
+	// 兜底配置，为空时保持不变
+	FallbackURL     *string `json:"fallback_url"`
+	FallbackTitle   *string `json:"fallback_title"`
+	FallbackMessage *string `json:"fallback_message"`
+	FallbackActions *string `json:"fallback_actions"` // 取值：redirect, page, 404, 410
+
+	// 二维码图片参数，为空时保持不变；qr_size 为0、qr_margin 为负数、qr_level 为空字符串表示使用全局配置
+	QRSize   *int    `json:"qr_size"`
+	QRMargin *int    `json:"qr_margin"`
+	QRLevel  *string `json:"qr_level"`
+
+	// 品牌样式（颜色、渐变、模块和定位点形状、Logo比例），为空时保持不变，空字符串表示清除样式（Logo需通过接口单独删除）
+	QRDesign *string `json:"qr_design"`
+
+	QRStyleID *uint `json:"qr_style_id"` // 样式模板，为空时保持不变，0表示不使用模板
+}
+
+// QRImageRequest 获取二维码图片的参数，为空时使用活码设置或全局配置
+type QRImageRequest struct {
+	Format string `form:"format"` // png（默认）, svg, pdf, eps
+	Size   int    `form:"size"`   // 图片尺寸（像素）
+	Margin *int   `form:"margin"` // 静区宽度（模块数）
+	Level  string `form:"level"`  // 纠错等级: L, M, Q, H
+
+	// 海报边框，frame=true 时在二维码四周加边框，并绘制二维码名称和说明文字；未指定时使用样式模板的设置
+	Frame    bool   `form:"frame"`
+	Caption  string `form:"caption"`  // 说明文字，如"扫码加入社群"
+	Position string `form:"position"` // 文字位置: bottom（默认）、top
+	Padding  int    `form:"padding"`  // 边框留白（像素）
 }
 
 // StaticQRCodeCreateRequest 创建静态码请求
 type StaticQRCodeCreateRequest struct {
-	ActiveQRCodeID uint       `json:"active_qr_code_id" binding:"required"`
-	Name           string     `json:"name" binding:"required"`
-	TargetURL      string     `json:"target_url" binding:"required"`
-	Weight         int        `json:"weight"`
-	Status         int        `json:"status"`
-	StartTime      *time.Time `json:"start_time"`
-	EndTime        *time.Time `json:"end_time"`
-	AllowedRegions string     `json:"allowed_regions"`
-	AllowedDevices string     `json:"allowed_devices"`
+	ActiveQRCodeID   uint       `json:"active_qr_code_id" binding:"required"`
+	Name             string     `json:"name" binding:"required"`
+	TargetURL        string     `json:"target_url"` // url类型必填，image类型在上传图片后自动填充
+	Type             string     `json:"type"`       // url（默认）或 image
+	LandingTitle     string     `json:"landing_title"`
+	LandingText      string     `json:"landing_text"`
+	LandingHint      string     `json:"landing_hint"`
+	ExpiresAt        *time.Time `json:"expires_at"` // 为空且目标为微信群邀请时自动设置为7天后
+	UTMParams        string     `json:"utm_params"`
+	Weight           int        `json:"weight"`
+	Priority         int        `json:"priority"`
+	Status           int        `json:"status"`
+	StartTime        *time.Time `json:"start_time"`
+	EndTime          *time.Time `json:"end_time"`
+	AllowedRegions   string     `json:"allowed_regions"`
+	AllowedDevices   string     `json:"allowed_devices"`
+	AllowedClients   string     `json:"allowed_clients"`
+	PreferredClients string     `json:"preferred_clients"`
+	AllowedLanguages string     `json:"allowed_languages"`
+	Condition        string     `json:"condition"`
+	OpenInBrowser    bool       `json:"open_in_browser"`
+	Schedule         string     `json:"schedule"`
+	ExcludeUnhealthy bool       `json:"exclude_unhealthy"`
+	MaxScans         int        `json:"max_scans"`
+	MaxDailyScans    int        `json:"max_daily_scans"`
 }
 
 // StaticQRCodeUpdateRequest 更新静态码请求
 type StaticQRCodeUpdateRequest struct {
-	ActiveQRCodeID *uint      `json:"active_qr_code_id"`
-	Name           *string    `json:"name"`
-	TargetURL      *string    `json:"target_url"`
-	Weight         *int       `json:"weight"`
-	Status         *int       `json:"status"`
-	StartTime      *time.Time `json:"start_time"`
-	EndTime        *time.Time `json:"end_time"`
-	AllowedRegions *string    `json:"allowed_regions"`
-	AllowedDevices *string    `json:"allowed_devices"`
+	ActiveQRCodeID   *uint      `json:"active_qr_code_id"`
+	Name             *string    `json:"name"`
+	TargetURL        *string    `json:"target_url"`
+	Type             *string    `json:"type"`
+	LandingTitle     *string    `json:"landing_title"`
+	LandingText      *string    `json:"landing_text"`
+	LandingHint      *string    `json:"landing_hint"`
+	ExpiresAt        *time.Time `json:"expires_at"`
+	ClearExpiresAt   bool       `json:"clear_expires_at"` // 清除过期时间
+	UTMParams        *string    `json:"utm_params"`
+	Weight           *int       `json:"weight"`
+	Priority         *int       `json:"priority"`
+	Status           *int       `json:"status"`
+	StartTime        *time.Time `json:"start_time"`
+	EndTime          *time.Time `json:"end_time"`
+	AllowedRegions   *string    `json:"allowed_regions"`
+	AllowedDevices   *string    `json:"allowed_devices"`
+	AllowedClients   *string    `json:"allowed_clients"`
+	PreferredClients *string    `json:"preferred_clients"`
+	AllowedLanguages *string    `json:"allowed_languages"`
+	Condition        *string    `json:"condition"`
+	OpenInBrowser    *bool      `json:"open_in_browser"`
+	Schedule         *string    `json:"schedule"`
+	ExcludeUnhealthy *bool      `json:"exclude_unhealthy"`
+	MaxScans         *int       `json:"max_scans"`
+	MaxDailyScans    *int       `json:"max_daily_scans"`
+	ResetScanCount   bool       `json:"reset_scan_count"` // 清零扫码计数并解除已满状态
 }
 
 // ActiveQRCodeUpdateRequest 更新活码请求
 type ActiveQRCodeUpdateRequest struct {
 	Name        string `json:"name"`
 	SwitchRule  string `json:"switch_rule"`
+	TimeZone    string `json:"time_zone"`
 	Description string `json:"description"`
 	Status      *int   `json:"status"`
 }
 
+// ExperimentCreateRequest 创建A/B实验请求
+type ExperimentCreateRequest struct {
+	Name       string                     `json:"name" binding:"required"`
+	Confidence float64                    `json:"confidence"` // 默认0.95
+	MinScans   int                        `json:"min_scans"`  // 默认100
+	AutoShift  bool                       `json:"auto_shift"`
+	Variants   []ExperimentVariantRequest `json:"variants" binding:"required"`
+}
+
+// ExperimentVariantRequest 实验版本
+type ExperimentVariantRequest struct {
+	Name           string `json:"name" binding:"required"`
+	StaticQRCodeID uint   `json:"static_qr_code_id" binding:"required"`
+}
+
+// ConversionRequest 转化回传请求
+type ConversionRequest struct {
+	Token string  `json:"token" form:"token" binding:"required"` // 跳转时 {conversion_token} 占位符生成的签名凭证
+	Event string  `json:"event" form:"event"`
+	Value float64 `json:"value" form:"value"`
+}
+
+// SimulateScanRequest 模拟扫码请求，用于排查活码的跳转决策，不写入扫描记录
+type SimulateScanRequest struct {
+	UserAgent      string            `json:"user_agent"`
+	IPAddress      string            `json:"ip_address"`
+	AcceptLanguage string            `json:"accept_language"`
+	Region         *SimulateRegion   `json:"region"`     // 指定地区时不再根据IP解析
+	Time           *time.Time        `json:"time"`       // 扫码时间，为空使用当前时间
+	Query          string            `json:"query"`      // 扫码链接的查询字符串，如 src=poster&utm_source=wechat
+	Cookies        map[string]string `json:"cookies"`    // 请求携带的Cookie
+	VisitorID      string            `json:"visitor_id"` // 访客ID，用于检查粘性分配
+}
+
+// SimulateRegion 模拟扫码的地区
+type SimulateRegion struct {
+	Country  string `json:"country"`
+	Province string `json:"province"`
+	City     string `json:"city"`
+}
+
 // QRCodeCreateRequest 创建二维码请求
 type QRCodeCreateRequest struct {
 	Name        string `json:"name" binding:"required"`
 	OriginalURL string `json:"original_url" binding:"required"`
 	Description string `json:"description"`
+	QRStyleID   *uint  `json:"qr_style_id"` // 样式模板
 }
 
 // QRCodeUpdateRequest 更新二维码请求
@@ -136,6 +423,21 @@ type QRCodeUpdateRequest struct {
 	Name        string `json:"name"`
 	OriginalURL string `json:"original_url"`
 	Status      *int   `json:"status"`
+	QRStyleID   *uint  `json:"qr_style_id"` // 为空时保持不变，0表示不使用模板
+}
+
+// QRStyleRequest 创建或更新样式模板请求
+type QRStyleRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Design      string `json:"design"`  // 品牌样式，JSON格式
+	Size        int    `json:"size"`    // 为0使用全局配置
+	Margin      *int   `json:"margin"`  // 为空或负数使用全局配置
+	Level       string `json:"level"`   // 为空使用全局配置
+	Caption     string `json:"caption"` // 说明文字
+	Description string `json:"description"`
+
+	FramePosition string `json:"frame_position"` // 边框文字位置: bottom（默认）、top
+	FramePadding  int    `json:"frame_padding"`  // 边框留白（像素）
 }
 
 // LoginRequest 登录请求
@@ -2,23 +2,30 @@ package services
 
 import (
 	"crypto/rand"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"math/big"
+	"os"
 	"strings"
 	"time"
 	"wechat-active-qrcode/internal/config"
 	"wechat-active-qrcode/internal/models"
+	"wechat-active-qrcode/pkg/geoip"
+	"wechat-active-qrcode/pkg/language"
 	"wechat-active-qrcode/pkg/qrcode"
+	"wechat-active-qrcode/pkg/schedule"
+	"wechat-active-qrcode/pkg/useragent"
 
 	"gorm.io/gorm"
 )
 
 // QRCodeError 二维码错误类型
 type QRCodeError struct {
-	Code    string // 错误代码：NOT_FOUND, DISABLED, NO_STATIC_QR, NO_MATCHING_QR
-	Message string // 错误消息
+	Code     string          // 错误代码：NOT_FOUND, DISABLED, NO_STATIC_QR, NO_MATCHING_QR
+	Message  string          // 错误消息
+	Fallback *FallbackAction // 活码配置的兜底行为，活码不存在时为空
 }
 
 func (e *QRCodeError) Error() string {
@@ -26,16 +33,21 @@ func (e *QRCodeError) Error() string {
 }
 
 type ActiveQRCodeService struct {
-	db          *gorm.DB
-	qrGenerator *qrcode.Generator
-	config      *config.Config
+	db             *gorm.DB
+	qrGenerator    *qrcode.Generator
+	config         *config.Config
+	regionResolver geoip.Resolver
 }
 
-func NewActiveQRCodeService(db *gorm.DB, qrGenerator *qrcode.Generator, cfg *config.Config) *ActiveQRCodeService {
+func NewActiveQRCodeService(db *gorm.DB, qrGenerator *qrcode.Generator, cfg *config.Config, regionResolver geoip.Resolver) *ActiveQRCodeService {
+	if regionResolver == nil {
+		regionResolver = geoip.NopResolver{}
+	}
 	return &ActiveQRCodeService{
-		db:          db,
-		qrGenerator: qrGenerator,
-		config:      cfg,
+		db:             db,
+		qrGenerator:    qrGenerator,
+		config:         cfg,
+		regionResolver: regionResolver,
 	}
 }
 
@@ -46,6 +58,19 @@ func (s *ActiveQRCodeService) GetDB() *gorm.DB {
 
 // CreateActiveQRCode 创建活码
 func (s *ActiveQRCodeService) CreateActiveQRCode(req *models.ActiveQRCodeCreateRequest) (*models.ActiveQRCode, error) {
+	// 校验切换规则、时区和跳转方式
+	if err := ValidateSwitchRule(req.SwitchRule); err != nil {
+		return nil, err
+	}
+	if err := validateTimeZone(req.TimeZone); err != nil {
+		return nil, err
+	}
+	if req.RedirectMode != nil {
+		if err := validateRedirectMode(*req.RedirectMode); err != nil {
+			return nil, err
+		}
+	}
+
 	// 生成短码
 	shortCode, err := s.generateShortCode()
 	if err != nil {
@@ -56,9 +81,31 @@ func (s *ActiveQRCodeService) CreateActiveQRCode(req *models.ActiveQRCodeCreateR
 		Name:        req.Name,
 		ShortCode:   shortCode,
 		SwitchRule:  req.SwitchRule,
+		TimeZone:    req.TimeZone,
 		Description: req.Description,
 		Status:      1,
 	}
+	if req.Sticky != nil {
+		activeQR.Sticky = *req.Sticky
+	}
+	if req.StickyDays != nil {
+		activeQR.StickyDays = *req.StickyDays
+	}
+	if req.RedirectMode != nil {
+		activeQR.RedirectMode = *req.RedirectMode
+	}
+	if req.PassQuery != nil {
+		activeQR.PassQuery = *req.PassQuery
+	}
+	if err := applyFallbackConfig(activeQR, req); err != nil {
+		return nil, err
+	}
+	if err := applyQRImageConfig(s.qrGenerator, activeQR, req); err != nil {
+		return nil, err
+	}
+	if err := s.applyQRDesign(activeQR, req); err != nil {
+		return nil, err
+	}
 
 	// 保存到数据库
 	if err := s.db.Create(activeQR).Error; err != nil {
@@ -66,18 +113,10 @@ func (s *ActiveQRCodeService) CreateActiveQRCode(req *models.ActiveQRCodeCreateR
 	}
 
 	// 生成活码二维码图片（指向中转页面）
-	redirectURL := fmt.Sprintf("%s/r/%s", s.config.Server.BaseURL, shortCode)
-	qrPath, err := s.qrGenerator.GenerateQRCode(redirectURL, fmt.Sprintf("active_%d.png", activeQR.ID))
-	if err != nil {
+	if err := s.saveActiveQRImage(activeQR); err != nil {
 		return nil, fmt.Errorf("failed to generate QR code image: %v", err)
 	}
 
-	// 更新二维码路径
-	activeQR.QRCodePath = qrPath
-	if err := s.db.Save(activeQR).Error; err != nil {
-		return nil, fmt.Errorf("failed to update QR code path: %v", err)
-	}
-
 	return activeQR, nil
 }
 
@@ -89,25 +128,72 @@ func (s *ActiveQRCodeService) AddStaticQRCode(activeQRCodeID uint, req *models.S
 		return nil, fmt.Errorf("active QR code not found: %v", err)
 	}
 
-	// 转换允许的地区和设备为JSON字符串
-	allowedRegionsJSON, _ := json.Marshal(req.AllowedRegions)
-	allowedDevicesJSON, _ := json.Marshal(req.AllowedDevices)
+	// 校验类型和每周时段
+	if req.Type == "" {
+		req.Type = models.StaticQRTypeURL
+	}
+	if err := validateStaticQRType(req.Type, req.TargetURL); err != nil {
+		return nil, err
+	}
+	if err := validateSchedule(req.Schedule); err != nil {
+		return nil, err
+	}
+	if err := validateRegionList(req.AllowedRegions); err != nil {
+		return nil, err
+	}
+	if err := validateDeviceList(req.AllowedDevices); err != nil {
+		return nil, err
+	}
+	if err := validateUTMParams(req.UTMParams); err != nil {
+		return nil, err
+	}
+	if err := validateClientList(req.AllowedClients, "允许的客户端"); err != nil {
+		return nil, err
+	}
+	if err := validateClientList(req.PreferredClients, "优先的客户端"); err != nil {
+		return nil, err
+	}
+	if err := validateLanguageList(req.AllowedLanguages); err != nil {
+		return nil, err
+	}
+	if err := validateCondition(req.Condition); err != nil {
+		return nil, err
+	}
 
 	staticQR := &models.StaticQRCode{
-		ActiveQRCodeID: activeQRCodeID,
-		Name:           req.Name,
-		TargetURL:      req.TargetURL,
-		Weight:         req.Weight,
-		StartTime:      req.StartTime,
-		EndTime:        req.EndTime,
-		AllowedRegions: string(allowedRegionsJSON),
-		AllowedDevices: string(allowedDevicesJSON),
-		Status:         1,
+		ActiveQRCodeID:   activeQRCodeID,
+		Name:             req.Name,
+		TargetURL:        req.TargetURL,
+		Type:             req.Type,
+		LandingTitle:     req.LandingTitle,
+		LandingText:      req.LandingText,
+		LandingHint:      req.LandingHint,
+		ExpiresAt:        req.ExpiresAt,
+		UTMParams:        req.UTMParams,
+		Weight:           req.Weight,
+		Priority:         req.Priority,
+		StartTime:        req.StartTime,
+		EndTime:          req.EndTime,
+		AllowedRegions:   req.AllowedRegions,
+		AllowedDevices:   req.AllowedDevices,
+		AllowedClients:   req.AllowedClients,
+		PreferredClients: req.PreferredClients,
+		AllowedLanguages: req.AllowedLanguages,
+		Condition:        strings.TrimSpace(req.Condition),
+		OpenInBrowser:    req.OpenInBrowser,
+		Schedule:         req.Schedule,
+		ExcludeUnhealthy: req.ExcludeUnhealthy,
+		MaxScans:         req.MaxScans,
+		MaxDailyScans:    req.MaxDailyScans,
+		Status:           1,
 	}
 
 	if staticQR.Weight <= 0 {
 		staticQR.Weight = 1
 	}
+	if staticQR.ExpiresAt == nil {
+		staticQR.ExpiresAt = groupInviteExpiresAt(staticQR.TargetURL, time.Now())
+	}
 
 	if err := s.db.Create(staticQR).Error; err != nil {
 		return nil, fmt.Errorf("failed to create static QR code: %v", err)
@@ -116,8 +202,197 @@ func (s *ActiveQRCodeService) AddStaticQRCode(activeQRCodeID uint, req *models.S
 	return staticQR, nil
 }
 
+// UpdateStaticQRCode 更新静态码
+func (s *ActiveQRCodeService) UpdateStaticQRCode(id uint, req *models.StaticQRCodeUpdateRequest) (*models.StaticQRCode, error) {
+	var staticQR models.StaticQRCode
+	if err := s.db.First(&staticQR, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, &models.AppError{
+				Code:    "STATIC_QR_NOT_FOUND",
+				Message: "静态码不存在",
+			}
+		}
+		return nil, err
+	}
+
+	// 更新字段
+	if req.Name != nil {
+		staticQR.Name = *req.Name
+	}
+	if req.TargetURL != nil {
+		// 更换为新的微信群邀请时重新计算过期时间
+		if *req.TargetURL != staticQR.TargetURL && req.ExpiresAt == nil && !req.ClearExpiresAt {
+			if expiresAt := groupInviteExpiresAt(*req.TargetURL, time.Now()); expiresAt != nil {
+				staticQR.ExpiresAt = expiresAt
+				staticQR.ExpiryNotifiedAt = nil
+			}
+		}
+		// 目标链接变更后旧的健康状态不再适用，等待下次检查重新判定
+		if *req.TargetURL != staticQR.TargetURL {
+			staticQR.HealthStatus = ""
+			staticQR.HealthFailures = 0
+			staticQR.HealthCheckedAt = nil
+		}
+		staticQR.TargetURL = *req.TargetURL
+	}
+	if req.ExpiresAt != nil {
+		staticQR.ExpiresAt = req.ExpiresAt
+		staticQR.ExpiryNotifiedAt = nil
+	}
+	if req.ClearExpiresAt {
+		staticQR.ExpiresAt = nil
+		staticQR.ExpiryNotifiedAt = nil
+	}
+	if req.Type != nil {
+		staticQR.Type = *req.Type
+	}
+	if req.Type != nil || req.TargetURL != nil {
+		if err := validateStaticQRType(staticQR.Type, staticQR.TargetURL); err != nil {
+			return nil, err
+		}
+	}
+	if req.UTMParams != nil {
+		if err := validateUTMParams(*req.UTMParams); err != nil {
+			return nil, err
+		}
+		staticQR.UTMParams = *req.UTMParams
+	}
+	if req.LandingTitle != nil {
+		staticQR.LandingTitle = *req.LandingTitle
+	}
+	if req.LandingText != nil {
+		staticQR.LandingText = *req.LandingText
+	}
+	if req.LandingHint != nil {
+		staticQR.LandingHint = *req.LandingHint
+	}
+	if req.Weight != nil {
+		staticQR.Weight = *req.Weight
+	}
+	if req.Priority != nil {
+		staticQR.Priority = *req.Priority
+	}
+	if req.Status != nil {
+		staticQR.Status = *req.Status
+	}
+	if req.StartTime != nil {
+		staticQR.StartTime = req.StartTime
+	}
+	if req.EndTime != nil {
+		staticQR.EndTime = req.EndTime
+	}
+	if req.AllowedRegions != nil {
+		if err := validateRegionList(*req.AllowedRegions); err != nil {
+			return nil, err
+		}
+		staticQR.AllowedRegions = *req.AllowedRegions
+	}
+	if req.AllowedDevices != nil {
+		if err := validateDeviceList(*req.AllowedDevices); err != nil {
+			return nil, err
+		}
+		staticQR.AllowedDevices = *req.AllowedDevices
+	}
+	if req.AllowedClients != nil {
+		if err := validateClientList(*req.AllowedClients, "允许的客户端"); err != nil {
+			return nil, err
+		}
+		staticQR.AllowedClients = *req.AllowedClients
+	}
+	if req.PreferredClients != nil {
+		if err := validateClientList(*req.PreferredClients, "优先的客户端"); err != nil {
+			return nil, err
+		}
+		staticQR.PreferredClients = *req.PreferredClients
+	}
+	if req.AllowedLanguages != nil {
+		if err := validateLanguageList(*req.AllowedLanguages); err != nil {
+			return nil, err
+		}
+		staticQR.AllowedLanguages = *req.AllowedLanguages
+	}
+	if req.Condition != nil {
+		if err := validateCondition(*req.Condition); err != nil {
+			return nil, err
+		}
+		staticQR.Condition = strings.TrimSpace(*req.Condition)
+	}
+	if req.OpenInBrowser != nil {
+		staticQR.OpenInBrowser = *req.OpenInBrowser
+	}
+	if req.Schedule != nil {
+		if err := validateSchedule(*req.Schedule); err != nil {
+			return nil, err
+		}
+		staticQR.Schedule = *req.Schedule
+	}
+	if req.ExcludeUnhealthy != nil {
+		staticQR.ExcludeUnhealthy = *req.ExcludeUnhealthy
+	}
+	if req.MaxScans != nil {
+		staticQR.MaxScans = *req.MaxScans
+	}
+	if req.MaxDailyScans != nil {
+		staticQR.MaxDailyScans = *req.MaxDailyScans
+	}
+
+	// 扫码计数由跳转时原子累加，除非显式清零，否则不覆盖
+	query := s.db
+	if req.ResetScanCount {
+		staticQR.ScanCount = 0
+		staticQR.DailyScanCount = 0
+		staticQR.DailyScanDate = ""
+		staticQR.DailyResetAt = nil
+	} else {
+		query = query.Omit("scan_count", "daily_scan_count", "daily_scan_date", "daily_reset_at")
+	}
+
+	// 根据新的上限重新计算是否已满
+	if req.MaxScans != nil || req.ResetScanCount {
+		full := staticQR.MaxScans > 0 && staticQR.ScanCount >= staticQR.MaxScans
+		if full && !staticQR.IsFull {
+			now := time.Now()
+			staticQR.FullAt = &now
+		}
+		if !full {
+			staticQR.FullAt = nil
+		}
+		staticQR.IsFull = full
+	}
+
+	if err := query.Save(&staticQR).Error; err != nil {
+		return nil, err
+	}
+
+	// 重新加载关联数据
+	if err := s.db.Preload("ActiveQRCode").First(&staticQR, staticQR.ID).Error; err != nil {
+		return nil, err
+	}
+
+	return &staticQR, nil
+}
+
+// ScanResult 扫码跳转结果
+type ScanResult struct {
+	ActiveQR      *models.ActiveQRCode
+	StaticQR      *models.StaticQRCode
+	TargetURL     string
+	RedirectMode  string // 生效的跳转方式
+	OpenInBrowser bool   // 需要引导用户在系统浏览器中打开（目标链接会被微信拦截）
+	VisitorID     string // 粘性分配使用的访客ID，处理器据此写入Cookie；非粘性活码为空
+}
+
 // GetTargetURL 根据活码短码和扫描环境获取目标URL
-func (s *ActiveQRCodeService) GetTargetURL(shortCode, userAgent, ipAddress, region string) (string, error) {
+func (s *ActiveQRCodeService) GetTargetURL(shortCode string, scan *ScanContext) (string, error) {
+	result, err := s.ResolveScan(shortCode, scan)
+	if err != nil {
+		return "", err
+	}
+	return result.TargetURL, nil
+}
+
+// ResolveScan 根据活码短码和扫描环境选择目标静态码
+func (s *ActiveQRCodeService) ResolveScan(shortCode string, scan *ScanContext) (*ScanResult, error) {
 	// 先查找活码（不考虑状态）
 	var activeQR models.ActiveQRCode
 	err := s.db.Where("short_code = ?", shortCode).
@@ -126,191 +401,407 @@ func (s *ActiveQRCodeService) GetTargetURL(shortCode, userAgent, ipAddress, regi
 
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return "", &QRCodeError{
+			return nil, &QRCodeError{
 				Code:    "NOT_FOUND",
 				Message: "二维码不存在",
 			}
 		}
-		return "", &QRCodeError{
+		return nil, &QRCodeError{
 			Code:    "NOT_FOUND",
 			Message: "查询失败",
 		}
 	}
 
-	// 调试信息：输出找到的活码信息
-	fmt.Printf("[DEBUG] Found activeQR: ID=%d, Name=%s, Status=%d, StaticQRCodes count=%d\n",
-		activeQR.ID, activeQR.Name, activeQR.Status, len(activeQR.StaticQRCodes))
+	// 补全扫描环境，扫码时间按活码所在时区计算
+	s.completeScan(&activeQR, scan, time.Now())
 
-	// 检查活码是否被禁用
-	if activeQR.Status != 1 {
-		return "", &QRCodeError{
-			Code:    "DISABLED",
-			Message: "二维码已被禁用",
-		}
+	// 筛选可用的静态码，与模拟扫码共用同一流程
+	candidates := filterScanCandidates(&activeQR, scan)
+	if qrErr := candidates.scanError(&activeQR); qrErr != nil {
+		return nil, s.failScan(&activeQR, scan, qrErr)
 	}
+	availableQRs := candidates.available
 
-	// 筛选启用的静态码
-	var enabledStaticQRs []models.StaticQRCode
-	for _, sqr := range activeQR.StaticQRCodes {
-		fmt.Printf("[DEBUG] StaticQR: ID=%d, Name=%s, Status=%d, TargetURL=%s\n",
-			sqr.ID, sqr.Name, sqr.Status, sqr.TargetURL)
-		if sqr.Status == 1 {
-			enabledStaticQRs = append(enabledStaticQRs, sqr)
-		}
+	// 根据切换规则选择目标静态码
+	strategy := switchStrategy(&activeQR)
+	strategyCtx := &StrategyContext{
+		DB:       s.db,
+		ActiveQR: &activeQR,
+		Scan:     scan,
 	}
 
-	fmt.Printf("[DEBUG] Enabled StaticQRs count: %d\n", len(enabledStaticQRs))
-
-	if len(enabledStaticQRs) == 0 {
-		return "", &QRCodeError{
-			Code:    "NO_STATIC_QR",
-			Message: "暂无可用的目标链接",
+	// 粘性分配：同一访客优先跳转到上次分配且仍然可用的静态码（仅限最高优先级，主码恢复后不再停留在备用码）
+	var selectedQR *models.StaticQRCode
+	stickyHit := false
+	if activeQR.Sticky {
+		if assigned := s.findStickyAssignment(&activeQR, scan, highestPriorityTier(availableQRs)); assigned != nil {
+			if s.tryAcquireScanSlot(assigned, scan.Time) {
+				selectedQR = assigned
+				stickyHit = true
+			} else {
+				availableQRs = removeStaticQRCode(availableQRs, assigned.ID)
+			}
+		}
+		// 首次访问粘性活码时生成访客ID，由处理器写入Cookie
+		if scan.VisitorID == "" {
+			scan.VisitorID = newVisitorID()
 		}
 	}
 
-	// 筛选可用的静态码
-	availableQRs := s.filterAvailableQRCodes(enabledStaticQRs, userAgent, region)
-	fmt.Printf("[DEBUG] Available QRs count after filtering: %d\n", len(availableQRs))
+	// 在可用的最高优先级中选择，选中的静态码需要占用一个扫码名额，
+	// 名额已满时排除后重新选择，该优先级全部已满时切换到下一级
+	for selectedQR == nil && len(availableQRs) > 0 {
+		candidate, err := strategy.Select(strategyCtx, highestPriorityTier(availableQRs))
+		if err != nil {
+			log.Printf("Strategy %s select failed: %v", strategy.Info().Name, err)
+		}
+		if candidate == nil {
+			break
+		}
 
-	if len(availableQRs) == 0 {
-		return "", &QRCodeError{
-			Code:    "NO_MATCHING_QR",
-			Message: "当前环境无匹配的目标链接",
+		if s.tryAcquireScanSlot(candidate, scan.Time) {
+			selectedQR = candidate
+			break
 		}
-	}
 
-	// 根据切换规则选择目标静态码
-	var selectedQR *models.StaticQRCode
-	switch activeQR.SwitchRule {
-	case "random":
-		selectedQR = s.selectRandomQR(availableQRs)
-	case "weight":
-		selectedQR = s.selectWeightedQR(availableQRs)
-	case "time":
-		selectedQR = s.selectTimeBasedQR(availableQRs)
-	default:
-		selectedQR = s.selectWeightedQR(availableQRs) // 默认按权重
+		// 名额已满或多次重试仍无法占用名额，排除后重新选择
+		availableQRs = removeStaticQRCode(availableQRs, candidate.ID)
 	}
 
 	if selectedQR == nil {
-		return "", &QRCodeError{
+		return nil, s.failScan(&activeQR, scan, &QRCodeError{
 			Code:    "NO_MATCHING_QR",
 			Message: "无法选择目标链接",
+		})
+	}
+
+	// 保存或续期粘性分配
+	if activeQR.Sticky {
+		s.saveStickyAssignment(&activeQR, scan, selectedQR.ID)
+	}
+
+	scanRecord := newScanRecord(&activeQR, selectedQR, scan, stickyHit)
+
+	// 生成最终跳转链接：替换占位符并追加参数（图片类型展示落地页，不处理）
+	targetURL := selectedQR.TargetURL
+	if !selectedQR.IsImage() {
+		// {scan_id} 和 {conversion_token} 需要先写入扫描记录以获得ID
+		if needsScanRecordID(targetURL) {
+			if err := s.db.Create(scanRecord).Error; err != nil {
+				log.Printf("Create scan record failed: %v", err)
+			}
 		}
+		targetURL = expandTargetURL(targetURL, s.placeholderValues(&activeQR, selectedQR, scan, scanRecord.ID))
+		targetURL = buildTargetURL(targetURL, activeQR.PassQuery, scan.Query, selectedQR.UTMParams)
 	}
+	scanRecord.TargetURL = targetURL
 
 	// 记录扫描
-	go s.recordScan(&activeQR, selectedQR, userAgent, ipAddress, region)
+	go s.recordScan(scanRecord)
+
+	return &ScanResult{
+		ActiveQR:      &activeQR,
+		StaticQR:      selectedQR,
+		TargetURL:     targetURL,
+		RedirectMode:  s.redirectMode(&activeQR),
+		OpenInBrowser: needsOpenInBrowser(selectedQR, scan.Agent.Client, targetURL),
+		VisitorID:     visitorIDFor(&activeQR, scan),
+	}, nil
+}
 
-	return selectedQR.TargetURL, nil
+// ResolveRegion 根据IP解析地区，解析失败时返回空地区
+//
+// 不在数据库覆盖范围内的IP（如 ip2region 不支持的IPv6）很常见，不记录日志。
+func (s *ActiveQRCodeService) ResolveRegion(ipAddress string) geoip.Region {
+	region, err := s.regionResolver.Resolve(ipAddress)
+	if err != nil {
+		if errors.Is(err, geoip.ErrNotCovered) {
+			return geoip.Region{}
+		}
+		log.Printf("Resolve region of %s failed: %v", ipAddress, err)
+		return geoip.Region{}
+	}
+	return region
 }
 
-// filterAvailableQRCodes 筛选可用的静态二维码
-func (s *ActiveQRCodeService) filterAvailableQRCodes(staticQRs []models.StaticQRCode, userAgent, region string) []models.StaticQRCode {
-	var available []models.StaticQRCode
-	now := time.Now()
-	device := s.detectDevice(userAgent)
+// completeScan 补全扫描环境：设备、User-Agent、首选语言和地区，并将扫码时间转换到活码所在时区
+//
+// scan.Region 已指定时（模拟扫码）不再根据IP解析。
+func (s *ActiveQRCodeService) completeScan(activeQR *models.ActiveQRCode, scan *ScanContext, now time.Time) {
+	loc, err := schedule.LoadLocation(activeQR.TimeZone)
+	if err != nil {
+		log.Printf("Invalid time zone %q of active QR %d, using server time zone: %v", activeQR.TimeZone, activeQR.ID, err)
+		loc = time.Local
+	}
+
+	scan.Device = s.detectDevice(scan.UserAgent)
+	scan.Agent = useragent.Parse(scan.UserAgent)
+	scan.Language = language.Preferred(scan.AcceptLanguage)
+	if scan.Region.IsEmpty() {
+		scan.Region = s.ResolveRegion(scan.IPAddress)
+	}
+	scan.Time = now.In(loc)
+}
 
-	fmt.Printf("[DEBUG] Filter params: userAgent=%s, region=%s, device=%s, now=%v\n", userAgent, region, device, now)
+// switchStrategy 返回活码的切换规则，未知规则使用默认规则
+func switchStrategy(activeQR *models.ActiveQRCode) Strategy {
+	strategy, ok := GetStrategy(activeQR.SwitchRule)
+	if !ok {
+		log.Printf("Unknown switch rule %q of active QR %d, using %s", activeQR.SwitchRule, activeQR.ID, DefaultSwitchRule)
+		strategy, _ = GetStrategy(DefaultSwitchRule)
+	}
+	return strategy
+}
 
-	for _, qr := range staticQRs {
-		fmt.Printf("[DEBUG] Filtering StaticQR ID=%d, Name=%s\n", qr.ID, qr.Name)
-		fmt.Printf("[DEBUG] - StartTime: %v, EndTime: %v\n", qr.StartTime, qr.EndTime)
-		fmt.Printf("[DEBUG] - AllowedRegions: %s\n", qr.AllowedRegions)
-		fmt.Printf("[DEBUG] - AllowedDevices: %s\n", qr.AllowedDevices)
+// scanCandidates 一次扫码中静态码的筛选结果
+type scanCandidates struct {
+	enabled    []models.StaticQRCode     // 已启用的静态码
+	available  []models.StaticQRCode     // 通过全部筛选的静态码，切换规则从其中最高的优先级开始选择
+	rejections map[uint]*FilterRejection // 未通过筛选的静态码及原因，优先级较低的静态码也记录在内
+}
 
-		// 检查时间范围
-		if qr.StartTime != nil && now.Before(*qr.StartTime) {
-			fmt.Printf("[DEBUG] - REJECTED: StartTime check failed\n")
+// filterScanCandidates 依次按启用状态、各项筛选条件、语言协商和优先客户端筛选静态码
+//
+// 扫码跳转和模拟扫码都通过这里筛选，保证两者的结果一致。
+func filterScanCandidates(activeQR *models.ActiveQRCode, scan *ScanContext) *scanCandidates {
+	c := &scanCandidates{rejections: make(map[uint]*FilterRejection)}
+
+	var passed []models.StaticQRCode
+	for i := range activeQR.StaticQRCodes {
+		qr := &activeQR.StaticQRCodes[i]
+		if qr.Status != 1 {
+			c.rejections[qr.ID] = &FilterRejection{FilterStatus, "静态码已禁用"}
 			continue
 		}
-		if qr.EndTime != nil && now.After(*qr.EndTime) {
-			fmt.Printf("[DEBUG] - REJECTED: EndTime check failed\n")
+		c.enabled = append(c.enabled, *qr)
+
+		if rejection := checkStaticQRCode(qr, scan); rejection != nil {
+			c.rejections[qr.ID] = rejection
 			continue
 		}
+		passed = append(passed, *qr)
+	}
 
-		// 检查地区限制
-		if qr.AllowedRegions != "" && qr.AllowedRegions != "null" {
-			var allowedRegions []string
-			if err := json.Unmarshal([]byte(qr.AllowedRegions), &allowedRegions); err == nil {
-				fmt.Printf("[DEBUG] - Parsed AllowedRegions: %v\n", allowedRegions)
-				if len(allowedRegions) > 0 && !contains(allowedRegions, region) {
-					fmt.Printf("[DEBUG] - REJECTED: Region check failed\n")
-					continue
-				}
-			}
+	// 按Accept-Language协商语言，只保留匹配该语言的静态码
+	matched := filterLanguageMatches(passed, scan)
+	negotiated := ""
+	for _, qr := range matched {
+		if len(parseJSONList(qr.AllowedLanguages)) > 0 {
+			negotiated = scan.Language
+			break
 		}
-
-		// 检查设备限制
-		if qr.AllowedDevices != "" && qr.AllowedDevices != "null" {
-			var allowedDevices []string
-			if err := json.Unmarshal([]byte(qr.AllowedDevices), &allowedDevices); err == nil {
-				fmt.Printf("[DEBUG] - Parsed AllowedDevices: %v\n", allowedDevices)
-				if len(allowedDevices) > 0 && !contains(allowedDevices, device) {
-					fmt.Printf("[DEBUG] - REJECTED: Device check failed\n")
-					continue
-				}
-			}
+	}
+	for _, qr := range excludedStaticQRCodes(passed, matched) {
+		reason := fmt.Sprintf("Accept-Language %q 与允许的语言 %s 协商失败", scan.AcceptLanguage, qr.AllowedLanguages)
+		if negotiated != "" && len(parseJSONList(qr.AllowedLanguages)) == 0 {
+			reason = fmt.Sprintf("已协商出语言 %s，优先使用声明了该语言的静态码", negotiated)
 		}
+		c.rejections[qr.ID] = &FilterRejection{FilterLanguage, reason}
+	}
+
+	// 有静态码将当前客户端设为优先时，只在这些静态码中选择
+	c.available = preferClientMatches(matched, scan.Agent.Client)
+	for _, qr := range excludedStaticQRCodes(matched, c.available) {
+		c.rejections[qr.ID] = &FilterRejection{FilterPreferredClient, fmt.Sprintf("存在将客户端 %s 设为优先的静态码", scan.Agent.Client)}
+	}
 
-		fmt.Printf("[DEBUG] - ACCEPTED: StaticQR ID=%d passed all filters\n", qr.ID)
-		available = append(available, qr)
+	tier := highestPriorityTier(c.available)
+	for _, qr := range excludedStaticQRCodes(c.available, tier) {
+		c.rejections[qr.ID] = &FilterRejection{FilterPriority, fmt.Sprintf("优先级 %d 低于可用的最高优先级 %d", qr.Priority, tier[0].Priority)}
 	}
 
-	return available
+	return c
 }
 
-// selectRandomQR 随机选择
-func (s *ActiveQRCodeService) selectRandomQR(qrs []models.StaticQRCode) *models.StaticQRCode {
-	if len(qrs) == 0 {
-		return nil
+// scanError 返回无法跳转时的错误，可以跳转时返回nil
+func (c *scanCandidates) scanError(activeQR *models.ActiveQRCode) *QRCodeError {
+	switch {
+	case activeQR.Status != 1:
+		return &QRCodeError{Code: "DISABLED", Message: "二维码已被禁用"}
+	case len(c.enabled) == 0:
+		return &QRCodeError{Code: "NO_STATIC_QR", Message: "暂无可用的目标链接"}
+	case len(c.available) == 0:
+		return &QRCodeError{Code: "NO_MATCHING_QR", Message: "当前环境无匹配的目标链接"}
 	}
-	n, _ := rand.Int(rand.Reader, big.NewInt(int64(len(qrs))))
-	return &qrs[n.Int64()]
+	return nil
 }
 
-// selectWeightedQR 按权重选择
-func (s *ActiveQRCodeService) selectWeightedQR(qrs []models.StaticQRCode) *models.StaticQRCode {
-	if len(qrs) == 0 {
-		return nil
+// excludedStaticQRCodes 返回在 all 中但不在 kept 中的静态码
+func excludedStaticQRCodes(all, kept []models.StaticQRCode) []models.StaticQRCode {
+	keptIDs := make(map[uint]bool, len(kept))
+	for _, qr := range kept {
+		keptIDs[qr.ID] = true
+	}
+	var excluded []models.StaticQRCode
+	for _, qr := range all {
+		if !keptIDs[qr.ID] {
+			excluded = append(excluded, qr)
+		}
+	}
+	return excluded
+}
+
+// 静态码筛选条件名称
+const (
+	FilterStatus          = "status"
+	FilterImage           = "image"
+	FilterExpired         = "expired"
+	FilterStartTime       = "start_time"
+	FilterEndTime         = "end_time"
+	FilterSchedule        = "schedule"
+	FilterHealth          = "health"
+	FilterMaxScans        = "max_scans"
+	FilterMaxDailyScans   = "max_daily_scans"
+	FilterRegion          = "region"
+	FilterDevice          = "device"
+	FilterClient          = "client"
+	FilterCondition       = "condition"
+	FilterLanguage        = "language"
+	FilterPreferredClient = "preferred_client"
+	FilterPriority        = "priority"
+)
+
+// FilterRejection 静态码未通过的筛选条件及原因
+type FilterRejection struct {
+	Filter string `json:"filter"`
+	Reason string `json:"reason"`
+}
+
+// checkStaticQRCode 依次检查静态码的各项筛选条件，全部通过时返回nil
+//
+// 语言、优先客户端和优先级需要与其他静态码比较，分别由 filterLanguageMatches、preferClientMatches 和 highestPriorityTier 处理。
+func checkStaticQRCode(qr *models.StaticQRCode, scan *ScanContext) *FilterRejection {
+	now := scan.Time
+
+	// 图片类型尚未上传图片时无法展示
+	if qr.IsImage() && qr.ImagePath == "" {
+		return &FilterRejection{FilterImage, "图片类型尚未上传二维码图片"}
 	}
 
-	// 计算总权重
-	totalWeight := 0
-	for _, qr := range qrs {
-		totalWeight += qr.Weight
+	// 检查是否已过期（如微信群二维码7天有效期）
+	if qr.ExpiresAt != nil && !now.Before(*qr.ExpiresAt) {
+		return &FilterRejection{FilterExpired, fmt.Sprintf("已于 %s 过期", qr.ExpiresAt.In(now.Location()).Format("2006-01-02 15:04:05"))}
 	}
 
-	if totalWeight == 0 {
-		return s.selectRandomQR(qrs)
+	// 检查时间范围
+	if qr.StartTime != nil && now.Before(*qr.StartTime) {
+		return &FilterRejection{FilterStartTime, fmt.Sprintf("尚未到生效时间 %s", qr.StartTime.In(now.Location()).Format("2006-01-02 15:04:05"))}
+	}
+	if qr.EndTime != nil && now.After(*qr.EndTime) {
+		return &FilterRejection{FilterEndTime, fmt.Sprintf("已超过结束时间 %s", qr.EndTime.In(now.Location()).Format("2006-01-02 15:04:05"))}
+	}
+
+	// 检查每周时段及例外日期
+	if sched, err := schedule.Parse(qr.Schedule); err == nil && sched != nil && !sched.IsActive(now) {
+		return &FilterRejection{FilterSchedule, fmt.Sprintf("%s 不在生效时段内", now.Format("2006-01-02 15:04 Mon"))}
+	}
+
+	// 检查目标链接健康状态（仅开启了异常时暂停分配的静态码）
+	if isUnhealthyExcluded(qr) {
+		return &FilterRejection{FilterHealth, fmt.Sprintf("目标链接连续%d次健康检查失败", qr.HealthFailures)}
+	}
+
+	// 检查扫码名额
+	if qr.IsFull {
+		return &FilterRejection{FilterMaxScans, fmt.Sprintf("累计扫码已达上限 %d", qr.MaxScans)}
+	}
+	if qr.IsDailyFull(now.Format("2006-01-02")) {
+		return &FilterRejection{FilterMaxDailyScans, fmt.Sprintf("今日扫码已达上限 %d", qr.MaxDailyScans)}
 	}
 
-	// 生成随机数
-	randNum, _ := rand.Int(rand.Reader, big.NewInt(int64(totalWeight)))
-	target := int(randNum.Int64())
+	// 检查地区限制（匹配国家、省份或城市任一级别即可）
+	if allowedRegions := parseJSONList(qr.AllowedRegions); len(allowedRegions) > 0 {
+		if scan.Region.BestMatchLevel(allowedRegions) == geoip.MatchNone {
+			return &FilterRejection{FilterRegion, fmt.Sprintf("地区 %q 不在允许列表 %v 中", scan.Region.String(), allowedRegions)}
+		}
+	}
 
-	// 根据权重选择
-	currentWeight := 0
-	for i, qr := range qrs {
-		currentWeight += qr.Weight
-		if target < currentWeight {
-			return &qrs[i]
+	// 检查设备限制
+	if qr.AllowedDevices != "" && qr.AllowedDevices != "null" {
+		var allowedDevices []string
+		if err := json.Unmarshal([]byte(qr.AllowedDevices), &allowedDevices); err == nil {
+			if len(allowedDevices) > 0 && !contains(allowedDevices, scan.Device) {
+				return &FilterRejection{FilterDevice, fmt.Sprintf("设备 %q 不在允许列表 %v 中", scan.Device, allowedDevices)}
+			}
 		}
 	}
 
-	return &qrs[0]
+	// 检查客户端（App内置浏览器）限制
+	if allowedClients := parseJSONList(qr.AllowedClients); len(allowedClients) > 0 && !contains(allowedClients, scan.Agent.Client) {
+		return &FilterRejection{FilterClient, fmt.Sprintf("客户端 %q 不在允许列表 %v 中", scan.Agent.Client, allowedClients)}
+	}
+
+	// 检查定向条件表达式
+	if !matchCondition(qr.Condition, scan) {
+		return &FilterRejection{FilterCondition, fmt.Sprintf("不满足定向条件: %s", qr.Condition)}
+	}
+
+	return nil
 }
 
-// selectTimeBasedQR 基于时间选择（轮询）
-func (s *ActiveQRCodeService) selectTimeBasedQR(qrs []models.StaticQRCode) *models.StaticQRCode {
-	if len(qrs) == 0 {
-		return nil
+// scanSlotMaxAttempts 占用扫码名额遇到数据库错误（如SQLite写锁冲突）时的最大尝试次数
+const scanSlotMaxAttempts = 3
+
+// tryAcquireScanSlot 占用扫码名额，数据库错误视为暂时性错误并重试，名额已满或重试耗尽时返回false
+func (s *ActiveQRCodeService) tryAcquireScanSlot(qr *models.StaticQRCode, now time.Time) bool {
+	for attempt := 1; ; attempt++ {
+		acquired, err := s.acquireScanSlot(qr, now)
+		if err == nil {
+			return acquired
+		}
+		if attempt >= scanSlotMaxAttempts {
+			log.Printf("Acquire scan slot for static QR %d failed after %d attempts: %v", qr.ID, attempt, err)
+			return false
+		}
+		time.Sleep(time.Duration(attempt) * 10 * time.Millisecond)
+	}
+}
+
+// acquireScanSlot 原子地为静态码占用一个扫码名额并累加计数，名额已满时返回false
+//
+// 计数和上限判断在同一条UPDATE语句中完成，并发扫码不会同时占用最后一个名额。
+func (s *ActiveQRCodeService) acquireScanSlot(qr *models.StaticQRCode, now time.Time) (bool, error) {
+	today := now.Format("2006-01-02")
+	resetAt := nextMidnight(now).In(time.Local)
+
+	result := s.db.Model(&models.StaticQRCode{}).
+		Where("id = ? AND is_full = ?", qr.ID, false).
+		Where("max_scans <= 0 OR scan_count < max_scans").
+		Where("max_daily_scans <= 0 OR daily_scan_date IS NULL OR daily_scan_date <> ? OR daily_scan_count < max_daily_scans", today).
+		Updates(map[string]interface{}{
+			"scan_count":       gorm.Expr("scan_count + 1"),
+			"daily_scan_count": gorm.Expr("CASE WHEN daily_scan_date = ? THEN daily_scan_count + 1 ELSE 1 END", today),
+			"daily_scan_date":  today,
+			"daily_reset_at":   resetAt,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	// 达到累计上限时自动标记为已满
+	if qr.MaxScans > 0 {
+		if err := s.markFullIfReached(qr.ID, now); err != nil {
+			log.Printf("Mark static QR %d full failed: %v", qr.ID, err)
+		}
 	}
 
-	// 基于当前小时进行轮询
-	hour := time.Now().Hour()
-	index := hour % len(qrs)
-	return &qrs[index]
+	return result.RowsAffected == 1, nil
+}
+
+// nextMidnight 返回 t 所在时区的次日零点
+func nextMidnight(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day+1, 0, 0, 0, 0, t.Location())
+}
+
+// markFullIfReached 静态码累计计数达到上限时标记为已满
+func (s *ActiveQRCodeService) markFullIfReached(id uint, now time.Time) error {
+	return s.db.Model(&models.StaticQRCode{}).
+		Where("id = ? AND is_full = ? AND max_scans > 0 AND scan_count >= max_scans", id, false).
+		Updates(map[string]interface{}{
+			"is_full": true,
+			"full_at": now.In(time.Local),
+		}).Error
 }
 
 // detectDevice 检测设备类型
@@ -325,21 +816,36 @@ func (s *ActiveQRCodeService) detectDevice(userAgent string) string {
 	return "desktop"
 }
 
-// recordScan 记录扫描
-func (s *ActiveQRCodeService) recordScan(activeQR *models.ActiveQRCode, selectedQR *models.StaticQRCode, userAgent, ipAddress, region string) {
-	device := s.detectDevice(userAgent)
-
-	scanRecord := &models.ScanRecord{
+// newScanRecord 根据本次扫码生成扫描记录
+func newScanRecord(activeQR *models.ActiveQRCode, selectedQR *models.StaticQRCode, scan *ScanContext, stickyHit bool) *models.ScanRecord {
+	params := trackingParams(scan.Query)
+	return &models.ScanRecord{
 		ActiveQRCodeID: &activeQR.ID,
 		StaticQRCodeID: &selectedQR.ID,
-		IPAddress:      ipAddress,
-		UserAgent:      userAgent,
-		ScanTime:       time.Now(),
-		Region:         region,
-		Device:         device,
-		TargetURL:      selectedQR.TargetURL,
+		IPAddress:      scan.IPAddress,
+		UserAgent:      scan.UserAgent,
+		ScanTime:       scan.recordTime(),
+		Region:         scan.Region.Name(),
+		Location:       scan.Region.String(),
+		Device:         scan.Device,
+		OS:             scan.Agent.OS,
+		OSVersion:      scan.Agent.OSVersion,
+		Browser:        scan.Agent.Browser,
+		Client:         scan.Agent.Client,
+		ClientVersion:  scan.Agent.ClientVersion,
+		Language:       scan.Language,
+		StickyHit:      stickyHit,
+		Channel:        scanChannel(params),
+		TrackingParams: encodeTrackingParams(params),
 	}
+}
 
+// recordScan 保存扫描记录，已提前写入的记录只更新最终跳转链接
+func (s *ActiveQRCodeService) recordScan(scanRecord *models.ScanRecord) {
+	if scanRecord.ID != 0 {
+		s.db.Model(scanRecord).Update("target_url", scanRecord.TargetURL)
+		return
+	}
 	s.db.Create(scanRecord)
 }
 
@@ -410,6 +916,19 @@ func (s *ActiveQRCodeService) GetActiveQRCode(id uint) (*models.ActiveQRCode, er
 
 // UpdateActiveQRCode 更新活码
 func (s *ActiveQRCodeService) UpdateActiveQRCode(id uint, req *models.ActiveQRCodeCreateRequest) (*models.ActiveQRCode, error) {
+	// 校验切换规则、时区和跳转方式
+	if err := ValidateSwitchRule(req.SwitchRule); err != nil {
+		return nil, err
+	}
+	if err := validateTimeZone(req.TimeZone); err != nil {
+		return nil, err
+	}
+	if req.RedirectMode != nil {
+		if err := validateRedirectMode(*req.RedirectMode); err != nil {
+			return nil, err
+		}
+	}
+
 	var activeQR models.ActiveQRCode
 	if err := s.db.First(&activeQR, id).Error; err != nil {
 		return nil, fmt.Errorf("active QR code not found: %v", err)
@@ -419,11 +938,40 @@ func (s *ActiveQRCodeService) UpdateActiveQRCode(id uint, req *models.ActiveQRCo
 	activeQR.Name = req.Name
 	activeQR.SwitchRule = req.SwitchRule
 	activeQR.Description = req.Description
+	if req.TimeZone != "" {
+		activeQR.TimeZone = req.TimeZone
+	}
+	if req.Sticky != nil {
+		activeQR.Sticky = *req.Sticky
+	}
+	if req.StickyDays != nil {
+		activeQR.StickyDays = *req.StickyDays
+	}
+	if req.RedirectMode != nil {
+		activeQR.RedirectMode = *req.RedirectMode
+	}
+	if req.PassQuery != nil {
+		activeQR.PassQuery = *req.PassQuery
+	}
+	if err := applyFallbackConfig(&activeQR, req); err != nil {
+		return nil, err
+	}
+	if err := applyQRImageConfig(s.qrGenerator, &activeQR, req); err != nil {
+		return nil, err
+	}
+	if err := s.applyQRDesign(&activeQR, req); err != nil {
+		return nil, err
+	}
 
 	if err := s.db.Save(&activeQR).Error; err != nil {
 		return nil, fmt.Errorf("failed to update active QR code: %v", err)
 	}
 
+	// 图片参数或样式可能已变化，重新生成图片文件
+	if err := s.saveActiveQRImage(&activeQR); err != nil {
+		return nil, err
+	}
+
 	// 重新加载带关联数据的记录
 	return s.GetActiveQRCode(id)
 }
@@ -439,52 +987,194 @@ func (s *ActiveQRCodeService) DeleteActiveQRCode(id uint) error {
 	// 开始事务
 	tx := s.db.Begin()
 
+	// 删除静态码的健康检查记录
+	staticIDs := tx.Model(&models.StaticQRCode{}).Select("id").Where("active_qr_code_id = ?", id)
+	if err := tx.Where("static_qr_code_id IN (?)", staticIDs).Delete(&models.TargetHealthCheck{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete health checks: %v", err)
+	}
+
 	// 删除关联的静态码
 	if err := tx.Where("active_qr_code_id = ?", id).Delete(&models.StaticQRCode{}).Error; err != nil {
 		tx.Rollback()
 		return fmt.Errorf("failed to delete static QR codes: %v", err)
 	}
 
+	// 删除轮询游标
+	if err := tx.Where("active_qr_code_id = ?", id).Delete(&models.RoundRobinCursor{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete round robin cursor: %v", err)
+	}
+
+	// 删除粘性分配记录
+	if err := tx.Where("active_qr_code_id = ?", id).Delete(&models.StickyAssignment{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete sticky assignments: %v", err)
+	}
+
+	// 删除A/B实验及转化记录
+	experimentIDs := tx.Model(&models.Experiment{}).Select("id").Where("active_qr_code_id = ?", id)
+	if err := tx.Where("experiment_id IN (?)", experimentIDs).Delete(&models.ExperimentVariant{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete experiment variants: %v", err)
+	}
+	if err := tx.Where("active_qr_code_id = ?", id).Delete(&models.Experiment{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete experiments: %v", err)
+	}
+	if err := tx.Where("active_qr_code_id = ?", id).Delete(&models.Conversion{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete conversions: %v", err)
+	}
+
 	// 删除活码
 	if err := tx.Delete(&activeQR).Error; err != nil {
 		tx.Rollback()
 		return fmt.Errorf("failed to delete active QR code: %v", err)
 	}
 
-	return tx.Commit().Error
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	// 删除上传的Logo
+	if activeQR.QRLogoPath != "" {
+		os.Remove(activeQR.QRLogoPath)
+	}
+	return nil
 }
 
-// GetActiveQRCodeImage 获取活码二维码图片
-func (s *ActiveQRCodeService) GetActiveQRCodeImage(id uint) ([]byte, error) {
+// GetActiveQRCodeImage 获取活码二维码图片，图片参数依次取请求参数、活码设置和全局配置
+func (s *ActiveQRCodeService) GetActiveQRCodeImage(id uint, req *models.QRImageRequest) ([]byte, qrcode.Format, error) {
 	var activeQR models.ActiveQRCode
 	if err := s.db.First(&activeQR, id).Error; err != nil {
-		return nil, fmt.Errorf("active QR code not found: %v", err)
+		return nil, "", fmt.Errorf("active QR code not found: %v", err)
+	}
+
+	tmpl, err := loadQRStyle(s.db, activeQR.QRStyleID)
+	if err != nil {
+		return nil, "", err
+	}
+	base, style, err := activeQRAppearance(s.qrGenerator, tmpl, &activeQR)
+	if err != nil {
+		return nil, "", err
+	}
+	format, opts, err := resolveQRImageRequest(base, req)
+	if err != nil {
+		return nil, "", err
 	}
 
-	// 重新生成二维码图片
+	// 按参数、品牌样式和海报边框生成（结果会被缓存），矢量格式用于海报和印刷
 	redirectURL := fmt.Sprintf("%s/r/%s", s.config.Server.BaseURL, activeQR.ShortCode)
+	frame := resolveQRFrame(tmpl, req, activeQR.Name)
+	imageData, err := s.qrGenerator.RenderFramed(redirectURL, format, opts, style, frame)
+	if err != nil {
+		// 请求参数与品牌样式不兼容（如PDF格式）时返回样式错误
+		return nil, "", designError(err)
+	}
 
-	// 如果有现有的文件路径，尝试读取
-	if activeQR.QRCodePath != "" {
-		imageData, err := s.qrGenerator.ReadQRCodeFile(activeQR.QRCodePath)
-		if err == nil {
-			return imageData, nil
+	return imageData, format, nil
+}
+
+// validateTimeZone 校验IANA时区名称，为空时使用默认时区
+func validateTimeZone(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, err := schedule.LoadLocation(name); err != nil {
+		return &models.AppError{
+			Code:    "INVALID_TIME_ZONE",
+			Message: err.Error(),
 		}
 	}
+	return nil
+}
 
-	// 如果文件不存在，重新生成
-	base64Data, err := s.qrGenerator.GenerateQRCodeBase64(redirectURL)
+// validateSchedule 校验每周时段配置
+func validateSchedule(value string) error {
+	sched, err := schedule.Parse(value)
+	if err == nil && sched != nil {
+		err = sched.Validate()
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate QR code image: %v", err)
+		return &models.AppError{
+			Code:    "INVALID_SCHEDULE",
+			Message: err.Error(),
+		}
+	}
+	return nil
+}
+
+// deviceTypes detectDevice 识别的设备类型
+var deviceTypes = []string{"mobile", "tablet", "desktop"}
+
+// validateRegionList 校验允许的地区（JSON数组，如 ["广东", "深圳市"]）
+func validateRegionList(value string) error {
+	regions, err := unmarshalJSONList(value, "允许的地区")
+	if err != nil {
+		return err
+	}
+	for _, region := range regions {
+		if strings.TrimSpace(region) == "" {
+			return &models.AppError{Code: "INVALID_PARAMS", Message: "允许的地区不能包含空字符串"}
+		}
 	}
+	return nil
+}
 
-	// 解码base64为字节数组
-	imageData, err := base64.StdEncoding.DecodeString(base64Data)
+// validateDeviceList 校验允许的设备类型（JSON数组）
+func validateDeviceList(value string) error {
+	devices, err := unmarshalJSONList(value, "允许的设备")
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode QR code image: %v", err)
+		return err
+	}
+	for _, device := range devices {
+		if !contains(deviceTypes, device) {
+			return &models.AppError{
+				Code:    "INVALID_PARAMS",
+				Message: fmt.Sprintf("允许的设备包含不支持的类型: %s，可选值: %s", device, strings.Join(deviceTypes, ", ")),
+			}
+		}
+	}
+	return nil
+}
+
+// unmarshalJSONList 解析JSON数组格式的限制条件，格式错误时返回 INVALID_PARAMS
+func unmarshalJSONList(value, field string) ([]string, error) {
+	if value == "" || value == "null" {
+		return nil, nil
+	}
+	var list []string
+	if err := json.Unmarshal([]byte(value), &list); err != nil {
+		return nil, &models.AppError{
+			Code:    "INVALID_PARAMS",
+			Message: fmt.Sprintf("%s格式错误，应为JSON数组: %v", field, err),
+		}
+	}
+	return list, nil
+}
+
+// parseJSONList 解析JSON数组格式的限制条件，空值或格式错误时返回nil
+func parseJSONList(value string) []string {
+	if value == "" || value == "null" {
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal([]byte(value), &list); err != nil {
+		return nil
 	}
+	return list
+}
 
-	return imageData, nil
+// removeStaticQRCode 从列表中移除指定ID的静态码
+func removeStaticQRCode(qrs []models.StaticQRCode, id uint) []models.StaticQRCode {
+	result := make([]models.StaticQRCode, 0, len(qrs))
+	for _, qr := range qrs {
+		if qr.ID != id {
+			result = append(result, qr)
+		}
+	}
+	return result
 }
 
 // contains 辅助函数：检查切片是否包含某个元素
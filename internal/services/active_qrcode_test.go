@@ -0,0 +1,203 @@
+package services
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"wechat-active-qrcode/internal/models"
+)
+
+func TestScanRecordUsesServerTime(t *testing.T) {
+	s, db := newTestService(t)
+	activeQR := createTestActiveQR(t, db, &models.ActiveQRCode{Name: "tz", SwitchRule: "weight", TimeZone: "Pacific/Kiritimati"},
+		models.StaticQRCode{Name: "a", TargetURL: "https://a.example.com", Weight: 1},
+	)
+
+	scan := &ScanContext{UserAgent: "Mozilla/5.0", IPAddress: "127.0.0.1"}
+	if _, err := s.ResolveScan(activeQR.ShortCode, scan); err != nil {
+		t.Fatalf("ResolveScan: %v", err)
+	}
+	if _, offset := scan.Time.Zone(); offset != 14*3600 {
+		t.Errorf("scan time should be evaluated in the active code time zone, got offset %d", offset)
+	}
+
+	var stored string
+	deadline := time.Now().Add(2 * time.Second)
+	for stored == "" && time.Now().Before(deadline) {
+		db.Raw("SELECT scan_time FROM scan_records WHERE active_qr_code_id = ?", activeQR.ID).Scan(&stored)
+		time.Sleep(10 * time.Millisecond)
+	}
+	if stored == "" {
+		t.Fatal("scan record was not saved")
+	}
+	if strings.Contains(stored, "+14:00") {
+		t.Errorf("scan_time %q was saved in the active code time zone", stored)
+	}
+
+	var today int64
+	db.Model(&models.ScanRecord{}).Where("DATE(scan_time) = ?", time.Now().Format("2006-01-02")).Count(&today)
+	if today != 1 {
+		t.Errorf("scan should be counted on the server date, got %d records today", today)
+	}
+}
+
+func TestDailyFullMatchesScanDayBoundary(t *testing.T) {
+	s, db := newTestService(t)
+	activeQR := createTestActiveQR(t, db, &models.ActiveQRCode{Name: "daily", SwitchRule: "capacity", TimeZone: "Pacific/Kiritimati"},
+		models.StaticQRCode{Name: "a", TargetURL: "https://a.example.com", MaxDailyScans: 1},
+	)
+	loc, _ := time.LoadLocation("Pacific/Kiritimati")
+	now := time.Now().In(loc)
+	qr := &activeQR.StaticQRCodes[0]
+
+	if acquired, err := s.acquireScanSlot(qr, now); err != nil || !acquired {
+		t.Fatalf("first acquireScanSlot = %v, %v", acquired, err)
+	}
+	if acquired, _ := s.acquireScanSlot(qr, now); acquired {
+		t.Error("daily limit should reject the second scan on the same local day")
+	}
+
+	var reloaded models.StaticQRCode
+	if err := db.First(&reloaded, qr.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.DailyScanDate != now.Format("2006-01-02") {
+		t.Errorf("daily_scan_date = %s, want local date %s", reloaded.DailyScanDate, now.Format("2006-01-02"))
+	}
+	if !reloaded.DailyFull || !reloaded.IsDailyFull(now.Format("2006-01-02")) {
+		t.Errorf("DailyFull = %v, IsDailyFull = %v, want both true", reloaded.DailyFull, reloaded.IsDailyFull(now.Format("2006-01-02")))
+	}
+
+	// 活码时区的次日零点后名额重新可用
+	tomorrow := nextMidnight(now)
+	if acquired, err := s.acquireScanSlot(qr, tomorrow); err != nil || !acquired {
+		t.Errorf("acquireScanSlot on the next local day = %v, %v", acquired, err)
+	}
+}
+
+func TestUpdateTargetURLResetsHealth(t *testing.T) {
+	s, db := newTestService(t)
+	activeQR := createTestActiveQR(t, db, &models.ActiveQRCode{Name: "health", SwitchRule: "weight"},
+		models.StaticQRCode{Name: "a", TargetURL: "https://broken.example.com", Weight: 1, ExcludeUnhealthy: true},
+	)
+	id := activeQR.StaticQRCodes[0].ID
+	now := time.Now()
+	if err := db.Model(&models.StaticQRCode{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"health_status":     HealthStatusUnhealthy,
+		"health_failures":   5,
+		"health_checked_at": now,
+	}).Error; err != nil {
+		t.Fatalf("mark unhealthy: %v", err)
+	}
+
+	name := "renamed"
+	updated, err := s.UpdateStaticQRCode(id, &models.StaticQRCodeUpdateRequest{Name: &name})
+	if err != nil {
+		t.Fatalf("UpdateStaticQRCode: %v", err)
+	}
+	if updated.HealthStatus != HealthStatusUnhealthy || updated.HealthFailures != 5 {
+		t.Fatalf("health changed without target change: %q/%d", updated.HealthStatus, updated.HealthFailures)
+	}
+
+	target := "https://fixed.example.com"
+	if _, err := s.UpdateStaticQRCode(id, &models.StaticQRCodeUpdateRequest{TargetURL: &target}); err != nil {
+		t.Fatalf("UpdateStaticQRCode: %v", err)
+	}
+	var got models.StaticQRCode
+	if err := db.First(&got, id).Error; err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if got.HealthStatus != "" || got.HealthFailures != 0 || got.HealthCheckedAt != nil {
+		t.Fatalf("health not reset: %q/%d/%v", got.HealthStatus, got.HealthFailures, got.HealthCheckedAt)
+	}
+	if isUnhealthyExcluded(&got) {
+		t.Fatal("fixed target still excluded")
+	}
+}
+
+func TestStaticQRCodeTargetingValidation(t *testing.T) {
+	s, db := newTestService(t)
+	activeQR := createTestActiveQR(t, db, &models.ActiveQRCode{Name: "targeting"},
+		models.StaticQRCode{Name: "a", TargetURL: "https://example.com"},
+	)
+
+	tests := map[string]models.StaticQRCodeCreateRequest{
+		"regions not json":   {AllowedRegions: "广东"},
+		"regions empty item": {AllowedRegions: `["广东", ""]`},
+		"devices not json":   {AllowedDevices: "mobile"},
+		"devices unknown":    {AllowedDevices: `["phone"]`},
+		"clients unknown":    {AllowedClients: `["line"]`},
+	}
+	for name, req := range tests {
+		t.Run(name, func(t *testing.T) {
+			req.Name, req.TargetURL = name, "https://example.com"
+			_, err := s.AddStaticQRCode(activeQR.ID, &req)
+			if appErr, ok := err.(*models.AppError); !ok || appErr.Code != "INVALID_PARAMS" {
+				t.Fatalf("AddStaticQRCode error = %v, want INVALID_PARAMS", err)
+			}
+
+			update := &models.StaticQRCodeUpdateRequest{}
+			switch {
+			case req.AllowedRegions != "":
+				update.AllowedRegions = &req.AllowedRegions
+			case req.AllowedDevices != "":
+				update.AllowedDevices = &req.AllowedDevices
+			default:
+				update.AllowedClients = &req.AllowedClients
+			}
+			_, err = s.UpdateStaticQRCode(activeQR.StaticQRCodes[0].ID, update)
+			if appErr, ok := err.(*models.AppError); !ok || appErr.Code != "INVALID_PARAMS" {
+				t.Fatalf("UpdateStaticQRCode error = %v, want INVALID_PARAMS", err)
+			}
+		})
+	}
+
+	valid := &models.StaticQRCodeCreateRequest{
+		Name:           "valid",
+		TargetURL:      "https://example.com",
+		AllowedRegions: `["广东", "北京"]`,
+		AllowedDevices: `["mobile", "tablet"]`,
+		AllowedClients: `["wechat"]`,
+	}
+	if _, err := s.AddStaticQRCode(activeQR.ID, valid); err != nil {
+		t.Fatalf("AddStaticQRCode with valid targeting: %v", err)
+	}
+}
+
+func TestAcquireScanSlotConcurrent(t *testing.T) {
+	s, db := newTestService(t)
+	activeQR := createTestActiveQR(t, db, &models.ActiveQRCode{Name: "capacity"},
+		models.StaticQRCode{Name: "a", TargetURL: "https://a.example.com", MaxScans: 1},
+	)
+	qr := activeQR.StaticQRCodes[0]
+
+	const workers = 20
+	var wg sync.WaitGroup
+	var acquired int32
+	now := time.Now()
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			qr := qr
+			if s.tryAcquireScanSlot(&qr, now) {
+				atomic.AddInt32(&acquired, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if acquired != 1 {
+		t.Fatalf("%d goroutines acquired the only slot, want 1", acquired)
+	}
+	var got models.StaticQRCode
+	if err := db.First(&got, qr.ID).Error; err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if got.ScanCount != 1 || !got.IsFull {
+		t.Fatalf("scan_count/is_full = %d/%v, want 1/true", got.ScanCount, got.IsFull)
+	}
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"wechat-active-qrcode/internal/models"
+	"wechat-active-qrcode/pkg/useragent"
+)
+
+// browserBlockedClients 会拦截部分链接（如App下载）的内置浏览器
+var browserBlockedClients = []string{useragent.ClientWeChat, useragent.ClientWeCom}
+
+// appDownloadExts 微信内无法直接下载的安装包后缀
+var appDownloadExts = []string{".apk", ".ipa"}
+
+// validateClientList 校验客户端列表（JSON数组）
+func validateClientList(value, field string) error {
+	clients, err := unmarshalJSONList(value, field)
+	if err != nil {
+		return err
+	}
+	for _, client := range clients {
+		if !useragent.IsClient(client) {
+			return &models.AppError{
+				Code:    "INVALID_PARAMS",
+				Message: fmt.Sprintf("%s包含不支持的客户端: %s，可选值: %s", field, client, strings.Join(useragent.Clients, ", ")),
+			}
+		}
+	}
+	return nil
+}
+
+// preferClientMatches 存在将当前客户端设为优先的静态码时，只在这些静态码中选择
+func preferClientMatches(qrs []models.StaticQRCode, client string) []models.StaticQRCode {
+	var preferred []models.StaticQRCode
+	for _, qr := range qrs {
+		if contains(parseJSONList(qr.PreferredClients), client) {
+			preferred = append(preferred, qr)
+		}
+	}
+	if len(preferred) == 0 {
+		return qrs
+	}
+	return preferred
+}
+
+// needsOpenInBrowser 判断是否需要引导用户在系统浏览器中打开目标链接
+func needsOpenInBrowser(qr *models.StaticQRCode, client, targetURL string) bool {
+	if qr.IsImage() || !contains(browserBlockedClients, client) {
+		return false
+	}
+	return qr.OpenInBrowser || isAppDownloadURL(targetURL)
+}
+
+// isAppDownloadURL 判断是否为安装包下载链接
+func isAppDownloadURL(targetURL string) bool {
+	u, err := url.Parse(targetURL)
+	if err != nil {
+		return false
+	}
+	ext := strings.ToLower(path.Ext(u.Path))
+	return contains(appDownloadExts, ext)
+}
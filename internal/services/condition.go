@@ -0,0 +1,112 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"wechat-active-qrcode/internal/models"
+	"wechat-active-qrcode/pkg/expr"
+)
+
+// conditionSchema 静态码定向条件可使用的扫码属性
+//
+// 时间相关变量按活码所在时区计算，weekday 0=周日 ... 6=周六，与每周时段一致。
+var conditionSchema = expr.Schema{
+	Vars: map[string]expr.Type{
+		"device":         expr.TypeString, // mobile, tablet, desktop
+		"os":             expr.TypeString, // iOS, Android, HarmonyOS, Windows, macOS, Linux, Other
+		"os_version":     expr.TypeString,
+		"browser":        expr.TypeString,
+		"client":         expr.TypeString, // wechat, wecom, alipay, qq, douyin, other
+		"client_version": expr.TypeString,
+		"language":       expr.TypeString, // Accept-Language中的首选语言，如zh-CN
+		"country":        expr.TypeString,
+		"province":       expr.TypeString,
+		"city":           expr.TypeString,
+		"hour":           expr.TypeNumber, // 0-23
+		"minute":         expr.TypeNumber, // 0-59
+		"weekday":        expr.TypeNumber, // 0-6
+		"date":           expr.TypeString, // YYYY-MM-DD
+	},
+	Maps: []string{"query", "cookie"},
+}
+
+// conditionCache 已编译的定向条件，按表达式源码缓存
+var conditionCache sync.Map
+
+// compileCondition 编译定向条件，结果会被缓存
+func compileCondition(source string) (*expr.Program, error) {
+	if program, ok := conditionCache.Load(source); ok {
+		return program.(*expr.Program), nil
+	}
+	program, err := expr.Compile(source, conditionSchema)
+	if err != nil {
+		return nil, err
+	}
+	conditionCache.Store(source, program)
+	return program, nil
+}
+
+// validateCondition 校验定向条件表达式，为空表示不限
+func validateCondition(source string) error {
+	if strings.TrimSpace(source) == "" {
+		return nil
+	}
+	if _, err := compileCondition(source); err != nil {
+		return &models.AppError{
+			Code:    "INVALID_CONDITION",
+			Message: fmt.Sprintf("定向条件错误: %v", err),
+		}
+	}
+	return nil
+}
+
+// matchCondition 判断扫码环境是否满足静态码的定向条件，条件为空时视为满足
+func matchCondition(source string, scan *ScanContext) bool {
+	if strings.TrimSpace(source) == "" {
+		return true
+	}
+	program, err := compileCondition(source)
+	if err != nil {
+		log.Printf("Compile condition %q failed: %v", source, err)
+		return false
+	}
+	matched, err := program.Eval(conditionEnv(scan))
+	if err != nil {
+		log.Printf("Evaluate condition %q failed: %v", source, err)
+		return false
+	}
+	return matched
+}
+
+// conditionEnv 将扫码环境转换为表达式变量
+func conditionEnv(scan *ScanContext) expr.Env {
+	query := make(map[string]string, len(scan.Query))
+	for key := range scan.Query {
+		query[key] = scan.Query.Get(key)
+	}
+
+	return expr.Env{
+		Vars: map[string]interface{}{
+			"device":         scan.Device,
+			"os":             scan.Agent.OS,
+			"os_version":     scan.Agent.OSVersion,
+			"browser":        scan.Agent.Browser,
+			"client":         scan.Agent.Client,
+			"client_version": scan.Agent.ClientVersion,
+			"language":       scan.Language,
+			"country":        scan.Region.Country,
+			"province":       scan.Region.Province,
+			"city":           scan.Region.City,
+			"hour":           scan.Time.Hour(),
+			"minute":         scan.Time.Minute(),
+			"weekday":        int(scan.Time.Weekday()),
+			"date":           scan.Time.Format("2006-01-02"),
+		},
+		Maps: map[string]map[string]string{
+			"query":  query,
+			"cookie": scan.Cookies,
+		},
+	}
+}
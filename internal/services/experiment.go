@@ -0,0 +1,540 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"wechat-active-qrcode/internal/config"
+	"wechat-active-qrcode/internal/models"
+	"wechat-active-qrcode/pkg/stats"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 实验默认配置
+const (
+	defaultExperimentConfidence    = 0.95
+	defaultExperimentMinScans      = 100
+	defaultExperimentCheckInterval = 5 // 分钟
+)
+
+// conversionSignatureSize 转化凭证中签名的字节数
+const conversionSignatureSize = 16
+
+// DefaultConversionEvent 未指定事件名称时的转化事件
+const DefaultConversionEvent = "conversion"
+
+var conversionEventPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// VariantReport 实验版本的效果数据
+type VariantReport struct {
+	VariantID      uint    `json:"variant_id"`
+	Name           string  `json:"name"`
+	StaticQRCodeID uint    `json:"static_qr_code_id"`
+	IsControl      bool    `json:"is_control"` // 第一个版本作为对照组
+	Scans          int64   `json:"scans"`
+	Conversions    int64   `json:"conversions"` // 发生转化的扫码次数
+	ConversionRate float64 `json:"conversion_rate"`
+	CILow          float64 `json:"ci_low"`  // 转化率置信区间下限
+	CIHigh         float64 `json:"ci_high"` // 转化率置信区间上限
+	Lift           float64 `json:"lift"`    // 相对对照组的转化率提升，对照组转化率为0时为0
+	PValue         float64 `json:"p_value"` // 与对照组差异的双侧p值
+}
+
+// ExperimentReport 实验报告
+type ExperimentReport struct {
+	Experiment  *models.Experiment `json:"experiment"`
+	Event       string             `json:"event,omitempty"` // 统计的转化事件，为空表示任意事件
+	Variants    []VariantReport    `json:"variants"`
+	Leader      *VariantReport     `json:"leader"`      // 当前转化率最高的版本
+	Significant bool               `json:"significant"` // 领先版本是否显著优于所有其他版本
+	Message     string             `json:"message"`
+}
+
+// CreateExperiment 在活码上创建A/B实验，同一活码同时只能有一个进行中的实验
+func (s *ActiveQRCodeService) CreateExperiment(activeQRCodeID uint, req *models.ExperimentCreateRequest) (*models.Experiment, error) {
+	activeQR, err := s.GetActiveQRCode(activeQRCodeID)
+	if err != nil {
+		return nil, &models.AppError{
+			Code:    "ACTIVE_QR_NOT_FOUND",
+			Message: "活码不存在",
+		}
+	}
+
+	experiment := &models.Experiment{
+		ActiveQRCodeID: activeQRCodeID,
+		Name:           strings.TrimSpace(req.Name),
+		Status:         models.ExperimentStatusRunning,
+		Confidence:     req.Confidence,
+		MinScans:       req.MinScans,
+		AutoShift:      req.AutoShift,
+		StartedAt:      time.Now(),
+	}
+	if experiment.Confidence == 0 {
+		experiment.Confidence = defaultExperimentConfidence
+	}
+	if experiment.MinScans <= 0 {
+		experiment.MinScans = defaultExperimentMinScans
+	}
+	if err := validateExperiment(activeQR, experiment, req.Variants); err != nil {
+		return nil, err
+	}
+	for _, variant := range req.Variants {
+		experiment.Variants = append(experiment.Variants, models.ExperimentVariant{
+			Name:           strings.TrimSpace(variant.Name),
+			StaticQRCodeID: variant.StaticQRCodeID,
+		})
+	}
+
+	var running int64
+	s.db.Model(&models.Experiment{}).
+		Where("active_qr_code_id = ? AND status = ?", activeQRCodeID, models.ExperimentStatusRunning).
+		Count(&running)
+	if running > 0 {
+		return nil, &models.AppError{
+			Code:    "EXPERIMENT_RUNNING",
+			Message: "该活码已有进行中的实验，请先停止",
+		}
+	}
+
+	if err := s.db.Create(experiment).Error; err != nil {
+		return nil, err
+	}
+	return experiment, nil
+}
+
+// validateExperiment 校验实验配置：至少两个版本，版本名称和静态码不能重复，静态码必须属于该活码
+func validateExperiment(activeQR *models.ActiveQRCode, experiment *models.Experiment, variants []models.ExperimentVariantRequest) error {
+	invalid := func(format string, args ...interface{}) error {
+		return &models.AppError{
+			Code:    "INVALID_EXPERIMENT",
+			Message: fmt.Sprintf(format, args...),
+		}
+	}
+
+	if experiment.Name == "" {
+		return invalid("实验名称不能为空")
+	}
+	if experiment.Confidence <= 0.5 || experiment.Confidence >= 1 {
+		return invalid("置信水平应在0.5到1之间，如0.95")
+	}
+	if len(variants) < 2 {
+		return invalid("实验至少需要两个版本")
+	}
+	if experiment.AutoShift && activeQR.SwitchRule != "weight" {
+		return invalid("自动切换通过调整权重实现，仅支持切换规则为 weight 的活码")
+	}
+
+	staticIDs := make(map[uint]bool, len(activeQR.StaticQRCodes))
+	for _, qr := range activeQR.StaticQRCodes {
+		staticIDs[qr.ID] = true
+	}
+	names := make(map[string]bool, len(variants))
+	used := make(map[uint]bool, len(variants))
+	for _, variant := range variants {
+		name := strings.TrimSpace(variant.Name)
+		if name == "" {
+			return invalid("版本名称不能为空")
+		}
+		if names[name] {
+			return invalid("版本名称重复: %s", name)
+		}
+		names[name] = true
+
+		if !staticIDs[variant.StaticQRCodeID] {
+			return invalid("静态码 %d 不属于该活码", variant.StaticQRCodeID)
+		}
+		if used[variant.StaticQRCodeID] {
+			return invalid("静态码 %d 被多个版本使用", variant.StaticQRCodeID)
+		}
+		used[variant.StaticQRCodeID] = true
+	}
+	return nil
+}
+
+// ListExperiments 获取活码的实验列表
+func (s *ActiveQRCodeService) ListExperiments(activeQRCodeID uint) ([]models.Experiment, error) {
+	var experiments []models.Experiment
+	err := s.db.Preload("Variants").
+		Where("active_qr_code_id = ?", activeQRCodeID).
+		Order("created_at DESC").
+		Find(&experiments).Error
+	return experiments, err
+}
+
+// GetExperiment 获取实验详情
+func (s *ActiveQRCodeService) GetExperiment(id uint) (*models.Experiment, error) {
+	var experiment models.Experiment
+	if err := s.db.Preload("Variants").First(&experiment, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, &models.AppError{
+				Code:    "EXPERIMENT_NOT_FOUND",
+				Message: "实验不存在",
+			}
+		}
+		return nil, err
+	}
+	return &experiment, nil
+}
+
+// StopExperiment 停止进行中的实验，停止后的扫码不再计入报告
+func (s *ActiveQRCodeService) StopExperiment(id uint) (*models.Experiment, error) {
+	result := s.db.Model(&models.Experiment{}).
+		Where("id = ? AND status = ?", id, models.ExperimentStatusRunning).
+		Updates(map[string]interface{}{
+			"status":   models.ExperimentStatusStopped,
+			"ended_at": time.Now(),
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := s.GetExperiment(id); err != nil {
+			return nil, err
+		}
+		return nil, &models.AppError{
+			Code:    "EXPERIMENT_NOT_RUNNING",
+			Message: "实验未在进行中",
+		}
+	}
+	return s.GetExperiment(id)
+}
+
+// DeleteExperiment 删除实验及其版本，扫描记录和转化记录保留
+func (s *ActiveQRCodeService) DeleteExperiment(id uint) error {
+	if _, err := s.GetExperiment(id); err != nil {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("experiment_id = ?", id).Delete(&models.ExperimentVariant{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Experiment{}, id).Error
+	})
+}
+
+// conversionToken 生成扫描记录的转化凭证，格式为「扫描记录ID.签名」
+//
+// 扫描记录ID是连续的，只凭ID回传任何人都可以伪造转化，因此回传时必须校验签名。
+func (s *ActiveQRCodeService) conversionToken(scanID uint) string {
+	id := strconv.FormatUint(uint64(scanID), 10)
+	return id + "." + base64.RawURLEncoding.EncodeToString(s.conversionSignature(id))
+}
+
+// parseConversionToken 校验转化凭证的签名并返回扫描记录ID
+func (s *ActiveQRCodeService) parseConversionToken(token string) (uint, bool) {
+	id, signature, ok := strings.Cut(strings.TrimSpace(token), ".")
+	if !ok {
+		return 0, false
+	}
+	scanID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil || scanID == 0 {
+		return 0, false
+	}
+	got, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(got, s.conversionSignature(id)) {
+		return 0, false
+	}
+	return uint(scanID), true
+}
+
+// conversionSignature 使用配置的密钥计算签名，未单独配置时使用JWT密钥
+func (s *ActiveQRCodeService) conversionSignature(id string) []byte {
+	var secret string
+	if s.config != nil {
+		secret = s.config.Experiment.ConversionSecret
+		if secret == "" {
+			secret = s.config.JWT.Secret
+		}
+	}
+	// 加上用途前缀，避免与同一密钥的其他签名混用
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("conversion:" + id))
+	return mac.Sum(nil)[:conversionSignatureSize]
+}
+
+// RecordConversion 记录落地页回传的转化，凭证无效时拒绝，重复回传同一事件时忽略
+//
+// 自动切换由 ExperimentMonitor 定期检查，回传本身只写入转化记录。
+func (s *ActiveQRCodeService) RecordConversion(req *models.ConversionRequest) error {
+	event := strings.TrimSpace(req.Event)
+	if event == "" {
+		event = DefaultConversionEvent
+	}
+	if !conversionEventPattern.MatchString(event) {
+		return &models.AppError{
+			Code:    "INVALID_EVENT",
+			Message: "事件名称只能包含字母、数字、下划线、点和减号，最长64个字符",
+		}
+	}
+
+	scanID, ok := s.parseConversionToken(req.Token)
+	if !ok {
+		return &models.AppError{
+			Code:    "INVALID_CONVERSION_TOKEN",
+			Message: "转化凭证无效",
+		}
+	}
+
+	var scanRecord models.ScanRecord
+	if err := s.db.First(&scanRecord, scanID).Error; err != nil {
+		return &models.AppError{
+			Code:    "SCAN_NOT_FOUND",
+			Message: "扫描记录不存在",
+		}
+	}
+
+	conversion := &models.Conversion{
+		ScanRecordID:   scanRecord.ID,
+		Event:          event,
+		ActiveQRCodeID: scanRecord.ActiveQRCodeID,
+		StaticQRCodeID: scanRecord.StaticQRCodeID,
+		Value:          req.Value,
+	}
+	return s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(conversion).Error
+}
+
+// GetExperimentReport 统计实验期间各版本的扫码、转化、转化率及置信区间，event 为空时统计任意转化事件
+func (s *ActiveQRCodeService) GetExperimentReport(id uint, event string) (*ExperimentReport, error) {
+	experiment, err := s.GetExperiment(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// 扫描记录的时间按服务器时区保存，比较前统一时区
+	start := experiment.StartedAt.In(time.Local)
+	end := time.Now()
+	if experiment.EndedAt != nil {
+		end = experiment.EndedAt.In(time.Local)
+	}
+
+	staticIDs := make([]uint, len(experiment.Variants))
+	for i, variant := range experiment.Variants {
+		staticIDs[i] = variant.StaticQRCodeID
+	}
+
+	type countRow struct {
+		StaticQRCodeID uint
+		Count          int64
+	}
+	var scanRows, conversionRows []countRow
+	err = s.db.Model(&models.ScanRecord{}).
+		Select("static_qr_code_id, COUNT(*) AS count").
+		Where("active_qr_code_id = ? AND static_qr_code_id IN ? AND scan_time >= ? AND scan_time <= ?", experiment.ActiveQRCodeID, staticIDs, start, end).
+		Group("static_qr_code_id").
+		Scan(&scanRows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	conversionQuery := s.db.Table("conversions").
+		Select("scan_records.static_qr_code_id, COUNT(DISTINCT conversions.scan_record_id) AS count").
+		Joins("JOIN scan_records ON scan_records.id = conversions.scan_record_id").
+		Where("scan_records.active_qr_code_id = ? AND scan_records.static_qr_code_id IN ? AND scan_records.scan_time >= ? AND scan_records.scan_time <= ?", experiment.ActiveQRCodeID, staticIDs, start, end)
+	if event != "" {
+		conversionQuery = conversionQuery.Where("conversions.event = ?", event)
+	}
+	if err := conversionQuery.Group("scan_records.static_qr_code_id").Scan(&conversionRows).Error; err != nil {
+		return nil, err
+	}
+
+	scans := make(map[uint]int64, len(scanRows))
+	for _, row := range scanRows {
+		scans[row.StaticQRCodeID] = row.Count
+	}
+	conversions := make(map[uint]int64, len(conversionRows))
+	for _, row := range conversionRows {
+		conversions[row.StaticQRCodeID] = row.Count
+	}
+
+	report := &ExperimentReport{
+		Experiment: experiment,
+		Event:      event,
+		Variants:   make([]VariantReport, len(experiment.Variants)),
+	}
+	for i, variant := range experiment.Variants {
+		v := &report.Variants[i]
+		v.VariantID = variant.ID
+		v.Name = variant.Name
+		v.StaticQRCodeID = variant.StaticQRCodeID
+		v.IsControl = i == 0
+		v.Scans = scans[variant.StaticQRCodeID]
+		v.Conversions = conversions[variant.StaticQRCodeID]
+		if v.Scans > 0 {
+			v.ConversionRate = float64(v.Conversions) / float64(v.Scans)
+		}
+		v.CILow, v.CIHigh = stats.WilsonInterval(v.Conversions, v.Scans, experiment.Confidence)
+	}
+
+	control := report.Variants[0]
+	for i := range report.Variants {
+		v := &report.Variants[i]
+		v.PValue = 1
+		if i > 0 {
+			v.PValue = stats.TwoProportionPValue(v.Conversions, v.Scans, control.Conversions, control.Scans)
+		}
+		if control.ConversionRate > 0 {
+			v.Lift = v.ConversionRate/control.ConversionRate - 1
+		}
+	}
+
+	evaluateExperiment(report)
+	return report, nil
+}
+
+// evaluateExperiment 找出转化率最高的版本，并判断其是否显著优于所有其他版本
+func evaluateExperiment(report *ExperimentReport) {
+	experiment := report.Experiment
+	leader := 0
+	for i, v := range report.Variants {
+		if v.ConversionRate > report.Variants[leader].ConversionRate {
+			leader = i
+		}
+	}
+	report.Leader = &report.Variants[leader]
+
+	for _, v := range report.Variants {
+		if v.Scans < int64(experiment.MinScans) {
+			report.Message = fmt.Sprintf("版本「%s」扫码次数 %d 未达到最少 %d 次，暂不判定结果", v.Name, v.Scans, experiment.MinScans)
+			return
+		}
+	}
+
+	alpha := 1 - experiment.Confidence
+	for i, v := range report.Variants {
+		if i == leader {
+			continue
+		}
+		if v.ConversionRate == report.Leader.ConversionRate ||
+			stats.TwoProportionPValue(report.Leader.Conversions, report.Leader.Scans, v.Conversions, v.Scans) >= alpha {
+			report.Message = fmt.Sprintf("版本「%s」与「%s」的差异在 %.0f%% 置信水平下不显著", report.Leader.Name, v.Name, experiment.Confidence*100)
+			return
+		}
+	}
+
+	report.Significant = true
+	report.Message = fmt.Sprintf("版本「%s」在 %.0f%% 置信水平下显著优于其他版本", report.Leader.Name, experiment.Confidence*100)
+}
+
+// ExperimentMonitor 定期检查开启了自动切换的进行中实验，结果显著时把流量切换到胜出版本
+type ExperimentMonitor struct {
+	service  *ActiveQRCodeService
+	interval time.Duration
+}
+
+// NewExperimentMonitor 创建实验自动切换任务
+func NewExperimentMonitor(service *ActiveQRCodeService, cfg config.ExperimentConfig) *ExperimentMonitor {
+	interval := cfg.CheckInterval
+	if interval <= 0 {
+		interval = defaultExperimentCheckInterval
+	}
+	return &ExperimentMonitor{
+		service:  service,
+		interval: time.Duration(interval) * time.Minute,
+	}
+}
+
+// Start 在后台定期检查，ctx 取消后退出
+func (m *ExperimentMonitor) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		for {
+			if err := m.Check(); err != nil {
+				log.Printf("Experiment check failed: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Check 评估所有开启了自动切换的进行中实验，单个实验失败不影响其他实验
+func (m *ExperimentMonitor) Check() error {
+	var experiments []models.Experiment
+	err := m.service.db.Where("status = ? AND auto_shift = ?", models.ExperimentStatusRunning, true).
+		Find(&experiments).Error
+	if err != nil {
+		return err
+	}
+
+	for _, experiment := range experiments {
+		report, err := m.service.GetExperimentReport(experiment.ID, "")
+		if err != nil {
+			log.Printf("Evaluate experiment ID=%d failed: %v", experiment.ID, err)
+			continue
+		}
+		if !report.Significant {
+			continue
+		}
+		if err := m.service.completeExperiment(report); err != nil {
+			log.Printf("Shift experiment ID=%d to winner failed: %v", experiment.ID, err)
+		}
+	}
+	return nil
+}
+
+// completeExperiment 结束实验并把权重切换到胜出版本：落败版本的权重设为0，胜出版本的权重至少为1
+//
+// 静态码保持启用，调整前的权重记录在实验版本上，可以手动恢复。活码已改为不参考权重的规则时只记录胜出版本。
+func (s *ActiveQRCodeService) completeExperiment(report *ExperimentReport) error {
+	winner := report.Leader
+	var activeQR models.ActiveQRCode
+	if err := s.db.First(&activeQR, report.Experiment.ActiveQRCodeID).Error; err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// 条件更新保证只切换一次
+		result := tx.Model(&models.Experiment{}).
+			Where("id = ? AND status = ?", report.Experiment.ID, models.ExperimentStatusRunning).
+			Updates(map[string]interface{}{
+				"status":            models.ExperimentStatusCompleted,
+				"winner_variant_id": winner.VariantID,
+				"ended_at":          time.Now(),
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		if activeQR.SwitchRule != "weight" {
+			log.Printf("Experiment ID=%d completed with winner %q, weights unchanged because active QR %d uses switch rule %q", report.Experiment.ID, winner.Name, activeQR.ID, activeQR.SwitchRule)
+			return nil
+		}
+		for _, v := range report.Variants {
+			var staticQR models.StaticQRCode
+			if err := tx.First(&staticQR, v.StaticQRCodeID).Error; err != nil {
+				return err
+			}
+			weight := 0
+			if v.VariantID == winner.VariantID {
+				weight = staticQR.Weight
+				if weight < 1 {
+					weight = 1
+				}
+			}
+			if err := tx.Model(&models.ExperimentVariant{}).Where("id = ?", v.VariantID).Update("weight_before", staticQR.Weight).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.StaticQRCode{}).Where("id = ?", staticQR.ID).Update("weight", weight).Error; err != nil {
+				return err
+			}
+		}
+
+		log.Printf("Experiment ID=%d completed, weight shifted to variant %q (StaticQR ID=%d)", report.Experiment.ID, winner.Name, winner.StaticQRCodeID)
+		return nil
+	})
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"wechat-active-qrcode/internal/models"
+)
+
+func TestEvaluateExperiment(t *testing.T) {
+	variant := func(name string, conversions, scans int64) VariantReport {
+		v := VariantReport{Name: name, Scans: scans, Conversions: conversions}
+		if scans > 0 {
+			v.ConversionRate = float64(conversions) / float64(scans)
+		}
+		return v
+	}
+
+	tests := []struct {
+		name        string
+		variants    []VariantReport
+		leader      string
+		significant bool
+		message     string
+	}{
+		{
+			name:        "winner",
+			variants:    []VariantReport{variant("A", 100, 1000), variant("B", 250, 1000)},
+			leader:      "B",
+			significant: true,
+			message:     "显著优于",
+		},
+		{
+			name:     "control leads without significance",
+			variants: []VariantReport{variant("A", 22, 200), variant("B", 20, 200)},
+			leader:   "A",
+			message:  "不显著",
+		},
+		{
+			name:     "not enough scans",
+			variants: []VariantReport{variant("A", 10, 1000), variant("B", 50, 99)},
+			leader:   "B",
+			message:  "未达到最少 100 次",
+		},
+		{
+			// 领先版本只显著优于其中一个版本时不判定胜出
+			name:     "runner-up too close",
+			variants: []VariantReport{variant("A", 100, 1000), variant("B", 250, 1000), variant("C", 240, 1000)},
+			leader:   "B",
+			message:  "「B」与「C」的差异",
+		},
+		{
+			name:     "tie",
+			variants: []VariantReport{variant("A", 0, 500), variant("B", 0, 500)},
+			leader:   "A",
+			message:  "不显著",
+		},
+	}
+
+	for _, tt := range tests {
+		report := &ExperimentReport{
+			Experiment: &models.Experiment{Confidence: 0.95, MinScans: 100},
+			Variants:   tt.variants,
+		}
+		evaluateExperiment(report)
+
+		if report.Leader == nil || report.Leader.Name != tt.leader {
+			t.Errorf("%s: leader = %+v, want %s", tt.name, report.Leader, tt.leader)
+		}
+		if report.Significant != tt.significant {
+			t.Errorf("%s: significant = %v, want %v (%s)", tt.name, report.Significant, tt.significant, report.Message)
+		}
+		if !strings.Contains(report.Message, tt.message) {
+			t.Errorf("%s: message = %q, want to contain %q", tt.name, report.Message, tt.message)
+		}
+	}
+}
+
+func TestConversionToken(t *testing.T) {
+	s, _ := newTestService(t)
+	s.config.JWT.Secret = "secret"
+
+	token := s.conversionToken(42)
+	if id, ok := s.parseConversionToken(token); !ok || id != 42 {
+		t.Fatalf("parseConversionToken(%q) = %d, %v", token, id, ok)
+	}
+
+	signature := token[strings.Index(token, "."):]
+	invalid := []string{
+		"",
+		"42",
+		"43" + signature,
+		token + "x",
+		"0" + signature,
+		"abc" + signature,
+	}
+	for _, tt := range invalid {
+		if id, ok := s.parseConversionToken(tt); ok {
+			t.Errorf("parseConversionToken(%q) = %d, want invalid", tt, id)
+		}
+	}
+
+	// 更换密钥后旧凭证失效
+	s.config.Experiment.ConversionSecret = "another"
+	if _, ok := s.parseConversionToken(token); ok {
+		t.Error("token signed with the old secret is still valid")
+	}
+}
+
+func TestRecordConversionRequiresValidToken(t *testing.T) {
+	s, db := newTestService(t)
+	activeQR := createTestActiveQR(t, db, &models.ActiveQRCode{Name: "conv", SwitchRule: "random"},
+		models.StaticQRCode{Name: "a", TargetURL: "https://a.example.com/?t={conversion_token}", Weight: 1},
+	)
+
+	result, err := s.ResolveScan(activeQR.ShortCode, &ScanContext{UserAgent: "Mozilla/5.0", IPAddress: "127.0.0.1"})
+	if err != nil {
+		t.Fatalf("ResolveScan: %v", err)
+	}
+	token := strings.TrimPrefix(result.TargetURL, "https://a.example.com/?t=")
+	if token == result.TargetURL || token == "" {
+		t.Fatalf("target url without token: %s", result.TargetURL)
+	}
+
+	err = s.RecordConversion(&models.ConversionRequest{Token: strings.SplitN(token, ".", 2)[0] + ".forged"})
+	if appErr, ok := err.(*models.AppError); !ok || appErr.Code != "INVALID_CONVERSION_TOKEN" {
+		t.Fatalf("forged token: err = %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := s.RecordConversion(&models.ConversionRequest{Token: token}); err != nil {
+			t.Fatalf("RecordConversion: %v", err)
+		}
+	}
+	var count int64
+	db.Model(&models.Conversion{}).Count(&count)
+	if count != 1 {
+		t.Fatalf("conversions = %d, want 1", count)
+	}
+}
+
+func TestExperimentMonitorShiftsWeightToWinner(t *testing.T) {
+	s, db := newTestService(t)
+	activeQR := createTestActiveQR(t, db, &models.ActiveQRCode{Name: "exp", SwitchRule: "weight"},
+		models.StaticQRCode{Name: "a", TargetURL: "https://a.example.com", Weight: 3},
+		models.StaticQRCode{Name: "b", TargetURL: "https://b.example.com", Weight: 1},
+	)
+	loser, winner := activeQR.StaticQRCodes[0], activeQR.StaticQRCodes[1]
+
+	req := &models.ExperimentCreateRequest{
+		Name:      "landing",
+		MinScans:  100,
+		AutoShift: true,
+		Variants: []models.ExperimentVariantRequest{
+			{Name: "A", StaticQRCodeID: loser.ID},
+			{Name: "B", StaticQRCodeID: winner.ID},
+		},
+	}
+	// 不参考权重的规则不能开启自动切换
+	random := createTestActiveQR(t, db, &models.ActiveQRCode{Name: "random", SwitchRule: "random"},
+		models.StaticQRCode{Name: "a", TargetURL: "https://a.example.com"},
+		models.StaticQRCode{Name: "b", TargetURL: "https://b.example.com"},
+	)
+	randomReq := *req
+	randomReq.Variants = []models.ExperimentVariantRequest{
+		{Name: "A", StaticQRCodeID: random.StaticQRCodes[0].ID},
+		{Name: "B", StaticQRCodeID: random.StaticQRCodes[1].ID},
+	}
+	if _, err := s.CreateExperiment(random.ID, &randomReq); err == nil {
+		t.Fatal("auto_shift accepted for the random rule")
+	}
+
+	experiment, err := s.CreateExperiment(activeQR.ID, req)
+	if err != nil {
+		t.Fatalf("CreateExperiment: %v", err)
+	}
+
+	// A 转化率 10%，B 转化率 30%
+	addScans := func(staticID uint, scans, conversions int) {
+		for i := 0; i < scans; i++ {
+			record := &models.ScanRecord{ActiveQRCodeID: &activeQR.ID, StaticQRCodeID: &staticID, ScanTime: time.Now()}
+			if err := db.Create(record).Error; err != nil {
+				t.Fatalf("create scan record: %v", err)
+			}
+			if i < conversions {
+				if err := s.RecordConversion(&models.ConversionRequest{Token: s.conversionToken(record.ID)}); err != nil {
+					t.Fatalf("RecordConversion: %v", err)
+				}
+			}
+		}
+	}
+	addScans(loser.ID, 150, 15)
+	addScans(winner.ID, 150, 45)
+
+	if err := NewExperimentMonitor(s, s.config.Experiment).Check(); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+
+	got, err := s.GetExperiment(experiment.ID)
+	if err != nil {
+		t.Fatalf("GetExperiment: %v", err)
+	}
+	if got.Status != models.ExperimentStatusCompleted || got.WinnerVariantID == nil || *got.WinnerVariantID != got.Variants[1].ID {
+		t.Fatalf("experiment = %s winner %v, want completed with B", got.Status, got.WinnerVariantID)
+	}
+
+	// 落败版本保持启用，权重设为0，调整前的权重记录在版本上
+	var gotLoser models.StaticQRCode
+	if err := db.First(&gotLoser, loser.ID).Error; err != nil {
+		t.Fatalf("reload loser: %v", err)
+	}
+	if gotLoser.Status != 1 || gotLoser.Weight != 0 {
+		t.Fatalf("loser status/weight = %d/%d, want 1/0", gotLoser.Status, gotLoser.Weight)
+	}
+	if before := got.Variants[0].WeightBefore; before == nil || *before != 3 {
+		t.Fatalf("loser weight_before = %v, want 3", before)
+	}
+	for i := 0; i < 20; i++ {
+		result, err := s.ResolveScan(activeQR.ShortCode, &ScanContext{UserAgent: "Mozilla/5.0", IPAddress: "127.0.0.1"})
+		if err != nil {
+			t.Fatalf("ResolveScan: %v", err)
+		}
+		if result.StaticQR.ID != winner.ID {
+			t.Fatalf("scan %d went to StaticQR %d, want winner %d", i, result.StaticQR.ID, winner.ID)
+		}
+	}
+}
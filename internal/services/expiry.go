@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"wechat-active-qrcode/internal/config"
+	"wechat-active-qrcode/internal/models"
+	"wechat-active-qrcode/pkg/notify"
+)
+
+// wechatGroupQRValidity 微信群二维码的有效期
+const wechatGroupQRValidity = 7 * 24 * time.Hour
+
+// 过期检查默认配置
+const (
+	defaultExpiryCheckInterval = 60 // 分钟
+	defaultExpiryWarnHours     = 48
+)
+
+// IsWeChatGroupInvite 判断二维码内容是否为微信群邀请链接
+func IsWeChatGroupInvite(content string) bool {
+	content = strings.TrimSpace(content)
+	return strings.HasPrefix(content, "https://weixin.qq.com/g/") ||
+		strings.HasPrefix(content, "http://weixin.qq.com/g/")
+}
+
+// groupInviteExpiresAt 微信群邀请按上传时间加7天计算过期时间，其他内容不过期
+func groupInviteExpiresAt(content string, from time.Time) *time.Time {
+	if !IsWeChatGroupInvite(content) {
+		return nil
+	}
+	expiresAt := from.Add(wechatGroupQRValidity)
+	return &expiresAt
+}
+
+// expiryWarnHours 提前提醒的小时数
+func (s *ActiveQRCodeService) expiryWarnHours() int {
+	if s.config != nil && s.config.Expiry.WarnHours > 0 {
+		return s.config.Expiry.WarnHours
+	}
+	return defaultExpiryWarnHours
+}
+
+// ListExpiringStaticQRCodes 获取指定小时内将要过期（含已过期）的启用中静态码，hours 为0时使用配置的提醒时间
+func (s *ActiveQRCodeService) ListExpiringStaticQRCodes(hours int) ([]models.StaticQRCode, error) {
+	if hours <= 0 {
+		hours = s.expiryWarnHours()
+	}
+	deadline := time.Now().Add(time.Duration(hours) * time.Hour)
+
+	var staticQRs []models.StaticQRCode
+	err := s.db.Preload("ActiveQRCode").
+		Where("status = ? AND expires_at IS NOT NULL AND expires_at <= ?", 1, deadline).
+		Order("expires_at ASC").
+		Find(&staticQRs).Error
+	if err != nil {
+		return nil, err
+	}
+	return staticQRs, nil
+}
+
+// ExpiryMonitor 定期检查即将过期的静态码并发送提醒，每个过期时间只提醒一次
+type ExpiryMonitor struct {
+	service  *ActiveQRCodeService
+	notifier notify.Notifier
+	interval time.Duration
+}
+
+// NewExpiryMonitor 创建过期提醒任务
+func NewExpiryMonitor(service *ActiveQRCodeService, notifier notify.Notifier, cfg config.ExpiryConfig) *ExpiryMonitor {
+	interval := cfg.CheckInterval
+	if interval <= 0 {
+		interval = defaultExpiryCheckInterval
+	}
+	if notifier == nil {
+		notifier = notify.LogNotifier{}
+	}
+	return &ExpiryMonitor{
+		service:  service,
+		notifier: notifier,
+		interval: time.Duration(interval) * time.Minute,
+	}
+}
+
+// Start 在后台定期检查，ctx 取消后退出
+func (m *ExpiryMonitor) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		for {
+			if err := m.Check(); err != nil {
+				log.Printf("Expiry check failed: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Check 发送尚未提醒过的过期提醒，任一渠道送达即标记为已提醒，所有渠道都失败时下次检查重试
+func (m *ExpiryMonitor) Check() error {
+	staticQRs, err := m.service.ListExpiringStaticQRCodes(0)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var lines []string
+	var ids []uint
+	for _, qr := range staticQRs {
+		if qr.ExpiryNotifiedAt != nil {
+			continue
+		}
+
+		state := "将于"
+		if !qr.ExpiresAt.After(now) {
+			state = "已于"
+		}
+		lines = append(lines, fmt.Sprintf("- 活码「%s」的静态码「%s」%s %s 过期",
+			qr.ActiveQRCode.Name, qr.Name, state, qr.ExpiresAt.Local().Format("2006-01-02 15:04")))
+		ids = append(ids, qr.ID)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	title := fmt.Sprintf("%d 个静态码即将过期", len(ids))
+	content := strings.Join(lines, "\n") + "\n请及时上传新的群二维码。"
+	if err := m.notifier.Notify(title, content); err != nil {
+		return err
+	}
+
+	return m.service.db.Model(&models.StaticQRCode{}).
+		Where("id IN ?", ids).
+		Update("expiry_notified_at", now).Error
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"wechat-active-qrcode/internal/config"
+	"wechat-active-qrcode/internal/models"
+	"wechat-active-qrcode/pkg/notify"
+)
+
+// countingNotifier 记录通知次数，fail 为真时返回错误
+type countingNotifier struct {
+	calls int
+	fail  bool
+}
+
+func (n *countingNotifier) Notify(title, content string) error {
+	n.calls++
+	if n.fail {
+		return errors.New("unavailable")
+	}
+	return nil
+}
+
+func TestExpiryMonitorNotifiesOnce(t *testing.T) {
+	s, db := newTestService(t)
+	expiresAt := time.Now().Add(time.Hour)
+	activeQR := createTestActiveQR(t, db, &models.ActiveQRCode{Name: "group"},
+		models.StaticQRCode{Name: "a", TargetURL: "https://a.example.com", ExpiresAt: &expiresAt},
+	)
+
+	webhook := &countingNotifier{fail: true}
+	other := &countingNotifier{fail: true}
+	monitor := NewExpiryMonitor(s, notify.MultiNotifier{webhook, other}, config.ExpiryConfig{})
+
+	// 所有渠道都失败时不标记，下次检查重新发送
+	if err := monitor.Check(); err == nil {
+		t.Fatal("Check should fail when no channel delivered")
+	}
+	other.fail = false
+	if err := monitor.Check(); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	var got models.StaticQRCode
+	if err := db.First(&got, activeQR.StaticQRCodes[0].ID).Error; err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if got.ExpiryNotifiedAt == nil {
+		t.Fatal("static qr not marked as notified after one channel delivered")
+	}
+
+	// 已送达的渠道不会因为其他渠道失败而重复收到提醒
+	if err := monitor.Check(); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if other.calls != 2 || webhook.calls != 2 {
+		t.Errorf("notifier calls = %d/%d, want 2/2", webhook.calls, other.calls)
+	}
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"wechat-active-qrcode/internal/models"
+	"wechat-active-qrcode/pkg/utils"
+)
+
+// 兜底行为
+const (
+	FallbackActionPage     = "page"     // 显示错误页面（可自定义标题和内容）
+	FallbackActionRedirect = "redirect" // 跳转到兜底链接
+	FallbackActionNotFound = "404"      // 返回404
+	FallbackActionGone     = "410"      // 返回410
+)
+
+// fallbackErrorCodes 可配置兜底行为的错误代码
+var fallbackErrorCodes = []string{"DISABLED", "NO_STATIC_QR", "NO_MATCHING_QR"}
+
+// FallbackAction 无可用静态码时的兜底处理方式
+type FallbackAction struct {
+	Action  string `json:"action"`
+	URL     string `json:"url"`
+	Title   string `json:"title"`
+	Message string `json:"message"`
+}
+
+// resolveFallback 根据活码配置确定错误代码对应的兜底行为
+//
+// 未单独配置的错误代码：设置了兜底链接时跳转，否则显示错误页面。
+func resolveFallback(activeQR *models.ActiveQRCode, code string) *FallbackAction {
+	fallback := &FallbackAction{
+		Action:  FallbackActionPage,
+		URL:     activeQR.FallbackURL,
+		Title:   activeQR.FallbackTitle,
+		Message: activeQR.FallbackMessage,
+	}
+
+	actions, _ := parseFallbackActions(activeQR.FallbackActions)
+	if action, ok := actions[code]; ok {
+		fallback.Action = action
+	} else if activeQR.FallbackURL != "" {
+		fallback.Action = FallbackActionRedirect
+	}
+
+	// 未配置兜底链接时无法跳转，退回到错误页面
+	if fallback.Action == FallbackActionRedirect && fallback.URL == "" {
+		fallback.Action = FallbackActionPage
+	}
+	return fallback
+}
+
+// parseFallbackActions 解析错误代码到兜底行为的JSON映射
+func parseFallbackActions(value string) (map[string]string, error) {
+	if value == "" || value == "null" {
+		return nil, nil
+	}
+	var actions map[string]string
+	if err := json.Unmarshal([]byte(value), &actions); err != nil {
+		return nil, err
+	}
+	return actions, nil
+}
+
+// validateFallback 校验兜底链接和兜底行为配置
+func validateFallback(fallbackURL, fallbackActions string) error {
+	if fallbackURL != "" && !utils.IsValidURL(fallbackURL) {
+		return &models.AppError{
+			Code:    "INVALID_FALLBACK",
+			Message: "兜底链接必须以http://或https://开头",
+		}
+	}
+
+	actions, err := parseFallbackActions(fallbackActions)
+	if err != nil {
+		return &models.AppError{
+			Code:    "INVALID_FALLBACK",
+			Message: fmt.Sprintf("兜底行为格式错误: %v", err),
+		}
+	}
+	for code, action := range actions {
+		if !contains(fallbackErrorCodes, code) {
+			return &models.AppError{
+				Code:    "INVALID_FALLBACK",
+				Message: fmt.Sprintf("不支持的错误代码: %s", code),
+			}
+		}
+		switch action {
+		case FallbackActionPage, FallbackActionNotFound, FallbackActionGone:
+		case FallbackActionRedirect:
+			if fallbackURL == "" {
+				return &models.AppError{
+					Code:    "INVALID_FALLBACK",
+					Message: fmt.Sprintf("%s 配置为跳转，但未设置兜底链接", code),
+				}
+			}
+		default:
+			return &models.AppError{
+				Code:    "INVALID_FALLBACK",
+				Message: fmt.Sprintf("不支持的兜底行为: %s", action),
+			}
+		}
+	}
+	return nil
+}
+
+// applyFallbackConfig 将请求中的兜底配置应用到活码，未提供的字段保持不变
+func applyFallbackConfig(activeQR *models.ActiveQRCode, req *models.ActiveQRCodeCreateRequest) error {
+	fallbackURL := activeQR.FallbackURL
+	if req.FallbackURL != nil {
+		fallbackURL = strings.TrimSpace(*req.FallbackURL)
+	}
+	fallbackActions := activeQR.FallbackActions
+	if req.FallbackActions != nil {
+		fallbackActions = strings.TrimSpace(*req.FallbackActions)
+	}
+	if err := validateFallback(fallbackURL, fallbackActions); err != nil {
+		return err
+	}
+
+	activeQR.FallbackURL = fallbackURL
+	activeQR.FallbackActions = fallbackActions
+	if req.FallbackTitle != nil {
+		activeQR.FallbackTitle = *req.FallbackTitle
+	}
+	if req.FallbackMessage != nil {
+		activeQR.FallbackMessage = *req.FallbackMessage
+	}
+	return nil
+}
+
+// failScan 为活码相关错误附加兜底行为，并记录失败的扫描
+func (s *ActiveQRCodeService) failScan(activeQR *models.ActiveQRCode, scan *ScanContext, qrErr *QRCodeError) error {
+	qrErr.Fallback = resolveFallback(activeQR, qrErr.Code)
+
+	targetURL := ""
+	if qrErr.Fallback.Action == FallbackActionRedirect {
+		targetURL = qrErr.Fallback.URL
+	}
+
+	params := trackingParams(scan.Query)
+	go s.db.Create(&models.ScanRecord{
+		ActiveQRCodeID: &activeQR.ID,
+		IPAddress:      scan.IPAddress,
+		UserAgent:      scan.UserAgent,
+		ScanTime:       scan.recordTime(),
+		Region:         scan.Region.Name(),
+		Location:       scan.Region.String(),
+		Device:         scan.Device,
+		OS:             scan.Agent.OS,
+		OSVersion:      scan.Agent.OSVersion,
+		Browser:        scan.Agent.Browser,
+		Client:         scan.Agent.Client,
+		ClientVersion:  scan.Agent.ClientVersion,
+		Language:       scan.Language,
+		TargetURL:      targetURL,
+		ErrorCode:      qrErr.Code,
+		Channel:        scanChannel(params),
+		TrackingParams: encodeTrackingParams(params),
+	})
+
+	return qrErr
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"wechat-active-qrcode/internal/config"
+	"wechat-active-qrcode/internal/models"
+	"wechat-active-qrcode/pkg/healthcheck"
+	"wechat-active-qrcode/pkg/notify"
+)
+
+// 目标链接健康状态
+const (
+	HealthStatusHealthy   = "healthy"
+	HealthStatusUnhealthy = "unhealthy"
+)
+
+// 健康检查默认配置
+const (
+	defaultHealthCheckInterval  = 10 // 分钟
+	defaultHealthFailureLimit   = 3
+	defaultHealthRetentionDays  = 30
+	healthCheckConcurrency      = 8
+	defaultHealthCheckListLimit = 50
+)
+
+// isUnhealthyExcluded 判断静态码是否因目标链接异常而暂停分配
+func isUnhealthyExcluded(qr *models.StaticQRCode) bool {
+	return qr.ExcludeUnhealthy && qr.HealthStatus == HealthStatusUnhealthy
+}
+
+// ListTargetHealthChecks 获取静态码最近的健康检查记录（按时间倒序）
+func (s *ActiveQRCodeService) ListTargetHealthChecks(staticQRCodeID uint, limit int) ([]models.TargetHealthCheck, error) {
+	if limit <= 0 {
+		limit = defaultHealthCheckListLimit
+	}
+	var checks []models.TargetHealthCheck
+	err := s.db.Where("static_qr_code_id = ?", staticQRCodeID).
+		Order("checked_at DESC").
+		Limit(limit).
+		Find(&checks).Error
+	return checks, err
+}
+
+// HealthMonitor 定期探测启用中静态码的目标链接，记录检查历史并更新健康状态
+//
+// 连续失败达到阈值时判定为异常，一次成功即恢复；状态变化时发送通知。
+// 开启了 ExcludeUnhealthy 的静态码在异常期间不参与分配。
+type HealthMonitor struct {
+	service   *ActiveQRCodeService
+	checker   *healthcheck.Checker
+	notifier  notify.Notifier
+	interval  time.Duration
+	threshold int
+	retention time.Duration
+}
+
+// NewHealthMonitor 创建健康检查任务，client 为空时使用 http.DefaultClient
+func NewHealthMonitor(service *ActiveQRCodeService, notifier notify.Notifier, cfg config.HealthCheckConfig, client *http.Client) *HealthMonitor {
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = defaultHealthCheckInterval
+	}
+	threshold := cfg.FailureThreshold
+	if threshold <= 0 {
+		threshold = defaultHealthFailureLimit
+	}
+	retentionDays := cfg.RetentionDays
+	if retentionDays <= 0 {
+		retentionDays = defaultHealthRetentionDays
+	}
+	if notifier == nil {
+		notifier = notify.LogNotifier{}
+	}
+	if err := healthcheck.ValidateMethod(cfg.Method); err != nil {
+		log.Printf("Invalid health check method, fallback to %s: %v", healthcheck.DefaultMethod, err)
+		cfg.Method = healthcheck.DefaultMethod
+	}
+
+	return &HealthMonitor{
+		service:   service,
+		checker:   healthcheck.New(client, cfg.Method, time.Duration(cfg.Timeout)*time.Second, cfg.ExpectedStatus),
+		notifier:  notifier,
+		interval:  time.Duration(interval) * time.Minute,
+		threshold: threshold,
+		retention: time.Duration(retentionDays) * 24 * time.Hour,
+	}
+}
+
+// Start 在后台定期检查，ctx 取消后退出
+func (m *HealthMonitor) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		for {
+			if err := m.Check(ctx); err != nil {
+				log.Printf("Health check failed: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Check 探测所有启用中的链接类型静态码，并清理过期的检查记录
+func (m *HealthMonitor) Check(ctx context.Context) error {
+	var staticQRs []models.StaticQRCode
+	err := m.service.db.Preload("ActiveQRCode").
+		Where("status = ? AND type = ?", 1, models.StaticQRTypeURL).
+		Find(&staticQRs).Error
+	if err != nil {
+		return err
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		changes []string
+	)
+	sem := make(chan struct{}, healthCheckConcurrency)
+	for i := range staticQRs {
+		qr := &staticQRs[i]
+		targetURL := healthCheckURL(qr)
+		if targetURL == "" {
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if change := m.checkOne(ctx, qr, targetURL); change != "" {
+				mu.Lock()
+				changes = append(changes, change)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(changes) > 0 {
+		title := fmt.Sprintf("%d 个静态码的目标链接健康状态发生变化", len(changes))
+		if err := m.notifier.Notify(title, strings.Join(changes, "\n")); err != nil {
+			log.Printf("Health check notify failed: %v", err)
+		}
+	}
+
+	return m.service.db.Where("checked_at < ?", time.Now().Add(-m.retention)).
+		Delete(&models.TargetHealthCheck{}).Error
+}
+
+// checkOne 探测单个静态码并更新状态，状态发生变化时返回通知内容
+func (m *HealthMonitor) checkOne(ctx context.Context, qr *models.StaticQRCode, targetURL string) string {
+	result := m.checker.Check(ctx, targetURL)
+	now := time.Now()
+
+	record := &models.TargetHealthCheck{
+		StaticQRCodeID: qr.ID,
+		URL:            targetURL,
+		Method:         m.checker.Method,
+		StatusCode:     result.StatusCode,
+		LatencyMs:      result.Latency.Milliseconds(),
+		Healthy:        result.Healthy,
+		CheckedAt:      now,
+	}
+	if result.Err != nil {
+		record.Error = result.Err.Error()
+	}
+	if err := m.service.db.Create(record).Error; err != nil {
+		log.Printf("Save health check of StaticQR ID=%d failed: %v", qr.ID, err)
+	}
+
+	failures := 0
+	status := HealthStatusHealthy
+	if !result.Healthy {
+		failures = qr.HealthFailures + 1
+		status = qr.HealthStatus
+		if failures >= m.threshold {
+			status = HealthStatusUnhealthy
+		}
+	}
+
+	// 检查期间目标链接被修改时丢弃本次结果，避免旧链接的状态覆盖已重置的健康状态
+	err := m.service.db.Model(&models.StaticQRCode{}).Where("id = ? AND target_url = ?", qr.ID, qr.TargetURL).Updates(map[string]interface{}{
+		"health_status":     status,
+		"health_failures":   failures,
+		"health_checked_at": now,
+	}).Error
+	if err != nil {
+		log.Printf("Update health status of StaticQR ID=%d failed: %v", qr.ID, err)
+		return ""
+	}
+
+	switch {
+	case status == HealthStatusUnhealthy && qr.HealthStatus != HealthStatusUnhealthy:
+		action := "仍在分配"
+		if qr.ExcludeUnhealthy {
+			action = "已暂停分配"
+		}
+		return fmt.Sprintf("- 活码「%s」的静态码「%s」目标链接连续%d次检查失败（%s），%s", qr.ActiveQRCode.Name, qr.Name, failures, record.Error, action)
+	case status == HealthStatusHealthy && qr.HealthStatus == HealthStatusUnhealthy:
+		return fmt.Sprintf("- 活码「%s」的静态码「%s」目标链接已恢复", qr.ActiveQRCode.Name, qr.Name)
+	}
+	return ""
+}
+
+// healthCheckURL 返回需要探测的链接，占位符按空值替换，非http(s)链接不探测
+func healthCheckURL(qr *models.StaticQRCode) string {
+	targetURL := strings.TrimSpace(qr.TargetURL)
+	if !strings.HasPrefix(targetURL, "http://") && !strings.HasPrefix(targetURL, "https://") {
+		return ""
+	}
+	empty := make(map[string]string, len(supportedPlaceholders))
+	for _, name := range supportedPlaceholders {
+		empty[name] = ""
+	}
+	return expandTargetURL(targetURL, empty)
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"time"
+	"wechat-active-qrcode/internal/models"
+	"wechat-active-qrcode/pkg/qrcode"
+
+	"gorm.io/gorm"
+)
+
+// maxStaticImageSize 静态码图片的最大上传大小
+const maxStaticImageSize = 5 << 20
+
+// 落地页默认文案
+const (
+	DefaultLandingText = "请长按下方二维码，识别后加入群聊"
+	DefaultLandingHint = "长按二维码识别"
+)
+
+// validateStaticQRType 校验静态码类型，url类型必须设置有效的目标链接
+func validateStaticQRType(qrType, targetURL string) error {
+	switch qrType {
+	case models.StaticQRTypeURL:
+		return validateTargetURL(targetURL)
+	case models.StaticQRTypeImage:
+	default:
+		return &models.AppError{
+			Code:    "INVALID_STATIC_QR_TYPE",
+			Message: fmt.Sprintf("不支持的静态码类型: %s", qrType),
+		}
+	}
+	return nil
+}
+
+// UploadStaticQRCodeImage 上传图片类型静态码的二维码图片
+//
+// 图片必须能识别出二维码，识别出的内容保存为目标链接，图片与生成的二维码存放在同一目录。
+func (s *ActiveQRCodeService) UploadStaticQRCodeImage(id uint, header *multipart.FileHeader) (*models.StaticQRCode, error) {
+	var staticQR models.StaticQRCode
+	if err := s.db.First(&staticQR, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, &models.AppError{
+				Code:    "STATIC_QR_NOT_FOUND",
+				Message: "静态码不存在",
+			}
+		}
+		return nil, err
+	}
+
+	if header.Size > maxStaticImageSize {
+		return nil, &models.AppError{
+			Code:    "INVALID_IMAGE",
+			Message: "图片大小不能超过5MB",
+		}
+	}
+
+	// 识别图片中的二维码
+	file, err := header.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open uploaded image: %v", err)
+	}
+	content, err := qrcode.NewParser().ParseFromFile(file, header)
+	if err != nil {
+		return nil, &models.AppError{
+			Code:    "INVALID_IMAGE",
+			Message: fmt.Sprintf("图片中未识别到二维码: %v", err),
+		}
+	}
+
+	data, err := readUploadedFile(header)
+	if err != nil {
+		return nil, err
+	}
+
+	var ext string
+	switch http.DetectContentType(data) {
+	case "image/png":
+		ext = "png"
+	case "image/jpeg":
+		ext = "jpg"
+	default:
+		return nil, &models.AppError{
+			Code:    "INVALID_IMAGE",
+			Message: "仅支持PNG或JPEG格式的图片",
+		}
+	}
+
+	filename := fmt.Sprintf("static_%d_%d.%s", staticQR.ID, time.Now().UnixNano(), ext)
+	imagePath, err := s.qrGenerator.SaveImage(data, filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save image: %v", err)
+	}
+
+	// 新上传的微信群二维码7天后过期，其他图片（如个人名片）不过期
+	oldPath := staticQR.ImagePath
+	updates := map[string]interface{}{
+		"type":               models.StaticQRTypeImage,
+		"image_path":         imagePath,
+		"target_url":         content,
+		"expires_at":         groupInviteExpiresAt(content, time.Now()),
+		"expiry_notified_at": nil,
+	}
+	if err := s.db.Model(&staticQR).Updates(updates).Error; err != nil {
+		os.Remove(imagePath)
+		return nil, fmt.Errorf("failed to update static QR code: %v", err)
+	}
+
+	// 替换图片后删除旧文件
+	if oldPath != "" && oldPath != imagePath {
+		os.Remove(oldPath)
+	}
+
+	if err := s.db.Preload("ActiveQRCode").First(&staticQR, staticQR.ID).Error; err != nil {
+		return nil, err
+	}
+	return &staticQR, nil
+}
+
+// GetStaticQRCodeImage 获取图片类型静态码的图片内容
+func (s *ActiveQRCodeService) GetStaticQRCodeImage(id uint) ([]byte, error) {
+	var staticQR models.StaticQRCode
+	if err := s.db.First(&staticQR, id).Error; err != nil {
+		return nil, fmt.Errorf("static QR code not found: %v", err)
+	}
+	if staticQR.ImagePath == "" {
+		return nil, fmt.Errorf("static QR code has no image")
+	}
+
+	return s.qrGenerator.ReadQRCodeFile(staticQR.ImagePath)
+}
+
+// readUploadedFile 读取上传文件的全部内容
+func readUploadedFile(header *multipart.FileHeader) ([]byte, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open uploaded image: %v", err)
+	}
+	defer file.Close()
+
+	return io.ReadAll(io.LimitReader(file, maxStaticImageSize+1))
+}
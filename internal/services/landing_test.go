@@ -0,0 +1,98 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/textproto"
+	"testing"
+
+	goqrcode "github.com/skip2/go-qrcode"
+
+	"wechat-active-qrcode/internal/models"
+)
+
+// uploadHeader 构造上传文件，模拟 multipart 表单中的图片
+func uploadHeader(t *testing.T, filename, contentType string, data []byte) *multipart.FileHeader {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="image"; filename="%s"`, filename))
+	header.Set("Content-Type", contentType)
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	writer.Close()
+
+	form, err := multipart.NewReader(&body, writer.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { form.RemoveAll() })
+	return form.File["image"][0]
+}
+
+func TestUploadStaticQRCodeImage(t *testing.T) {
+	s, db := newTestService(t)
+	activeQR := createTestActiveQR(t, db, &models.ActiveQRCode{Name: "landing"},
+		models.StaticQRCode{Name: "group", TargetURL: "https://example.com"},
+	)
+	id := activeQR.StaticQRCodes[0].ID
+
+	content := "https://weixin.qq.com/g/AbCdEfGh"
+	upload, err := goqrcode.Encode(content, goqrcode.Medium, 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	staticQR, err := s.UploadStaticQRCodeImage(id, uploadHeader(t, "group.png", "image/png", upload))
+	if err != nil {
+		t.Fatalf("UploadStaticQRCodeImage: %v", err)
+	}
+	if !staticQR.IsImage() || staticQR.TargetURL != content || staticQR.ImagePath == "" {
+		t.Fatalf("uploaded static qr = type %q target %q image %q", staticQR.Type, staticQR.TargetURL, staticQR.ImagePath)
+	}
+	data, err := s.GetStaticQRCodeImage(id)
+	if err != nil {
+		t.Fatalf("GetStaticQRCodeImage: %v", err)
+	}
+	if !bytes.Equal(data, upload) {
+		t.Error("stored image differs from the upload")
+	}
+
+	// 扫码跳转到落地页，目标为图片类型的静态码
+	result, err := s.ResolveScan(activeQR.ShortCode, &ScanContext{UserAgent: "Mozilla/5.0", IPAddress: "10.0.0.1"})
+	if err != nil {
+		t.Fatalf("ResolveScan: %v", err)
+	}
+	if !result.StaticQR.IsImage() {
+		t.Errorf("scan resolved to type %q, want image", result.StaticQR.Type)
+	}
+
+	// 识别不出二维码或格式不支持的图片被拒绝
+	for name, data := range map[string][]byte{
+		"blank.png": pngWithoutQR(t),
+		"note.png":  []byte("not an image"),
+	} {
+		if _, err := s.UploadStaticQRCodeImage(id, uploadHeader(t, name, "image/png", data)); err == nil {
+			t.Errorf("upload of %s accepted", name)
+		} else if _, ok := err.(*models.AppError); !ok {
+			t.Errorf("upload of %s returned %v, want AppError", name, err)
+		}
+	}
+}
+
+// pngWithoutQR 生成不含二维码的纯色PNG
+func pngWithoutQR(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, 64, 64))
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"wechat-active-qrcode/internal/models"
+	"wechat-active-qrcode/pkg/language"
+)
+
+// validateLanguageList 校验语言列表（JSON数组），如["zh-CN","en"]
+func validateLanguageList(value string) error {
+	if value == "" || value == "null" {
+		return nil
+	}
+	var tags []string
+	if err := json.Unmarshal([]byte(value), &tags); err != nil {
+		return &models.AppError{
+			Code:    "INVALID_LANGUAGES",
+			Message: fmt.Sprintf("允许的语言格式错误，应为JSON数组: %v", err),
+		}
+	}
+	for _, tag := range tags {
+		if !language.IsValid(tag) {
+			return &models.AppError{
+				Code:    "INVALID_LANGUAGES",
+				Message: fmt.Sprintf("无效的语言标签: %q，应为zh-CN、en等格式", tag),
+			}
+		}
+	}
+	return nil
+}
+
+// filterLanguageMatches 按扫码者的Accept-Language在静态码中协商语言并筛选
+//
+// 协商范围为各静态码声明的允许语言。协商成功时只保留声明了该语言的静态码，
+// 否则只保留未限制语言的静态码，因此未限制语言的静态码可作为其他语言的默认目标。
+// 协商出的语言写入 scan.Language，用于扫描记录。
+func filterLanguageMatches(qrs []models.StaticQRCode, scan *ScanContext) []models.StaticQRCode {
+	var supported []string
+	for _, qr := range qrs {
+		supported = append(supported, parseJSONList(qr.AllowedLanguages)...)
+	}
+	if len(supported) == 0 {
+		return qrs
+	}
+
+	negotiated := language.Negotiate(scan.AcceptLanguage, supported)
+	if negotiated != "" {
+		scan.Language = negotiated
+	}
+
+	var matched []models.StaticQRCode
+	for _, qr := range qrs {
+		allowed := parseJSONList(qr.AllowedLanguages)
+		if (negotiated != "" && language.Contains(allowed, negotiated)) || (negotiated == "" && len(allowed) == 0) {
+			matched = append(matched, qr)
+		}
+	}
+	return matched
+}
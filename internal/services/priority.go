@@ -0,0 +1,30 @@
+package services
+
+import (
+	"wechat-active-qrcode/internal/models"
+)
+
+// highestPriorityTier 返回优先级最高的一档静态码
+//
+// 切换规则只在这一档中选择；这一档全部不可用（禁用、过期、不在时段内或名额已满）时，
+// 筛选后自然只剩较低的档位，实现主备自动切换。
+func highestPriorityTier(qrs []models.StaticQRCode) []models.StaticQRCode {
+	if len(qrs) == 0 {
+		return qrs
+	}
+
+	highest := qrs[0].Priority
+	for _, qr := range qrs {
+		if qr.Priority > highest {
+			highest = qr.Priority
+		}
+	}
+
+	tier := make([]models.StaticQRCode, 0, len(qrs))
+	for _, qr := range qrs {
+		if qr.Priority == highest {
+			tier = append(tier, qr)
+		}
+	}
+	return tier
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"testing"
+
+	"wechat-active-qrcode/internal/models"
+)
+
+func TestHighestPriorityTier(t *testing.T) {
+	qrs := []models.StaticQRCode{{ID: 1, Priority: 0}, {ID: 2, Priority: 10}, {ID: 3, Priority: 10}, {ID: 4, Priority: 5}}
+	tier := highestPriorityTier(qrs)
+	if len(tier) != 2 || tier[0].ID != 2 || tier[1].ID != 3 {
+		t.Errorf("highestPriorityTier = %v, want IDs 2 and 3", tier)
+	}
+	if tier := highestPriorityTier(nil); len(tier) != 0 {
+		t.Errorf("highestPriorityTier(nil) = %v", tier)
+	}
+}
+
+func TestScanFailsOverWhenTopTierUnavailable(t *testing.T) {
+	s, db := newTestService(t)
+	activeQR := createTestActiveQR(t, db, &models.ActiveQRCode{Name: "failover", SwitchRule: "weight"},
+		models.StaticQRCode{Name: "primary-disabled", TargetURL: "https://a.example.com", Priority: 10},
+		models.StaticQRCode{Name: "primary-full", TargetURL: "https://b.example.com", Priority: 10, MaxScans: 1},
+		models.StaticQRCode{Name: "backup", TargetURL: "https://c.example.com", Priority: 0},
+	)
+	if err := db.Model(&models.StaticQRCode{}).Where("id = ?", activeQR.StaticQRCodes[0].ID).Update("status", 0).Error; err != nil {
+		t.Fatalf("disable primary: %v", err)
+	}
+
+	// 第一次扫码占满 primary-full，之后最高一档全部不可用，切换到备用码
+	scan := func() string {
+		t.Helper()
+		result, err := s.ResolveScan(activeQR.ShortCode, &ScanContext{UserAgent: "Mozilla/5.0", IPAddress: "10.0.0.1"})
+		if err != nil {
+			t.Fatalf("ResolveScan: %v", err)
+		}
+		return result.StaticQR.Name
+	}
+	if got := scan(); got != "primary-full" {
+		t.Fatalf("first scan went to %s, want primary-full", got)
+	}
+	for i := 0; i < 3; i++ {
+		if got := scan(); got != "backup" {
+			t.Fatalf("scan after top tier is full went to %s, want backup", got)
+		}
+	}
+}
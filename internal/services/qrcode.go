@@ -2,8 +2,10 @@ package services
 
 import (
 	"errors"
+	"path/filepath"
 	"wechat-active-qrcode/internal/models"
 	"wechat-active-qrcode/pkg/qrcode"
+	"wechat-active-qrcode/pkg/useragent"
 	"wechat-active-qrcode/pkg/utils"
 
 	"gorm.io/gorm"
@@ -28,11 +30,18 @@ func (s *QRCodeService) CreateQRCode(req *models.QRCodeCreateRequest) (*models.Q
 		return nil, errors.New("invalid URL format")
 	}
 
+	// 读取样式模板
+	styleID := optionalStyleID(req.QRStyleID)
+	tmpl, err := loadQRStyle(s.db, styleID)
+	if err != nil {
+		return nil, err
+	}
+
 	// 生成二维码文件名
 	filename := s.generator.GenerateFilename("qr")
 
-	// 生成二维码图片
-	qrCodePath, err := s.generator.GenerateQRCode(req.OriginalURL, filename)
+	// 按样式模板生成二维码图片
+	qrCodePath, err := s.saveQRCodeImage(tmpl, req.OriginalURL, filename)
 	if err != nil {
 		return nil, err
 	}
@@ -42,6 +51,7 @@ func (s *QRCodeService) CreateQRCode(req *models.QRCodeCreateRequest) (*models.Q
 		Name:        req.Name,
 		OriginalURL: req.OriginalURL,
 		QRCodePath:  qrCodePath,
+		QRStyleID:   styleID,
 		Status:      1,
 	}
 
@@ -75,6 +85,13 @@ func (s *QRCodeService) UpdateQRCode(id uint, req *models.QRCodeUpdateRequest) (
 	if req.Name != "" {
 		qrCode.Name = req.Name
 	}
+	if req.QRStyleID != nil {
+		qrCode.QRStyleID = optionalStyleID(req.QRStyleID)
+	}
+	tmpl, err := loadQRStyle(s.db, qrCode.QRStyleID)
+	if err != nil {
+		return nil, err
+	}
 	if req.OriginalURL != "" {
 		if !utils.IsValidURL(req.OriginalURL) {
 			return nil, errors.New("invalid URL format")
@@ -83,7 +100,7 @@ func (s *QRCodeService) UpdateQRCode(id uint, req *models.QRCodeUpdateRequest) (
 
 		// 重新生成二维码
 		filename := s.generator.GenerateFilename("qr")
-		qrCodePath, err := s.generator.GenerateQRCode(req.OriginalURL, filename)
+		qrCodePath, err := s.saveQRCodeImage(tmpl, req.OriginalURL, filename)
 		if err != nil {
 			return nil, err
 		}
@@ -94,6 +111,11 @@ func (s *QRCodeService) UpdateQRCode(id uint, req *models.QRCodeUpdateRequest) (
 		}
 
 		qrCode.QRCodePath = qrCodePath
+	} else if req.QRStyleID != nil && qrCode.QRCodePath != "" {
+		// 样式模板变化后覆盖原图片
+		if _, err := s.saveQRCodeImage(tmpl, qrCode.OriginalURL, filepath.Base(qrCode.QRCodePath)); err != nil {
+			return nil, err
+		}
 	}
 	if req.Status != nil {
 		qrCode.Status = *req.Status
@@ -160,27 +182,65 @@ func (s *QRCodeService) RecordScan(qrCodeID uint, ipAddress, userAgent string) e
 		return errors.New("QR code is disabled")
 	}
 
-	// 创建扫描记录
+	// 创建扫描记录，与活码扫码一样解析User-Agent
+	agent := useragent.Parse(userAgent)
 	scanRecord := &models.ScanRecord{
-		QRCodeID:  &qrCodeID,
-		IPAddress: ipAddress,
-		UserAgent: userAgent,
+		QRCodeID:      &qrCodeID,
+		IPAddress:     ipAddress,
+		UserAgent:     userAgent,
+		OS:            agent.OS,
+		OSVersion:     agent.OSVersion,
+		Browser:       agent.Browser,
+		Client:        agent.Client,
+		ClientVersion: agent.ClientVersion,
 	}
 
 	return s.db.Create(scanRecord).Error
 }
 
-// GetQRCodeImage 获取二维码图片
-func (s *QRCodeService) GetQRCodeImage(id uint) ([]byte, error) {
+// GetQRCodeImage 获取二维码图片，未指定的图片参数使用全局配置
+func (s *QRCodeService) GetQRCodeImage(id uint, req *models.QRImageRequest) ([]byte, qrcode.Format, error) {
 	var qrCode models.QRCode
 	if err := s.db.First(&qrCode, id).Error; err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	if qrCode.QRCodePath == "" {
-		return nil, errors.New("QR code image not found")
+	tmpl, err := loadQRStyle(s.db, qrCode.QRStyleID)
+	if err != nil {
+		return nil, "", err
 	}
+	base, style, err := templateAppearance(s.generator, tmpl)
+	if err != nil {
+		return nil, "", err
+	}
+	format, opts, err := resolveQRImageRequest(base, req)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// 按参数、样式模板和海报边框从原始链接生成（结果会被缓存）
+	frame := resolveQRFrame(tmpl, req, qrCode.Name)
+	imageData, err := s.generator.RenderFramed(qrCode.OriginalURL, format, opts, style, frame)
+	if err != nil {
+		return nil, "", designError(err)
+	}
+	return imageData, format, nil
+}
+
+// saveQRCodeImage 按样式模板生成二维码图片并保存为 filename
+func (s *QRCodeService) saveQRCodeImage(tmpl *models.QRStyle, content, filename string) (string, error) {
+	data, err := renderTemplateImage(s.generator, tmpl, content)
+	if err != nil {
+		return "", err
+	}
+	return s.generator.SaveImage(data, filename)
+}
 
-	// 读取二维码图片文件
-	return s.generator.ReadQRCodeFile(qrCode.QRCodePath)
+// optionalStyleID 请求中的样式模板ID，为空或0表示不使用模板
+func optionalStyleID(id *uint) *uint {
+	if id == nil || *id == 0 {
+		return nil
+	}
+	styleID := *id
+	return &styleID
 }
@@ -0,0 +1,30 @@
+package services
+
+import (
+	"testing"
+
+	"wechat-active-qrcode/internal/models"
+)
+
+func TestRecordScanParsesUserAgent(t *testing.T) {
+	_, db := newTestService(t)
+	s := NewQRCodeService(db, nil)
+
+	qrCode := &models.QRCode{Name: "legacy", OriginalURL: "https://example.com", Status: 1}
+	if err := db.Create(qrCode).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	ua := "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 MicroMessenger/8.0.40(0x18002831) NetType/WIFI Language/zh_CN"
+	if err := s.RecordScan(qrCode.ID, "127.0.0.1", ua); err != nil {
+		t.Fatalf("RecordScan: %v", err)
+	}
+
+	var record models.ScanRecord
+	if err := db.Where("qr_code_id = ?", qrCode.ID).First(&record).Error; err != nil {
+		t.Fatal(err)
+	}
+	if record.OS != "iOS" || record.OSVersion != "17.1" || record.Client != "wechat" || record.ClientVersion != "8.0.40" {
+		t.Errorf("record = os %q %q, client %q %q", record.OS, record.OSVersion, record.Client, record.ClientVersion)
+	}
+}
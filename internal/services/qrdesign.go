@@ -0,0 +1,263 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+	"wechat-active-qrcode/internal/models"
+	"wechat-active-qrcode/pkg/qrcode"
+
+	"gorm.io/gorm"
+)
+
+// invalidQRDesign 品牌样式错误
+func invalidQRDesign(err error) error {
+	return &models.AppError{
+		Code:    "INVALID_QR_DESIGN",
+		Message: err.Error(),
+	}
+}
+
+// designError 将样式错误转换为 AppError，其他错误原样返回
+func designError(err error) error {
+	var designErr *qrcode.DesignError
+	if errors.As(err, &designErr) {
+		return invalidQRDesign(err)
+	}
+	return err
+}
+
+// parseQRDesign 解析保存的品牌样式，为空表示标准黑白二维码
+func parseQRDesign(raw string) (*qrcode.Style, error) {
+	style := &qrcode.Style{}
+	if raw == "" {
+		return style, nil
+	}
+	if err := json.Unmarshal([]byte(raw), style); err != nil {
+		return nil, invalidQRDesign(fmt.Errorf("品牌样式格式错误: %v", err))
+	}
+	return style, nil
+}
+
+// normalizeQRDesign 校验品牌样式并统一格式，标准样式返回空字符串
+func normalizeQRDesign(raw string) (string, error) {
+	style, err := parseQRDesign(raw)
+	if err != nil {
+		return "", err
+	}
+	if err := style.Validate(); err != nil {
+		return "", invalidQRDesign(err)
+	}
+	if style.IsZero() {
+		return "", nil
+	}
+	normalized, _ := json.Marshal(style)
+	return string(normalized), nil
+}
+
+// readQRDesign 解析品牌样式，Logo 文件在生成图片未命中缓存时才读取
+func readQRDesign(design, logoPath string) (*qrcode.Style, error) {
+	style, err := parseQRDesign(design)
+	if err != nil {
+		return nil, err
+	}
+	style.LogoFile = logoPath
+	return style, nil
+}
+
+// activeQRAppearance 活码最终使用的图片参数和品牌样式，活码自身的设置优先于样式模板
+func activeQRAppearance(generator *qrcode.Generator, tmpl *models.QRStyle, activeQR *models.ActiveQRCode) (qrcode.Options, *qrcode.Style, error) {
+	opts, err := activeQRImageOptions(generator, tmpl, activeQR)
+	if err != nil {
+		// 保存时已校验，全局配置修改后才可能超出范围，此时使用全局配置
+		opts = generator.Options()
+	}
+
+	design, logoPath := activeQR.QRDesign, activeQR.QRLogoPath
+	if tmpl != nil {
+		if design == "" {
+			design = tmpl.Design
+		}
+		if logoPath == "" {
+			logoPath = tmpl.LogoPath
+		}
+	}
+	style, err := readQRDesign(design, logoPath)
+	return opts, style, err
+}
+
+// renderActiveQRImage 按活码的图片参数和品牌样式生成PNG图片，同时确认设计能被识别
+func (s *ActiveQRCodeService) renderActiveQRImage(activeQR *models.ActiveQRCode) ([]byte, error) {
+	tmpl, err := loadQRStyle(s.db, activeQR.QRStyleID)
+	if err != nil {
+		return nil, err
+	}
+	return s.renderActiveQRImageWith(tmpl, activeQR)
+}
+
+// renderActiveQRImageWith 使用指定的样式模板生成活码PNG图片
+func (s *ActiveQRCodeService) renderActiveQRImageWith(tmpl *models.QRStyle, activeQR *models.ActiveQRCode) ([]byte, error) {
+	opts, style, err := activeQRAppearance(s.qrGenerator, tmpl, activeQR)
+	if err != nil {
+		return nil, err
+	}
+	redirectURL := fmt.Sprintf("%s/r/%s", s.config.Server.BaseURL, activeQR.ShortCode)
+	data, err := s.qrGenerator.RenderStyled(redirectURL, qrcode.FormatPNG, opts, style)
+	if err != nil {
+		return nil, designError(err)
+	}
+	return data, nil
+}
+
+// saveActiveQRImage 重新生成活码的二维码图片文件
+func (s *ActiveQRCodeService) saveActiveQRImage(activeQR *models.ActiveQRCode) error {
+	data, err := s.renderActiveQRImage(activeQR)
+	if err != nil {
+		return err
+	}
+	return s.writeActiveQRImage(activeQR, data)
+}
+
+// writeActiveQRImage 保存活码的二维码图片文件，首次保存时记录路径
+func (s *ActiveQRCodeService) writeActiveQRImage(activeQR *models.ActiveQRCode, data []byte) error {
+	filename := fmt.Sprintf("active_%d.png", activeQR.ID)
+	if activeQR.QRCodePath != "" {
+		filename = filepath.Base(activeQR.QRCodePath)
+	}
+	qrPath, err := s.qrGenerator.SaveImage(data, filename)
+	if err != nil {
+		return fmt.Errorf("failed to save QR code image: %v", err)
+	}
+	if activeQR.QRCodePath != qrPath {
+		activeQR.QRCodePath = qrPath
+		return s.db.Model(activeQR).Update("qr_code_path", qrPath).Error
+	}
+	return nil
+}
+
+// applyQRDesign 更新活码的品牌样式和样式模板并校验，未传入的字段保持不变
+func (s *ActiveQRCodeService) applyQRDesign(activeQR *models.ActiveQRCode, req *models.ActiveQRCodeCreateRequest) error {
+	if req.QRDesign != nil {
+		design, err := normalizeQRDesign(*req.QRDesign)
+		if err != nil {
+			return err
+		}
+		activeQR.QRDesign = design
+	}
+	if req.QRStyleID != nil {
+		activeQR.QRStyleID = optionalStyleID(req.QRStyleID)
+	}
+
+	// 样式或图片参数变化后都需要重新确认能被识别
+	_, err := s.renderActiveQRImage(activeQR)
+	return err
+}
+
+// UploadActiveQRLogo 上传活码二维码中心的Logo
+//
+// 上传后会使用H级纠错，并确认加上Logo后的设计仍能被识别。
+func (s *ActiveQRCodeService) UploadActiveQRLogo(id uint, header *multipart.FileHeader) (*models.ActiveQRCode, error) {
+	var activeQR models.ActiveQRCode
+	if err := s.db.First(&activeQR, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, &models.AppError{
+				Code:    "ACTIVE_QR_NOT_FOUND",
+				Message: "活码不存在",
+			}
+		}
+		return nil, err
+	}
+
+	logoPath, err := s.saveLogo(header, fmt.Sprintf("logo_active_%d", activeQR.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	oldPath := activeQR.QRLogoPath
+	activeQR.QRLogoPath = logoPath
+	data, err := s.renderActiveQRImage(&activeQR)
+	if err != nil {
+		os.Remove(logoPath)
+		return nil, err
+	}
+	if err := s.db.Model(&activeQR).Update("qr_logo_path", logoPath).Error; err != nil {
+		os.Remove(logoPath)
+		return nil, fmt.Errorf("failed to update active QR code: %v", err)
+	}
+	if err := s.writeActiveQRImage(&activeQR, data); err != nil {
+		return nil, err
+	}
+
+	// 替换Logo后删除旧文件
+	if oldPath != "" && oldPath != logoPath {
+		os.Remove(oldPath)
+	}
+
+	return s.GetActiveQRCode(id)
+}
+
+// DeleteActiveQRLogo 删除活码二维码中心的Logo
+func (s *ActiveQRCodeService) DeleteActiveQRLogo(id uint) (*models.ActiveQRCode, error) {
+	var activeQR models.ActiveQRCode
+	if err := s.db.First(&activeQR, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, &models.AppError{
+				Code:    "ACTIVE_QR_NOT_FOUND",
+				Message: "活码不存在",
+			}
+		}
+		return nil, err
+	}
+
+	if activeQR.QRLogoPath != "" {
+		if err := s.db.Model(&activeQR).Update("qr_logo_path", "").Error; err != nil {
+			return nil, fmt.Errorf("failed to update active QR code: %v", err)
+		}
+		os.Remove(activeQR.QRLogoPath)
+		activeQR.QRLogoPath = ""
+		if err := s.saveActiveQRImage(&activeQR); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.GetActiveQRCode(id)
+}
+
+// saveLogo 校验上传的Logo图片并保存，文件名为 prefix_时间戳.扩展名
+func (s *ActiveQRCodeService) saveLogo(header *multipart.FileHeader, prefix string) (string, error) {
+	if header.Size > maxStaticImageSize {
+		return "", &models.AppError{
+			Code:    "INVALID_IMAGE",
+			Message: "图片大小不能超过5MB",
+		}
+	}
+	data, err := readUploadedFile(header)
+	if err != nil {
+		return "", err
+	}
+
+	var ext string
+	switch http.DetectContentType(data) {
+	case "image/png":
+		ext = "png"
+	case "image/jpeg":
+		ext = "jpg"
+	default:
+		return "", &models.AppError{
+			Code:    "INVALID_IMAGE",
+			Message: "仅支持PNG或JPEG格式的图片",
+		}
+	}
+
+	filename := fmt.Sprintf("%s_%d.%s", prefix, time.Now().UnixNano(), ext)
+	logoPath, err := s.qrGenerator.SaveImage(data, filename)
+	if err != nil {
+		return "", fmt.Errorf("failed to save logo: %v", err)
+	}
+	return logoPath, nil
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"wechat-active-qrcode/internal/models"
+	"wechat-active-qrcode/pkg/qrcode"
+)
+
+// invalidImageOptions 图片参数错误
+func invalidImageOptions(err error) error {
+	return &models.AppError{
+		Code:    "INVALID_IMAGE_OPTIONS",
+		Message: err.Error(),
+	}
+}
+
+// applyQRImageConfig 更新活码的二维码图片参数并校验，未传入的字段保持不变
+func applyQRImageConfig(generator *qrcode.Generator, activeQR *models.ActiveQRCode, req *models.ActiveQRCodeCreateRequest) error {
+	if req.QRSize != nil {
+		activeQR.QRSize = *req.QRSize
+	}
+	if req.QRMargin != nil {
+		activeQR.QRMargin = nil
+		if *req.QRMargin >= 0 {
+			margin := *req.QRMargin
+			activeQR.QRMargin = &margin
+		}
+	}
+	if req.QRLevel != nil {
+		activeQR.QRLevel = *req.QRLevel
+	}
+
+	opts, err := generator.Options().Override(activeQR.QRSize, activeQR.QRMargin, activeQR.QRLevel)
+	if err != nil {
+		return invalidImageOptions(err)
+	}
+	if activeQR.QRLevel != "" {
+		activeQR.QRLevel = string(opts.Level)
+	}
+	return nil
+}
+
+// templateImageOptions 在全局配置上应用样式模板的图片参数，tmpl 为空时返回全局配置
+func templateImageOptions(generator *qrcode.Generator, tmpl *models.QRStyle) (qrcode.Options, error) {
+	if tmpl == nil {
+		return generator.Options(), nil
+	}
+	return generator.Options().Override(tmpl.Size, tmpl.Margin, tmpl.Level)
+}
+
+// activeQRImageOptions 依次应用全局配置、样式模板和活码的图片参数
+func activeQRImageOptions(generator *qrcode.Generator, tmpl *models.QRStyle, activeQR *models.ActiveQRCode) (qrcode.Options, error) {
+	base, err := templateImageOptions(generator, tmpl)
+	if err != nil {
+		return base, err
+	}
+	return base.Override(activeQR.QRSize, activeQR.QRMargin, activeQR.QRLevel)
+}
+
+// resolveQRImageRequest 解析请求的图片格式，并在 base 上应用请求中的图片参数
+func resolveQRImageRequest(base qrcode.Options, req *models.QRImageRequest) (qrcode.Format, qrcode.Options, error) {
+	if req == nil {
+		return qrcode.FormatPNG, base, nil
+	}
+	format, err := qrcode.ParseFormat(req.Format)
+	if err != nil {
+		return "", base, invalidImageOptions(err)
+	}
+	opts, err := base.Override(req.Size, req.Margin, req.Level)
+	if err != nil {
+		return "", base, invalidImageOptions(err)
+	}
+	return format, opts, nil
+}
+
+// resolveQRFrame 按请求参数和样式模板生成海报边框，请求未要求边框时返回 nil
+func resolveQRFrame(tmpl *models.QRStyle, req *models.QRImageRequest, title string) *qrcode.Frame {
+	if req == nil || !req.Frame {
+		return nil
+	}
+	frame := &qrcode.Frame{
+		Title:    title,
+		Caption:  req.Caption,
+		Position: req.Position,
+		Padding:  req.Padding,
+	}
+	if tmpl != nil {
+		if frame.Caption == "" {
+			frame.Caption = tmpl.Caption
+		}
+		if frame.Position == "" {
+			frame.Position = tmpl.FramePosition
+		}
+		if frame.Padding == 0 {
+			frame.Padding = tmpl.FramePadding
+		}
+	}
+	return frame
+}
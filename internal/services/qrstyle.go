@@ -0,0 +1,307 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+	"wechat-active-qrcode/internal/models"
+	"wechat-active-qrcode/pkg/qrcode"
+
+	"gorm.io/gorm"
+)
+
+// qrStyleNotFound 样式模板不存在
+func qrStyleNotFound() error {
+	return &models.AppError{
+		Code:    "QR_STYLE_NOT_FOUND",
+		Message: "样式模板不存在",
+	}
+}
+
+// loadQRStyle 读取二维码引用的样式模板，未引用时返回 nil
+func loadQRStyle(db *gorm.DB, id *uint) (*models.QRStyle, error) {
+	if id == nil {
+		return nil, nil
+	}
+	var tmpl models.QRStyle
+	if err := db.First(&tmpl, *id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, qrStyleNotFound()
+		}
+		return nil, err
+	}
+	return &tmpl, nil
+}
+
+// templateAppearance 普通二维码使用的图片参数和品牌样式，均来自样式模板
+func templateAppearance(generator *qrcode.Generator, tmpl *models.QRStyle) (qrcode.Options, *qrcode.Style, error) {
+	opts, err := templateImageOptions(generator, tmpl)
+	if err != nil {
+		// 保存时已校验，全局配置修改后才可能超出范围，此时使用全局配置
+		opts = generator.Options()
+	}
+	if tmpl == nil {
+		return opts, &qrcode.Style{}, nil
+	}
+	style, err := readQRDesign(tmpl.Design, tmpl.LogoPath)
+	return opts, style, err
+}
+
+// renderTemplateImage 使用样式模板生成PNG图片，同时确认设计能被识别
+func renderTemplateImage(generator *qrcode.Generator, tmpl *models.QRStyle, content string) ([]byte, error) {
+	opts, style, err := templateAppearance(generator, tmpl)
+	if err != nil {
+		return nil, err
+	}
+	data, err := generator.RenderStyled(content, qrcode.FormatPNG, opts, style)
+	if err != nil {
+		return nil, designError(err)
+	}
+	return data, nil
+}
+
+// ListQRStyles 获取样式模板列表
+func (s *ActiveQRCodeService) ListQRStyles() ([]models.QRStyle, error) {
+	var styles []models.QRStyle
+	if err := s.db.Order("id").Find(&styles).Error; err != nil {
+		return nil, err
+	}
+	return styles, nil
+}
+
+// GetQRStyle 获取样式模板
+func (s *ActiveQRCodeService) GetQRStyle(id uint) (*models.QRStyle, error) {
+	return loadQRStyle(s.db, &id)
+}
+
+// CreateQRStyle 创建样式模板
+func (s *ActiveQRCodeService) CreateQRStyle(req *models.QRStyleRequest) (*models.QRStyle, error) {
+	tmpl := &models.QRStyle{}
+	if err := s.applyQRStyleRequest(tmpl, req); err != nil {
+		return nil, err
+	}
+
+	// 按活码链接的长度确认设计能被识别
+	sample := fmt.Sprintf("%s/r/%s", s.config.Server.BaseURL, strings.Repeat("0", 8))
+	if _, err := renderTemplateImage(s.qrGenerator, tmpl, sample); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(tmpl).Error; err != nil {
+		return nil, fmt.Errorf("failed to create QR style: %v", err)
+	}
+	return tmpl, nil
+}
+
+// UpdateQRStyle 更新样式模板，并重新生成引用它的所有二维码图片
+//
+// 任何一个引用它的二维码使用新样式后无法被识别时，不做修改并返回错误。
+func (s *ActiveQRCodeService) UpdateQRStyle(id uint, req *models.QRStyleRequest) (*models.QRStyle, error) {
+	tmpl, err := loadQRStyle(s.db, &id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyQRStyleRequest(tmpl, req); err != nil {
+		return nil, err
+	}
+
+	images, err := s.renderQRStyleUsers(tmpl)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Save(tmpl).Error; err != nil {
+		return nil, fmt.Errorf("failed to update QR style: %v", err)
+	}
+	s.writeQRStyleImages(images)
+	return tmpl, nil
+}
+
+// DeleteQRStyle 删除样式模板，仍被二维码引用时不能删除
+func (s *ActiveQRCodeService) DeleteQRStyle(id uint) error {
+	tmpl, err := loadQRStyle(s.db, &id)
+	if err != nil {
+		return err
+	}
+
+	var activeCount, legacyCount int64
+	s.db.Model(&models.ActiveQRCode{}).Where("qr_style_id = ?", id).Count(&activeCount)
+	s.db.Model(&models.QRCode{}).Where("qr_style_id = ?", id).Count(&legacyCount)
+	if activeCount+legacyCount > 0 {
+		return &models.AppError{
+			Code:    "QR_STYLE_IN_USE",
+			Message: fmt.Sprintf("样式模板正在被 %d 个二维码使用，请先解除引用", activeCount+legacyCount),
+		}
+	}
+
+	if err := s.db.Delete(tmpl).Error; err != nil {
+		return fmt.Errorf("failed to delete QR style: %v", err)
+	}
+	if tmpl.LogoPath != "" {
+		os.Remove(tmpl.LogoPath)
+	}
+	return nil
+}
+
+// UploadQRStyleLogo 上传样式模板的Logo，并重新生成引用它的所有二维码图片
+func (s *ActiveQRCodeService) UploadQRStyleLogo(id uint, header *multipart.FileHeader) (*models.QRStyle, error) {
+	tmpl, err := loadQRStyle(s.db, &id)
+	if err != nil {
+		return nil, err
+	}
+
+	logoPath, err := s.saveLogo(header, fmt.Sprintf("logo_style_%d", tmpl.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	oldPath := tmpl.LogoPath
+	tmpl.LogoPath = logoPath
+	images, err := s.renderQRStyleUsers(tmpl)
+	if err != nil {
+		os.Remove(logoPath)
+		return nil, err
+	}
+	if err := s.db.Model(tmpl).Update("logo_path", logoPath).Error; err != nil {
+		os.Remove(logoPath)
+		return nil, fmt.Errorf("failed to update QR style: %v", err)
+	}
+	s.writeQRStyleImages(images)
+
+	// 替换Logo后删除旧文件
+	if oldPath != "" && oldPath != logoPath {
+		os.Remove(oldPath)
+	}
+	return tmpl, nil
+}
+
+// DeleteQRStyleLogo 删除样式模板的Logo，并重新生成引用它的所有二维码图片
+func (s *ActiveQRCodeService) DeleteQRStyleLogo(id uint) (*models.QRStyle, error) {
+	tmpl, err := loadQRStyle(s.db, &id)
+	if err != nil {
+		return nil, err
+	}
+	if tmpl.LogoPath == "" {
+		return tmpl, nil
+	}
+
+	oldPath := tmpl.LogoPath
+	tmpl.LogoPath = ""
+	images, err := s.renderQRStyleUsers(tmpl)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(tmpl).Update("logo_path", "").Error; err != nil {
+		return nil, fmt.Errorf("failed to update QR style: %v", err)
+	}
+	s.writeQRStyleImages(images)
+	os.Remove(oldPath)
+	return tmpl, nil
+}
+
+// applyQRStyleRequest 校验请求并写入样式模板
+func (s *ActiveQRCodeService) applyQRStyleRequest(tmpl *models.QRStyle, req *models.QRStyleRequest) error {
+	design, err := normalizeQRDesign(req.Design)
+	if err != nil {
+		return err
+	}
+
+	var margin *int
+	if req.Margin != nil && *req.Margin >= 0 {
+		value := *req.Margin
+		margin = &value
+	}
+	opts, err := s.qrGenerator.Options().Override(req.Size, margin, req.Level)
+	if err != nil {
+		return invalidImageOptions(err)
+	}
+
+	tmpl.Name = req.Name
+	tmpl.Design = design
+	tmpl.Size = req.Size
+	tmpl.Margin = margin
+	tmpl.Level = ""
+	if req.Level != "" {
+		tmpl.Level = string(opts.Level)
+	}
+	frame := &qrcode.Frame{Caption: req.Caption, Position: req.FramePosition, Padding: req.FramePadding}
+	if err := frame.Validate(); err != nil {
+		return invalidQRDesign(err)
+	}
+
+	tmpl.Caption = req.Caption
+	tmpl.FramePosition = req.FramePosition
+	tmpl.FramePadding = req.FramePadding
+	tmpl.Description = req.Description
+	return nil
+}
+
+// qrStyleImage 引用样式模板的二维码按新样式生成的图片
+type qrStyleImage struct {
+	activeQR *models.ActiveQRCode
+	qrCode   *models.QRCode
+	data     []byte
+}
+
+// renderQRStyleUsers 清除引用样式模板的二维码的缓存图片，并按 tmpl 重新生成
+//
+// 任何一个二维码无法被识别时返回错误，此时已清除的缓存会在下次访问时按原样式重新生成。
+func (s *ActiveQRCodeService) renderQRStyleUsers(tmpl *models.QRStyle) ([]qrStyleImage, error) {
+	var activeQRs []models.ActiveQRCode
+	if err := s.db.Where("qr_style_id = ?", tmpl.ID).Find(&activeQRs).Error; err != nil {
+		return nil, err
+	}
+	var qrCodes []models.QRCode
+	if err := s.db.Where("qr_style_id = ?", tmpl.ID).Find(&qrCodes).Error; err != nil {
+		return nil, err
+	}
+
+	images := make([]qrStyleImage, 0, len(activeQRs)+len(qrCodes))
+	for i := range activeQRs {
+		activeQR := &activeQRs[i]
+		s.qrGenerator.Invalidate(fmt.Sprintf("%s/r/%s", s.config.Server.BaseURL, activeQR.ShortCode))
+		data, err := s.renderActiveQRImageWith(tmpl, activeQR)
+		if err != nil {
+			return nil, qrStyleUserError(err, fmt.Sprintf("活码「%s」", activeQR.Name))
+		}
+		images = append(images, qrStyleImage{activeQR: activeQR, data: data})
+	}
+	for i := range qrCodes {
+		qrCode := &qrCodes[i]
+		s.qrGenerator.Invalidate(qrCode.OriginalURL)
+		data, err := renderTemplateImage(s.qrGenerator, tmpl, qrCode.OriginalURL)
+		if err != nil {
+			return nil, qrStyleUserError(err, fmt.Sprintf("二维码「%s」", qrCode.Name))
+		}
+		images = append(images, qrStyleImage{qrCode: qrCode, data: data})
+	}
+	return images, nil
+}
+
+// qrStyleUserError 在样式错误前加上无法使用新样式的二维码名称
+func qrStyleUserError(err error, name string) error {
+	if appErr, ok := err.(*models.AppError); ok {
+		return &models.AppError{
+			Code:    appErr.Code,
+			Message: fmt.Sprintf("%s使用该样式后%s", name, appErr.Message),
+		}
+	}
+	return err
+}
+
+// writeQRStyleImages 保存重新生成的二维码图片文件，单个文件失败不影响其他二维码
+func (s *ActiveQRCodeService) writeQRStyleImages(images []qrStyleImage) {
+	for _, image := range images {
+		var err error
+		if image.activeQR != nil {
+			err = s.writeActiveQRImage(image.activeQR, image.data)
+		} else if image.qrCode.QRCodePath != "" {
+			_, err = s.qrGenerator.SaveImage(image.data, filepath.Base(image.qrCode.QRCodePath))
+		}
+		if err != nil {
+			log.Printf("Failed to regenerate QR code image after style change: %v", err)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"fmt"
+	"wechat-active-qrcode/internal/models"
+)
+
+// 活码跳转方式
+const (
+	RedirectModeFound     = "302"  // 临时重定向（默认）
+	RedirectModeTemporary = "307"  // 临时重定向，保留请求方法
+	RedirectModePermanent = "301"  // 永久重定向，会被浏览器和微信缓存，切换目标后可能不生效
+	RedirectModeHTML      = "html" // 返回meta refresh + JS跳转页面，兼容处理Location异常的App内置浏览器
+)
+
+// DefaultRedirectMode 未配置时的跳转方式
+const DefaultRedirectMode = RedirectModeFound
+
+// isValidRedirectMode 判断跳转方式是否受支持
+func isValidRedirectMode(mode string) bool {
+	switch mode {
+	case RedirectModeFound, RedirectModeTemporary, RedirectModePermanent, RedirectModeHTML:
+		return true
+	}
+	return false
+}
+
+// validateRedirectMode 校验活码的跳转方式，为空表示使用全局配置
+func validateRedirectMode(mode string) error {
+	if mode == "" || isValidRedirectMode(mode) {
+		return nil
+	}
+	return &models.AppError{
+		Code:    "INVALID_REDIRECT_MODE",
+		Message: fmt.Sprintf("不支持的跳转方式: %s，可选值为301、302、307、html", mode),
+	}
+}
+
+// redirectMode 返回活码生效的跳转方式：活码配置优先，其次全局配置，默认302
+func (s *ActiveQRCodeService) redirectMode(activeQR *models.ActiveQRCode) string {
+	if isValidRedirectMode(activeQR.RedirectMode) {
+		return activeQR.RedirectMode
+	}
+	if s.config != nil && isValidRedirectMode(s.config.Server.RedirectMode) {
+		return s.config.Server.RedirectMode
+	}
+	return DefaultRedirectMode
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"wechat-active-qrcode/internal/config"
+	"wechat-active-qrcode/internal/database"
+	"wechat-active-qrcode/internal/models"
+	"wechat-active-qrcode/pkg/qrcode"
+)
+
+// newTestService 使用临时SQLite数据库创建活码服务
+func newTestService(t *testing.T) (*ActiveQRCodeService, *gorm.DB) {
+	t.Helper()
+	db, err := database.NewSQLiteConnection(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	db.Logger = logger.Default.LogMode(logger.Silent)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	cfg := &config.Config{Server: config.ServerConfig{BaseURL: "http://localhost"}}
+	return NewActiveQRCodeService(db, qrcode.NewGenerator(t.TempDir()), cfg, nil), db
+}
+
+// createTestActiveQR 创建活码及其静态码，静态码按传入顺序保存
+func createTestActiveQR(t *testing.T, db *gorm.DB, activeQR *models.ActiveQRCode, staticQRs ...models.StaticQRCode) *models.ActiveQRCode {
+	t.Helper()
+	if activeQR.ShortCode == "" {
+		activeQR.ShortCode = "test" + activeQR.Name
+	}
+	if activeQR.Status == 0 {
+		activeQR.Status = 1
+	}
+	if err := db.Create(activeQR).Error; err != nil {
+		t.Fatalf("create active qr: %v", err)
+	}
+	for i := range staticQRs {
+		staticQRs[i].ActiveQRCodeID = activeQR.ID
+		if staticQRs[i].Status == 0 {
+			staticQRs[i].Status = 1
+		}
+		if err := db.Create(&staticQRs[i]).Error; err != nil {
+			t.Fatalf("create static qr: %v", err)
+		}
+	}
+	if err := db.Preload("StaticQRCodes").First(activeQR, activeQR.ID).Error; err != nil {
+		t.Fatalf("reload active qr: %v", err)
+	}
+	return activeQR
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"wechat-active-qrcode/internal/models"
+	"wechat-active-qrcode/pkg/geoip"
+)
+
+// SimulatedScan 模拟扫码时补全的扫描环境
+type SimulatedScan struct {
+	Device        string    `json:"device"`
+	OS            string    `json:"os"`
+	OSVersion     string    `json:"os_version"`
+	Browser       string    `json:"browser"`
+	Client        string    `json:"client"`
+	ClientVersion string    `json:"client_version"`
+	Language      string    `json:"language"`
+	Country       string    `json:"country"`
+	Province      string    `json:"province"`
+	City          string    `json:"city"`
+	Time          time.Time `json:"time"` // 活码所在时区的扫码时间
+	Weekday       int       `json:"weekday"`
+	Channel       string    `json:"channel"`
+}
+
+// StaticQRCodeDecision 静态码在模拟扫码中的筛选结果
+type StaticQRCodeDecision struct {
+	ID            uint             `json:"id"`
+	Name          string           `json:"name"`
+	Accepted      bool             `json:"accepted"`            // 是否进入切换规则的候选列表
+	Rejection     *FilterRejection `json:"rejection,omitempty"` // 未通过的筛选条件
+	Probability   float64          `json:"probability"`         // 被选中的概率
+	TargetURL     string           `json:"target_url,omitempty"`
+	OpenInBrowser bool             `json:"open_in_browser"`
+}
+
+// SimulationResult 模拟扫码结果
+type SimulationResult struct {
+	ActiveQRCodeID       uint                   `json:"active_qr_code_id"`
+	ShortCode            string                 `json:"short_code"`
+	Scan                 SimulatedScan          `json:"scan"`
+	Strategy             string                 `json:"strategy"`
+	StickyHit            bool                   `json:"sticky_hit"`
+	RedirectMode         string                 `json:"redirect_mode"`
+	ErrorCode            string                 `json:"error_code,omitempty"` // 无法跳转时的错误代码
+	ErrorMessage         string                 `json:"error_message,omitempty"`
+	Fallback             *FallbackAction        `json:"fallback,omitempty"`
+	StaticQRCodes        []StaticQRCodeDecision `json:"static_qrcodes"`
+	ProbabilityEstimated bool                   `json:"probability_estimated"` // 切换规则是否支持估算概率
+}
+
+// SimulateScan 按给定的扫描环境模拟一次扫码，返回每个静态码的筛选结果、切换规则和选中概率
+//
+// 模拟过程只读：不写入扫描记录，不占用扫码名额，不保存粘性分配，也不推进轮询游标。
+func (s *ActiveQRCodeService) SimulateScan(id uint, req *models.SimulateScanRequest) (*SimulationResult, error) {
+	activeQR, err := s.GetActiveQRCode(id)
+	if err != nil {
+		return nil, &QRCodeError{
+			Code:    "NOT_FOUND",
+			Message: "二维码不存在",
+		}
+	}
+
+	query, err := url.ParseQuery(strings.TrimPrefix(req.Query, "?"))
+	if err != nil {
+		return nil, &models.AppError{
+			Code:    "INVALID_QUERY",
+			Message: fmt.Sprintf("查询字符串格式错误: %v", err),
+		}
+	}
+
+	scan := &ScanContext{
+		UserAgent:      req.UserAgent,
+		AcceptLanguage: req.AcceptLanguage,
+		IPAddress:      req.IPAddress,
+		VisitorID:      req.VisitorID,
+		Query:          query,
+		Cookies:        req.Cookies,
+	}
+	if req.Region != nil {
+		scan.Region = geoip.Region{Country: req.Region.Country, Province: req.Region.Province, City: req.Region.City}
+	}
+	now := time.Now()
+	if req.Time != nil {
+		now = *req.Time
+	}
+	s.completeScan(activeQR, scan, now)
+
+	strategy := switchStrategy(activeQR)
+	result := &SimulationResult{
+		ActiveQRCodeID: activeQR.ID,
+		ShortCode:      activeQR.ShortCode,
+		Strategy:       strategy.Info().Name,
+		RedirectMode:   s.redirectMode(activeQR),
+		StaticQRCodes:  make([]StaticQRCodeDecision, len(activeQR.StaticQRCodes)),
+	}
+
+	// 与扫码跳转使用同一筛选流程
+	filtered := filterScanCandidates(activeQR, scan)
+	decisions := make(map[uint]*StaticQRCodeDecision, len(activeQR.StaticQRCodes))
+	for i, qr := range activeQR.StaticQRCodes {
+		decision := &result.StaticQRCodes[i]
+		decision.ID = qr.ID
+		decision.Name = qr.Name
+		decision.Rejection = filtered.rejections[qr.ID]
+		decisions[qr.ID] = decision
+	}
+	candidates := highestPriorityTier(filtered.available)
+
+	result.Scan = SimulatedScan{
+		Device:        scan.Device,
+		OS:            scan.Agent.OS,
+		OSVersion:     scan.Agent.OSVersion,
+		Browser:       scan.Agent.Browser,
+		Client:        scan.Agent.Client,
+		ClientVersion: scan.Agent.ClientVersion,
+		Language:      scan.Language,
+		Country:       scan.Region.Country,
+		Province:      scan.Region.Province,
+		City:          scan.Region.City,
+		Time:          scan.Time,
+		Weekday:       int(scan.Time.Weekday()),
+		Channel:       scanChannel(trackingParams(scan.Query)),
+	}
+
+	if qrErr := filtered.scanError(activeQR); qrErr != nil {
+		result.ErrorCode, result.ErrorMessage = qrErr.Code, qrErr.Message
+		result.Fallback = resolveFallback(activeQR, result.ErrorCode)
+		return result, nil
+	}
+
+	for _, qr := range candidates {
+		decision := decisions[qr.ID]
+		decision.Accepted = true
+		if !qr.IsImage() {
+			decision.TargetURL = expandTargetURL(qr.TargetURL, s.placeholderValues(activeQR, &qr, scan, 0))
+			decision.TargetURL = buildTargetURL(decision.TargetURL, activeQR.PassQuery, scan.Query, qr.UTMParams)
+		}
+		decision.OpenInBrowser = needsOpenInBrowser(&qr, scan.Agent.Client, decision.TargetURL)
+	}
+
+	// 粘性分配命中时必定跳转到上次的静态码
+	if activeQR.Sticky {
+		if assigned := s.findStickyAssignment(activeQR, scan, candidates); assigned != nil {
+			result.StickyHit = true
+			result.ProbabilityEstimated = true
+			decisions[assigned.ID].Probability = 1
+			return result, nil
+		}
+	}
+
+	estimator, ok := strategy.(StrategyEstimator)
+	if !ok {
+		return result, nil
+	}
+	probabilities, err := estimator.Probabilities(&StrategyContext{DB: s.db, ActiveQR: activeQR, Scan: scan}, candidates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate probabilities: %v", err)
+	}
+	for i, qr := range candidates {
+		decisions[qr.ID].Probability = probabilities[i]
+	}
+	result.ProbabilityEstimated = true
+
+	return result, nil
+}
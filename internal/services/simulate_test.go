@@ -0,0 +1,124 @@
+package services
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"wechat-active-qrcode/internal/models"
+)
+
+const (
+	wechatUA        = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 MicroMessenger/8.0.40(0x18002831) NetType/WIFI Language/zh_CN"
+	wechatDesktopUA = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/81.0.4044.138 Safari/537.36 NetType/WIFI MicroMessenger/7.0.20.1781(0x6700143B) WindowsWechat(0x6307001e)"
+)
+
+func TestSimulateMatchesResolveScan(t *testing.T) {
+	s, db := newTestService(t)
+	expired := time.Now().Add(-time.Hour)
+	activeQR := createTestActiveQR(t, db, &models.ActiveQRCode{Name: "sim", SwitchRule: "random"},
+		models.StaticQRCode{Name: "disabled", TargetURL: "https://disabled.example.com"},
+		models.StaticQRCode{Name: "expired", TargetURL: "https://expired.example.com", ExpiresAt: &expired},
+		models.StaticQRCode{Name: "en", TargetURL: "https://en.example.com", AllowedLanguages: `["en"]`},
+		models.StaticQRCode{Name: "zh-a", TargetURL: "https://zh-a.example.com", AllowedLanguages: `["zh-CN"]`, Priority: 1},
+		models.StaticQRCode{Name: "zh-b", TargetURL: "https://zh-b.example.com", AllowedLanguages: `["zh-CN"]`, Priority: 1},
+		models.StaticQRCode{Name: "zh-backup", TargetURL: "https://zh-backup.example.com", AllowedLanguages: `["zh-CN"]`},
+		models.StaticQRCode{Name: "wechat", TargetURL: "https://wechat.example.com", PreferredClients: `["wechat"]`},
+		models.StaticQRCode{Name: "desktop", TargetURL: "https://desktop.example.com", AllowedDevices: `["desktop"]`},
+	)
+	if err := db.Model(&models.StaticQRCode{}).Where("name = ?", "disabled").Update("status", 0).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		userAgent      string
+		acceptLanguage string
+		want           []string // 期望的候选静态码，为空表示无法跳转
+		errorCode      string
+	}{
+		{"language and priority", "Mozilla/5.0 (iPhone)", "zh-CN,zh;q=0.9", []string{"zh-a", "zh-b"}, ""},
+		{"other language", "Mozilla/5.0 (iPhone)", "en-US", []string{"en"}, ""},
+		{"unlisted language uses unrestricted codes", "Mozilla/5.0 (iPhone)", "fr", []string{"wechat"}, ""},
+		{"wechat on phone", wechatUA, "fr", []string{"wechat"}, ""},
+		{"preferred client", wechatDesktopUA, "fr", []string{"wechat"}, ""},
+		{"desktop", "Mozilla/5.0 (Windows NT 10.0)", "fr", []string{"wechat", "desktop"}, ""},
+		{"no accept-language", "Mozilla/5.0 (iPhone)", "", []string{"wechat"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			simulated, err := s.SimulateScan(activeQR.ID, &models.SimulateScanRequest{UserAgent: tt.userAgent, AcceptLanguage: tt.acceptLanguage, IPAddress: "10.0.0.1"})
+			if err != nil {
+				t.Fatalf("SimulateScan: %v", err)
+			}
+			if simulated.ErrorCode != tt.errorCode {
+				t.Fatalf("simulated error code = %q, want %q", simulated.ErrorCode, tt.errorCode)
+			}
+
+			var accepted []string
+			for _, decision := range simulated.StaticQRCodes {
+				if decision.Accepted {
+					accepted = append(accepted, decision.Name)
+				} else if decision.Rejection == nil {
+					t.Errorf("static code %s is neither accepted nor rejected", decision.Name)
+				}
+			}
+			sort.Strings(accepted)
+			want := append([]string(nil), tt.want...)
+			sort.Strings(want)
+			if !equalStrings(accepted, want) {
+				t.Errorf("simulated candidates = %v, want %v", accepted, want)
+			}
+
+			// 多次真实扫码命中的静态码集合应与模拟的候选集合一致
+			resolved := make(map[string]bool)
+			for i := 0; i < 40; i++ {
+				result, err := s.ResolveScan(activeQR.ShortCode, &ScanContext{UserAgent: tt.userAgent, AcceptLanguage: tt.acceptLanguage, IPAddress: "10.0.0.1"})
+				if err != nil {
+					t.Fatalf("ResolveScan: %v", err)
+				}
+				resolved[result.StaticQR.Name] = true
+			}
+			var got []string
+			for name := range resolved {
+				got = append(got, name)
+			}
+			sort.Strings(got)
+			if !equalStrings(got, accepted) {
+				t.Errorf("resolved static codes = %v, simulated candidates = %v", got, accepted)
+			}
+		})
+	}
+}
+
+func TestSimulateMatchesResolveScanErrors(t *testing.T) {
+	s, db := newTestService(t)
+	activeQR := createTestActiveQR(t, db, &models.ActiveQRCode{Name: "err", SwitchRule: "weight"},
+		models.StaticQRCode{Name: "zh", TargetURL: "https://zh.example.com", AllowedLanguages: `["zh-CN"]`},
+		models.StaticQRCode{Name: "desktop", TargetURL: "https://desktop.example.com", AllowedDevices: `["desktop"]`},
+	)
+
+	check := func(wantCode string) {
+		t.Helper()
+		simulated, err := s.SimulateScan(activeQR.ID, &models.SimulateScanRequest{UserAgent: "Mozilla/5.0 (iPhone)", AcceptLanguage: "fr"})
+		if err != nil {
+			t.Fatalf("SimulateScan: %v", err)
+		}
+		_, err = s.ResolveScan(activeQR.ShortCode, &ScanContext{UserAgent: "Mozilla/5.0 (iPhone)", AcceptLanguage: "fr"})
+		qrErr, ok := err.(*QRCodeError)
+		if !ok {
+			t.Fatalf("ResolveScan error = %v, want *QRCodeError", err)
+		}
+		if simulated.ErrorCode != wantCode || qrErr.Code != wantCode {
+			t.Errorf("simulated %q, resolved %q, want %q", simulated.ErrorCode, qrErr.Code, wantCode)
+		}
+	}
+
+	check("NO_MATCHING_QR")
+
+	db.Model(&models.StaticQRCode{}).Where("active_qr_code_id = ?", activeQR.ID).Update("status", 0)
+	check("NO_STATIC_QR")
+
+	db.Model(&models.ActiveQRCode{}).Where("id = ?", activeQR.ID).Update("status", 0)
+	check("DISABLED")
+}
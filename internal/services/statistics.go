@@ -1,6 +1,8 @@
 package services
 
 import (
+	"database/sql"
+	"strings"
 	"time"
 	"wechat-active-qrcode/internal/models"
 
@@ -17,24 +19,31 @@ func NewStatisticsService(db *gorm.DB) *StatisticsService {
 	}
 }
 
+// successfulScans 只统计跳转成功的扫描记录，跳转失败的记录（error_code 非空）单独由 GetFailedScanStats 统计
+//
+// 新增 error_code 列之前的记录该列为NULL，视为成功。
+func successfulScans(db *gorm.DB) *gorm.DB {
+	return db.Where("COALESCE(error_code, '') = ''")
+}
+
 // GetScanStatistics 获取二维码扫描统计
 func (s *StatisticsService) GetScanStatistics(qrCodeID uint) (*models.ScanStats, error) {
 	var stats models.ScanStats
 
 	// 获取总扫描次数
-	s.db.Model(&models.ScanRecord{}).Where("qr_code_id = ?", qrCodeID).Count(&stats.TotalScans)
+	s.db.Model(&models.ScanRecord{}).Scopes(successfulScans).Where("qr_code_id = ?", qrCodeID).Count(&stats.TotalScans)
 
 	// 获取今日扫描次数
 	today := time.Now().Format("2006-01-02")
-	s.db.Model(&models.ScanRecord{}).Where("qr_code_id = ? AND DATE(scan_time) = ?", qrCodeID, today).Count(&stats.TodayScans)
+	s.db.Model(&models.ScanRecord{}).Scopes(successfulScans).Where("qr_code_id = ? AND DATE(scan_time) = ?", qrCodeID, today).Count(&stats.TodayScans)
 
 	// 获取本周扫描次数
 	weekStart := time.Now().AddDate(0, 0, -int(time.Now().Weekday()))
-	s.db.Model(&models.ScanRecord{}).Where("qr_code_id = ? AND scan_time >= ?", qrCodeID, weekStart).Count(&stats.WeekScans)
+	s.db.Model(&models.ScanRecord{}).Scopes(successfulScans).Where("qr_code_id = ? AND scan_time >= ?", qrCodeID, weekStart).Count(&stats.WeekScans)
 
 	// 获取本月扫描次数
 	monthStart := time.Now().AddDate(0, 0, -time.Now().Day()+1)
-	s.db.Model(&models.ScanRecord{}).Where("qr_code_id = ? AND scan_time >= ?", qrCodeID, monthStart).Count(&stats.MonthScans)
+	s.db.Model(&models.ScanRecord{}).Scopes(successfulScans).Where("qr_code_id = ? AND scan_time >= ?", qrCodeID, monthStart).Count(&stats.MonthScans)
 
 	return &stats, nil
 }
@@ -47,9 +56,13 @@ func (s *StatisticsService) GetOverviewStats() (map[string]interface{}, error) {
 	var totalQRCodes int64
 	s.db.Model(&models.QRCode{}).Count(&totalQRCodes)
 
-	// 总扫描次数
+	// 总扫描次数（不含跳转失败）
 	var totalScans int64
-	s.db.Model(&models.ScanRecord{}).Count(&totalScans)
+	s.db.Model(&models.ScanRecord{}).Scopes(successfulScans).Count(&totalScans)
+
+	// 跳转失败的扫描次数
+	var failedScans int64
+	s.db.Model(&models.ScanRecord{}).Where("error_code <> ''").Count(&failedScans)
 
 	// 今日新增二维码
 	var todayNewQRCodes int64
@@ -58,7 +71,7 @@ func (s *StatisticsService) GetOverviewStats() (map[string]interface{}, error) {
 
 	// 今日扫描次数
 	var todayScans int64
-	s.db.Model(&models.ScanRecord{}).Where("DATE(scan_time) = ?", today).Count(&todayScans)
+	s.db.Model(&models.ScanRecord{}).Scopes(successfulScans).Where("DATE(scan_time) = ?", today).Count(&todayScans)
 
 	// 活跃二维码数量（有扫描记录的）
 	var activeQRCodes int64
@@ -67,6 +80,7 @@ func (s *StatisticsService) GetOverviewStats() (map[string]interface{}, error) {
 	result = map[string]interface{}{
 		"total_qr_codes":     totalQRCodes,
 		"total_scans":        totalScans,
+		"failed_scans":       failedScans,
 		"today_new_qr_codes": todayNewQRCodes,
 		"today_scans":        todayScans,
 		"active_qr_codes":    activeQRCodes,
@@ -85,7 +99,7 @@ func (s *StatisticsService) GetTrendData(days int) ([]map[string]interface{}, er
 
 		// 当日扫描次数
 		var dailyScans int64
-		s.db.Model(&models.ScanRecord{}).Where("DATE(scan_time) = ?", dateStr).Count(&dailyScans)
+		s.db.Model(&models.ScanRecord{}).Scopes(successfulScans).Where("DATE(scan_time) = ?", dateStr).Count(&dailyScans)
 
 		// 当日新增二维码
 		var dailyNewQRCodes int64
@@ -189,6 +203,7 @@ func (s *StatisticsService) GetDeviceStats() (map[string]int64, error) {
 	}
 
 	err := s.db.Model(&models.ScanRecord{}).
+		Scopes(successfulScans).
 		Select("device, COUNT(*) as count").
 		Group("device").
 		Find(&results).Error
@@ -217,6 +232,7 @@ func (s *StatisticsService) GetRegionStats() (map[string]int64, error) {
 	}
 
 	err := s.db.Model(&models.ScanRecord{}).
+		Scopes(successfulScans).
 		Select("region, COUNT(*) as count").
 		Group("region").
 		Find(&results).Error
@@ -236,3 +252,126 @@ func (s *StatisticsService) GetRegionStats() (map[string]int64, error) {
 
 	return regionStats, nil
 }
+
+// GetFailedScanStats 获取跳转失败的扫描统计（按错误代码），activeQRCodeID 为0时统计全部活码
+func (s *StatisticsService) GetFailedScanStats(activeQRCodeID uint) (map[string]int64, error) {
+	var results []struct {
+		ErrorCode string
+		Count     int64
+	}
+
+	query := s.db.Model(&models.ScanRecord{}).
+		Select("error_code, COUNT(*) as count").
+		Where("error_code <> ''")
+	if activeQRCodeID > 0 {
+		query = query.Where("active_qr_code_id = ?", activeQRCodeID)
+	}
+
+	if err := query.Group("error_code").Find(&results).Error; err != nil {
+		return nil, err
+	}
+
+	failedStats := make(map[string]int64)
+	for _, result := range results {
+		failedStats[result.ErrorCode] = result.Count
+	}
+
+	return failedStats, nil
+}
+
+// GetChannelStats 获取投放渠道统计（扫码链接的src/utm_source参数），activeQRCodeID 为0时统计全部活码
+func (s *StatisticsService) GetChannelStats(activeQRCodeID uint) (map[string]int64, error) {
+	var results []struct {
+		Channel string
+		Count   int64
+	}
+
+	query := s.db.Model(&models.ScanRecord{}).
+		Scopes(successfulScans).
+		Select("channel, COUNT(*) as count").
+		Where("active_qr_code_id IS NOT NULL")
+	if activeQRCodeID > 0 {
+		query = query.Where("active_qr_code_id = ?", activeQRCodeID)
+	}
+
+	if err := query.Group("channel").Find(&results).Error; err != nil {
+		return nil, err
+	}
+
+	channelStats := make(map[string]int64)
+	for _, result := range results {
+		channel := result.Channel
+		if channel == "" {
+			channel = "direct"
+		}
+		channelStats[channel] = result.Count
+	}
+
+	return channelStats, nil
+}
+
+// GetOSStats 获取操作系统统计
+func (s *StatisticsService) GetOSStats() (map[string]int64, error) {
+	return s.groupScanCount("os")
+}
+
+// GetOSVersionStats 获取操作系统版本统计，键为"系统 版本"，如"iOS 17.1"
+func (s *StatisticsService) GetOSVersionStats() (map[string]int64, error) {
+	return s.groupScanCount("os", "os_version")
+}
+
+// GetBrowserStats 获取浏览器统计
+func (s *StatisticsService) GetBrowserStats() (map[string]int64, error) {
+	return s.groupScanCount("browser")
+}
+
+// GetClientStats 获取App内置浏览器统计（微信、企业微信、支付宝、QQ、抖音等）
+func (s *StatisticsService) GetClientStats() (map[string]int64, error) {
+	return s.groupScanCount("client")
+}
+
+// GetClientVersionStats 获取App版本统计，键为"客户端 版本"，如"wechat 8.0.40"
+func (s *StatisticsService) GetClientVersionStats() (map[string]int64, error) {
+	return s.groupScanCount("client", "client_version")
+}
+
+// groupScanCount 按扫描记录的一个或多个字段分组计数，多个字段的值以空格连接，均为空时记为unknown
+func (s *StatisticsService) groupScanCount(columns ...string) (map[string]int64, error) {
+	rows, err := s.db.Model(&models.ScanRecord{}).
+		Scopes(successfulScans).
+		Select(strings.Join(columns, ", ") + ", COUNT(*) as count").
+		Group(strings.Join(columns, ", ")).
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := make(map[string]int64)
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		dest := make([]interface{}, 0, len(columns)+1)
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		var count int64
+		dest = append(dest, &count)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		var parts []string
+		for _, value := range values {
+			if value.String != "" {
+				parts = append(parts, value.String)
+			}
+		}
+		key := strings.Join(parts, " ")
+		if key == "" {
+			key = "unknown"
+		}
+		stats[key] += count
+	}
+
+	return stats, rows.Err()
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"wechat-active-qrcode/internal/models"
+)
+
+func TestStatisticsExcludeFailedScans(t *testing.T) {
+	_, db := newTestService(t)
+	stats := NewStatisticsService(db)
+
+	activeID := uint(1)
+	now := time.Now()
+	records := []models.ScanRecord{
+		{ActiveQRCodeID: &activeID, ScanTime: now, Device: "mobile", OS: "iOS", Channel: "poster"},
+		{ActiveQRCodeID: &activeID, ScanTime: now, Device: "mobile", OS: "iOS", Channel: "poster", ErrorCode: "NO_MATCHING_QR"},
+		{ActiveQRCodeID: &activeID, ScanTime: now, Device: "desktop", OS: "Windows", ErrorCode: "DISABLED"},
+	}
+	if err := db.Create(&records).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 新增 error_code 列之前的记录该列为NULL
+	if err := db.Exec("INSERT INTO scan_records (active_qr_code_id, scan_time, device, os) VALUES (?, ?, ?, ?)", activeID, now, "mobile", "Android").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	overview, err := stats.GetOverviewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if overview["total_scans"] != int64(2) || overview["today_scans"] != int64(2) {
+		t.Errorf("total_scans = %v, today_scans = %v, want 2", overview["total_scans"], overview["today_scans"])
+	}
+	if overview["failed_scans"] != int64(2) {
+		t.Errorf("failed_scans = %v, want 2", overview["failed_scans"])
+	}
+
+	trend, err := stats.GetTrendData(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trend[0]["scans"] != int64(2) {
+		t.Errorf("trend scans = %v, want 2", trend[0]["scans"])
+	}
+
+	devices, err := stats.GetDeviceStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if devices["mobile"] != 2 || devices["desktop"] != 0 {
+		t.Errorf("device stats = %v", devices)
+	}
+
+	osStats, err := stats.GetOSStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if osStats["iOS"] != 1 || osStats["Windows"] != 0 {
+		t.Errorf("os stats = %v", osStats)
+	}
+
+	channels, err := stats.GetChannelStats(activeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if channels["poster"] != 1 {
+		t.Errorf("channel stats = %v", channels)
+	}
+
+	failed, err := stats.GetFailedScanStats(activeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed["NO_MATCHING_QR"] != 1 || failed["DISABLED"] != 1 {
+		t.Errorf("failed scan stats = %v", failed)
+	}
+}
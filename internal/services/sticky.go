@@ -0,0 +1,92 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"time"
+	"wechat-active-qrcode/internal/models"
+	"wechat-active-qrcode/pkg/utils"
+
+	"gorm.io/gorm/clause"
+)
+
+// defaultStickyDays 未配置有效天数时粘性分配的保留天数
+const defaultStickyDays = 30
+
+// visitorIDLength 访客ID长度
+const visitorIDLength = 32
+
+// newVisitorID 生成新的访客ID
+func newVisitorID() string {
+	return utils.GenerateRandomString(visitorIDLength)
+}
+
+// visitorIDFor 返回需要写入Cookie的访客ID，只有粘性活码才需要
+func visitorIDFor(activeQR *models.ActiveQRCode, scan *ScanContext) string {
+	if !activeQR.Sticky {
+		return ""
+	}
+	return scan.VisitorID
+}
+
+// cookieVisitorKey Cookie中访客ID对应的访客标识
+func cookieVisitorKey(visitorID string) string {
+	return "cookie:" + visitorID
+}
+
+// fingerprintVisitorKey IP+User-Agent指纹对应的访客标识
+func fingerprintVisitorKey(scan *ScanContext) string {
+	sum := sha256.Sum256([]byte(scan.IPAddress + "|" + scan.UserAgent))
+	return "fp:" + hex.EncodeToString(sum[:16])
+}
+
+// findStickyAssignment 查找访客上次分配的静态码，仅当其仍在可用列表中时返回
+//
+// 客户端带有访客ID Cookie时只按Cookie查找，避免同一出口IP下使用相同机型的其他访客被当作同一人；
+// 没有Cookie（首次访问或客户端不保存Cookie）时才按IP+User-Agent指纹查找。
+func (s *ActiveQRCodeService) findStickyAssignment(activeQR *models.ActiveQRCode, scan *ScanContext, availableQRs []models.StaticQRCode) *models.StaticQRCode {
+	key := fingerprintVisitorKey(scan)
+	if scan.VisitorID != "" {
+		key = cookieVisitorKey(scan.VisitorID)
+	}
+
+	var assignment models.StickyAssignment
+	err := s.db.Where("active_qr_code_id = ? AND visitor_key = ? AND expires_at > ?", activeQR.ID, key, time.Now()).
+		First(&assignment).Error
+	if err != nil {
+		return nil
+	}
+	for i := range availableQRs {
+		if availableQRs[i].ID == assignment.StaticQRCodeID {
+			return &availableQRs[i]
+		}
+	}
+	// 上次分配的静态码已不可用，重新分配
+	return nil
+}
+
+// saveStickyAssignment 保存（或续期）访客的粘性分配，同时记录Cookie和指纹两个访客标识
+func (s *ActiveQRCodeService) saveStickyAssignment(activeQR *models.ActiveQRCode, scan *ScanContext, staticQRCodeID uint) {
+	days := activeQR.StickyDays
+	if days <= 0 {
+		days = defaultStickyDays
+	}
+	expiresAt := time.Now().AddDate(0, 0, days)
+
+	for _, key := range []string{cookieVisitorKey(scan.VisitorID), fingerprintVisitorKey(scan)} {
+		assignment := &models.StickyAssignment{
+			ActiveQRCodeID: activeQR.ID,
+			VisitorKey:     key,
+			StaticQRCodeID: staticQRCodeID,
+			ExpiresAt:      expiresAt,
+		}
+		err := s.db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "active_qr_code_id"}, {Name: "visitor_key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"static_qr_code_id", "expires_at", "updated_at"}),
+		}).Create(assignment).Error
+		if err != nil {
+			log.Printf("Save sticky assignment for active QR %d failed: %v", activeQR.ID, err)
+		}
+	}
+}
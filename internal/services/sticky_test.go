@@ -0,0 +1,80 @@
+package services
+
+import (
+	"testing"
+
+	"wechat-active-qrcode/internal/models"
+)
+
+func TestVisitorIDOnlyForStickyCodes(t *testing.T) {
+	s, db := newTestService(t)
+	plain := createTestActiveQR(t, db, &models.ActiveQRCode{Name: "plain", SwitchRule: "random"},
+		models.StaticQRCode{Name: "a", TargetURL: "https://a.example.com"},
+	)
+	sticky := createTestActiveQR(t, db, &models.ActiveQRCode{Name: "sticky", SwitchRule: "random", Sticky: true},
+		models.StaticQRCode{Name: "a", TargetURL: "https://a.example.com"},
+		models.StaticQRCode{Name: "b", TargetURL: "https://b.example.com"},
+	)
+
+	result, err := s.ResolveScan(plain.ShortCode, &ScanContext{UserAgent: "Mozilla/5.0", IPAddress: "10.0.0.1"})
+	if err != nil {
+		t.Fatalf("ResolveScan: %v", err)
+	}
+	if result.VisitorID != "" {
+		t.Errorf("non-sticky code should not assign a visitor id, got %q", result.VisitorID)
+	}
+
+	first, err := s.ResolveScan(sticky.ShortCode, &ScanContext{UserAgent: "Mozilla/5.0", IPAddress: "10.0.0.2"})
+	if err != nil {
+		t.Fatalf("ResolveScan: %v", err)
+	}
+	if first.VisitorID == "" {
+		t.Fatal("sticky code should assign a visitor id")
+	}
+
+	// 换了网络的同一访客凭Cookie回到同一目标
+	for i := 0; i < 5; i++ {
+		again, err := s.ResolveScan(sticky.ShortCode, &ScanContext{UserAgent: "Other", IPAddress: "10.0.0.3", VisitorID: first.VisitorID})
+		if err != nil {
+			t.Fatalf("ResolveScan: %v", err)
+		}
+		if again.StaticQR.ID != first.StaticQR.ID {
+			t.Fatalf("sticky visitor moved from %d to %d", first.StaticQR.ID, again.StaticQR.ID)
+		}
+		if again.VisitorID != first.VisitorID {
+			t.Errorf("existing visitor id should be kept, got %q", again.VisitorID)
+		}
+	}
+}
+
+func TestStickyFingerprintOnlyWithoutCookie(t *testing.T) {
+	s, db := newTestService(t)
+	// 按权重只会选中 a，命中粘性分配时才会跳转到 b
+	activeQR := createTestActiveQR(t, db, &models.ActiveQRCode{Name: "fp", SwitchRule: "weight", Sticky: true},
+		models.StaticQRCode{Name: "a", TargetURL: "https://a.example.com", Weight: 1},
+		models.StaticQRCode{Name: "b", TargetURL: "https://b.example.com", Weight: 0},
+	)
+	a, b := activeQR.StaticQRCodes[0], activeQR.StaticQRCodes[1]
+	if err := db.Model(&models.StaticQRCode{}).Where("id = ?", b.ID).Update("weight", 0).Error; err != nil {
+		t.Fatalf("set weight: %v", err)
+	}
+
+	scan := func(visitorID string) *ScanResult {
+		t.Helper()
+		result, err := s.ResolveScan(activeQR.ShortCode, &ScanContext{UserAgent: "Mozilla/5.0", IPAddress: "10.0.0.9", VisitorID: visitorID})
+		if err != nil {
+			t.Fatalf("ResolveScan: %v", err)
+		}
+		return result
+	}
+	s.saveStickyAssignment(activeQR, &ScanContext{UserAgent: "Mozilla/5.0", IPAddress: "10.0.0.9", VisitorID: "someone-else"}, b.ID)
+
+	// 没有Cookie时按指纹命中
+	if got := scan(""); got.StaticQR.ID != b.ID {
+		t.Errorf("visitor without cookie went to %d, want fingerprint assignment %d", got.StaticQR.ID, b.ID)
+	}
+	// 带有其他Cookie的访客不使用同一指纹的分配
+	if got := scan("another-visitor"); got.StaticQR.ID != a.ID {
+		t.Errorf("visitor with cookie went to %d, want %d", got.StaticQR.ID, a.ID)
+	}
+}
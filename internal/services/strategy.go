@@ -0,0 +1,501 @@
+package services
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log"
+	"math/big"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+	"wechat-active-qrcode/internal/models"
+	"wechat-active-qrcode/pkg/geoip"
+	"wechat-active-qrcode/pkg/useragent"
+
+	"gorm.io/gorm"
+)
+
+// DefaultSwitchRule 未知切换规则时使用的默认规则
+const DefaultSwitchRule = "weight"
+
+// ScanContext 扫描环境信息
+//
+// UserAgent、AcceptLanguage、IPAddress、VisitorID、Query、Cookies 由处理器从HTTP请求中提取，其余字段由服务层补全。
+type ScanContext struct {
+	UserAgent      string
+	AcceptLanguage string // Accept-Language请求头
+	IPAddress      string
+	VisitorID      string            // 首方Cookie中的访客ID，用于粘性分配
+	Query          url.Values        // 扫码链接的查询参数
+	Cookies        map[string]string // 请求携带的Cookie，用于定向条件
+	Device         string
+	Agent          useragent.Info // User-Agent解析出的系统、浏览器及App内置浏览器
+	Language       string         // 协商出的语言，静态码未限制语言时为请求头中的首选语言
+	Region         geoip.Region
+	Time           time.Time // 扫码时间，已转换到活码时区，用于时段、定向条件和每日名额的判断
+}
+
+// recordTime 返回保存扫描记录使用的时间
+//
+// 统计按服务器时区的日期查询扫描记录，因此记录统一使用服务器时区，不随活码时区变化。
+func (scan *ScanContext) recordTime() time.Time {
+	if scan.Time.IsZero() {
+		return time.Now()
+	}
+	return scan.Time.In(time.Local)
+}
+
+// StrategyContext 切换规则选择目标时可用的上下文
+type StrategyContext struct {
+	DB       *gorm.DB
+	ActiveQR *models.ActiveQRCode
+	Scan     *ScanContext
+}
+
+// StrategyParam 切换规则依赖的可配置参数
+type StrategyParam struct {
+	Name        string `json:"name"`  // 字段名，与JSON字段一致
+	Scope       string `json:"scope"` // active: 活码字段, static: 静态码字段
+	Type        string `json:"type"`  // int, string, json 等
+	Description string `json:"description"`
+}
+
+// StrategyInfo 切换规则描述信息
+type StrategyInfo struct {
+	Name        string          `json:"name"`
+	Label       string          `json:"label"`
+	Description string          `json:"description"`
+	Params      []StrategyParam `json:"params"`
+}
+
+// Strategy 切换规则：从已筛选的可用静态码中选出本次跳转的目标
+type Strategy interface {
+	Info() StrategyInfo
+	Select(ctx *StrategyContext, candidates []models.StaticQRCode) (*models.StaticQRCode, error)
+}
+
+// StrategyEstimator 可选接口：在不产生副作用的情况下估算各候选静态码被选中的概率，用于模拟扫码
+//
+// 返回值与 candidates 一一对应，之和为1；确定性的规则返回选中项为1、其余为0。
+type StrategyEstimator interface {
+	Probabilities(ctx *StrategyContext, candidates []models.StaticQRCode) ([]float64, error)
+}
+
+var (
+	strategyMu sync.RWMutex
+	strategies = make(map[string]Strategy)
+)
+
+// RegisterStrategy 注册切换规则，名称重复时panic，应在init中调用
+func RegisterStrategy(strategy Strategy) {
+	name := strategy.Info().Name
+
+	strategyMu.Lock()
+	defer strategyMu.Unlock()
+
+	if _, exists := strategies[name]; exists {
+		panic(fmt.Sprintf("switch strategy already registered: %s", name))
+	}
+	strategies[name] = strategy
+}
+
+// GetStrategy 根据名称获取切换规则
+func GetStrategy(name string) (Strategy, bool) {
+	strategyMu.RLock()
+	defer strategyMu.RUnlock()
+
+	strategy, ok := strategies[name]
+	return strategy, ok
+}
+
+// ListStrategies 获取所有已注册的切换规则（按名称排序）
+func ListStrategies() []StrategyInfo {
+	strategyMu.RLock()
+	defer strategyMu.RUnlock()
+
+	infos := make([]StrategyInfo, 0, len(strategies))
+	for _, strategy := range strategies {
+		infos = append(infos, strategy.Info())
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// ValidateSwitchRule 校验切换规则名称，为空时使用模型默认值
+func ValidateSwitchRule(rule string) error {
+	if rule == "" {
+		return nil
+	}
+	if _, ok := GetStrategy(rule); !ok {
+		return &models.AppError{
+			Code:    "INVALID_SWITCH_RULE",
+			Message: fmt.Sprintf("不支持的切换规则: %s", rule),
+		}
+	}
+	return nil
+}
+
+func init() {
+	RegisterStrategy(randomStrategy{})
+	RegisterStrategy(weightStrategy{})
+	RegisterStrategy(timeStrategy{})
+	RegisterStrategy(geoStrategy{})
+	RegisterStrategy(roundRobinStrategy{})
+	RegisterStrategy(capacityStrategy{})
+}
+
+// randomStrategy 随机选择
+type randomStrategy struct{}
+
+func (randomStrategy) Info() StrategyInfo {
+	return StrategyInfo{
+		Name:        "random",
+		Label:       "随机分配",
+		Description: "在可用的静态码中等概率随机选择",
+		Params:      []StrategyParam{},
+	}
+}
+
+func (randomStrategy) Select(ctx *StrategyContext, candidates []models.StaticQRCode) (*models.StaticQRCode, error) {
+	return selectRandomQR(candidates), nil
+}
+
+func (randomStrategy) Probabilities(ctx *StrategyContext, candidates []models.StaticQRCode) ([]float64, error) {
+	return uniformProbabilities(len(candidates)), nil
+}
+
+// weightStrategy 按权重选择
+type weightStrategy struct{}
+
+func (weightStrategy) Info() StrategyInfo {
+	return StrategyInfo{
+		Name:        "weight",
+		Label:       "权重分配",
+		Description: "按静态码权重比例分配流量",
+		Params: []StrategyParam{
+			{Name: "weight", Scope: "static", Type: "int", Description: "权重，数值越大分配到的流量越多"},
+		},
+	}
+}
+
+func (weightStrategy) Select(ctx *StrategyContext, candidates []models.StaticQRCode) (*models.StaticQRCode, error) {
+	return selectWeightedQR(candidates), nil
+}
+
+func (weightStrategy) Probabilities(ctx *StrategyContext, candidates []models.StaticQRCode) ([]float64, error) {
+	return weightedProbabilities(candidates), nil
+}
+
+// timeStrategy 按小时轮换
+type timeStrategy struct{}
+
+func (timeStrategy) Info() StrategyInfo {
+	return StrategyInfo{
+		Name:        "time",
+		Label:       "时间分配",
+		Description: "按当前小时在可用的静态码之间轮换，同一小时内跳转到同一目标；如需逐次轮询请使用round_robin",
+		Params:      []StrategyParam{},
+	}
+}
+
+func (timeStrategy) Select(ctx *StrategyContext, candidates []models.StaticQRCode) (*models.StaticQRCode, error) {
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	index := ctx.Scan.Time.Hour() % len(candidates)
+	return &candidates[index], nil
+}
+
+func (timeStrategy) Probabilities(ctx *StrategyContext, candidates []models.StaticQRCode) ([]float64, error) {
+	probabilities := make([]float64, len(candidates))
+	if len(candidates) > 0 {
+		probabilities[ctx.Scan.Time.Hour()%len(candidates)] = 1
+	}
+	return probabilities, nil
+}
+
+// geoStrategy 按地区选择：优先匹配最精确的静态码（城市 > 省份 > 国家 > 不限地区），同级按权重
+type geoStrategy struct{}
+
+func (geoStrategy) Info() StrategyInfo {
+	return StrategyInfo{
+		Name:        "geo",
+		Label:       "地区分配",
+		Description: "根据扫码IP所在地区选择匹配最精确的静态码（城市优先于省份，省份优先于国家），同级按权重分配",
+		Params: []StrategyParam{
+			{Name: "allowed_regions", Scope: "static", Type: "json", Description: "允许的地区列表，如[\"广东\",\"深圳\"]"},
+			{Name: "weight", Scope: "static", Type: "int", Description: "同一匹配级别内的权重"},
+		},
+	}
+}
+
+func (geoStrategy) Select(ctx *StrategyContext, candidates []models.StaticQRCode) (*models.StaticQRCode, error) {
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	var matched []models.StaticQRCode
+	for i, best := range bestGeoMatches(ctx.Scan.Region, candidates) {
+		if best {
+			matched = append(matched, candidates[i])
+		}
+	}
+
+	return selectWeightedQR(matched), nil
+}
+
+func (geoStrategy) Probabilities(ctx *StrategyContext, candidates []models.StaticQRCode) ([]float64, error) {
+	best := bestGeoMatches(ctx.Scan.Region, candidates)
+
+	var matched []models.StaticQRCode
+	for i, qr := range candidates {
+		if best[i] {
+			matched = append(matched, qr)
+		}
+	}
+
+	probabilities := make([]float64, len(candidates))
+	matchedProbabilities := weightedProbabilities(matched)
+	j := 0
+	for i := range candidates {
+		if best[i] {
+			probabilities[i] = matchedProbabilities[j]
+			j++
+		}
+	}
+	return probabilities, nil
+}
+
+// bestGeoMatches 标记地区匹配级别最精确的静态码
+func bestGeoMatches(region geoip.Region, candidates []models.StaticQRCode) []bool {
+	bestLevel := geoip.MatchNone
+	levels := make([]int, len(candidates))
+	for i, qr := range candidates {
+		levels[i] = region.BestMatchLevel(parseJSONList(qr.AllowedRegions))
+		if levels[i] > bestLevel {
+			bestLevel = levels[i]
+		}
+	}
+
+	best := make([]bool, len(candidates))
+	for i := range candidates {
+		best[i] = levels[i] == bestLevel
+	}
+	return best
+}
+
+// roundRobinMaxAttempts 轮询游标并发更新冲突时的最大重试次数
+const roundRobinMaxAttempts = 10
+
+// roundRobinStrategy 逐次轮询：按静态码ID顺序依次跳转，游标持久化在数据库中
+type roundRobinStrategy struct{}
+
+func (roundRobinStrategy) Info() StrategyInfo {
+	return StrategyInfo{
+		Name:        "round_robin",
+		Label:       "轮询分配",
+		Description: "按静态码ID顺序逐次轮流跳转，跳过当前不可用的静态码，重启后继续上次的位置",
+		Params:      []StrategyParam{},
+	}
+}
+
+func (roundRobinStrategy) Select(ctx *StrategyContext, candidates []models.StaticQRCode) (*models.StaticQRCode, error) {
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	sorted := sortedByID(candidates)
+
+	activeQRCodeID := ctx.ActiveQR.ID
+	var lastErr error
+	var lastCursor *models.RoundRobinCursor
+	for attempt := 0; attempt < roundRobinMaxAttempts; attempt++ {
+		var cursor models.RoundRobinCursor
+		if err := ctx.DB.Where(models.RoundRobinCursor{ActiveQRCodeID: activeQRCodeID}).
+			FirstOrCreate(&cursor).Error; err != nil {
+			lastErr = err
+			continue
+		}
+		lastCursor = &cursor
+
+		next := nextAfterCursor(sorted, cursor.LastStaticQRCodeID)
+
+		// 比较并交换：只有游标未被其他请求修改时才更新成功
+		result := ctx.DB.Model(&models.RoundRobinCursor{}).
+			Where("active_qr_code_id = ? AND last_static_qr_code_id = ?", activeQRCodeID, cursor.LastStaticQRCodeID).
+			Update("last_static_qr_code_id", next.ID)
+		if result.Error != nil {
+			lastErr = result.Error
+			continue
+		}
+		if result.RowsAffected == 1 {
+			return next, nil
+		}
+	}
+
+	// 游标无法推进时不让扫码失败：按最后读到的游标选择下一个，读取也失败时随机选择
+	if lastErr == nil {
+		lastErr = fmt.Errorf("cursor update conflict")
+	}
+	log.Printf("Round robin cursor of active QR %d not advanced, using fallback selection: %v", activeQRCodeID, lastErr)
+	if lastCursor != nil {
+		return nextAfterCursor(sorted, lastCursor.LastStaticQRCodeID), nil
+	}
+	return selectRandomQR(sorted), nil
+}
+
+// Probabilities 只读取游标，不推进
+func (roundRobinStrategy) Probabilities(ctx *StrategyContext, candidates []models.StaticQRCode) ([]float64, error) {
+	probabilities := make([]float64, len(candidates))
+	if len(candidates) == 0 {
+		return probabilities, nil
+	}
+
+	var cursor models.RoundRobinCursor
+	err := ctx.DB.Where("active_qr_code_id = ?", ctx.ActiveQR.ID).First(&cursor).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	next := nextAfterCursor(sortedByID(candidates), cursor.LastStaticQRCodeID)
+	for i := range candidates {
+		if candidates[i].ID == next.ID {
+			probabilities[i] = 1
+		}
+	}
+	return probabilities, nil
+}
+
+// sortedByID 返回按ID升序排列的副本
+func sortedByID(candidates []models.StaticQRCode) []models.StaticQRCode {
+	sorted := make([]models.StaticQRCode, len(candidates))
+	copy(sorted, candidates)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ID < sorted[j].ID
+	})
+	return sorted
+}
+
+// nextAfterCursor 选择ID大于游标的第一个静态码，没有则从头开始，sorted 不能为空
+func nextAfterCursor(sorted []models.StaticQRCode, cursor uint) *models.StaticQRCode {
+	for i := range sorted {
+		if sorted[i].ID > cursor {
+			return &sorted[i]
+		}
+	}
+	return &sorted[0]
+}
+
+// capacityStrategy 按容量切换：按静态码ID顺序使用，当前静态码扫码次数达到上限后切换到下一个
+//
+// 名额的占用和已满标记由 ActiveQRCodeService.acquireScanSlot 统一完成，已满的静态码在筛选阶段被排除。
+type capacityStrategy struct{}
+
+func (capacityStrategy) Info() StrategyInfo {
+	return StrategyInfo{
+		Name:        "capacity",
+		Label:       "容量切换",
+		Description: "按静态码添加顺序依次使用，扫码次数达到上限（如微信群满200人）后自动切换到下一个",
+		Params: []StrategyParam{
+			{Name: "max_scans", Scope: "static", Type: "int", Description: "累计扫码上限，0表示不限"},
+			{Name: "max_daily_scans", Scope: "static", Type: "int", Description: "每日扫码上限，0表示不限"},
+		},
+	}
+}
+
+func (capacityStrategy) Select(ctx *StrategyContext, candidates []models.StaticQRCode) (*models.StaticQRCode, error) {
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	return &candidates[firstByID(candidates)], nil
+}
+
+func (capacityStrategy) Probabilities(ctx *StrategyContext, candidates []models.StaticQRCode) ([]float64, error) {
+	probabilities := make([]float64, len(candidates))
+	if len(candidates) > 0 {
+		probabilities[firstByID(candidates)] = 1
+	}
+	return probabilities, nil
+}
+
+// firstByID 返回ID最小的静态码下标，candidates 不能为空
+func firstByID(candidates []models.StaticQRCode) int {
+	first := 0
+	for i := range candidates {
+		if candidates[i].ID < candidates[first].ID {
+			first = i
+		}
+	}
+	return first
+}
+
+// selectRandomQR 随机选择
+func selectRandomQR(qrs []models.StaticQRCode) *models.StaticQRCode {
+	if len(qrs) == 0 {
+		return nil
+	}
+	n, _ := rand.Int(rand.Reader, big.NewInt(int64(len(qrs))))
+	return &qrs[n.Int64()]
+}
+
+// selectWeightedQR 按权重选择
+func selectWeightedQR(qrs []models.StaticQRCode) *models.StaticQRCode {
+	if len(qrs) == 0 {
+		return nil
+	}
+
+	// 计算总权重
+	totalWeight := 0
+	for _, qr := range qrs {
+		totalWeight += qr.Weight
+	}
+
+	if totalWeight == 0 {
+		return selectRandomQR(qrs)
+	}
+
+	// 生成随机数
+	randNum, _ := rand.Int(rand.Reader, big.NewInt(int64(totalWeight)))
+	target := int(randNum.Int64())
+
+	// 根据权重选择
+	currentWeight := 0
+	for i, qr := range qrs {
+		currentWeight += qr.Weight
+		if target < currentWeight {
+			return &qrs[i]
+		}
+	}
+
+	return &qrs[0]
+}
+
+// uniformProbabilities 等概率分布
+func uniformProbabilities(n int) []float64 {
+	probabilities := make([]float64, n)
+	for i := range probabilities {
+		probabilities[i] = 1 / float64(n)
+	}
+	return probabilities
+}
+
+// weightedProbabilities 按权重计算选中概率，与 selectWeightedQR 一致：总权重为0时等概率
+func weightedProbabilities(qrs []models.StaticQRCode) []float64 {
+	totalWeight := 0
+	for _, qr := range qrs {
+		totalWeight += qr.Weight
+	}
+	if totalWeight == 0 {
+		return uniformProbabilities(len(qrs))
+	}
+
+	probabilities := make([]float64, len(qrs))
+	for i, qr := range qrs {
+		probabilities[i] = float64(qr.Weight) / float64(totalWeight)
+	}
+	return probabilities
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"wechat-active-qrcode/internal/models"
+)
+
+func TestStrategyRegistry(t *testing.T) {
+	for _, name := range []string{"random", "weight", "time", "geo"} {
+		strategy, ok := GetStrategy(name)
+		if !ok {
+			t.Fatalf("strategy %q not registered", name)
+		}
+		if got := strategy.Info().Name; got != name {
+			t.Errorf("GetStrategy(%q).Info().Name = %q", name, got)
+		}
+	}
+	if _, ok := GetStrategy("unknown"); ok {
+		t.Error("GetStrategy returned an unregistered strategy")
+	}
+
+	infos := ListStrategies()
+	for i := 1; i < len(infos); i++ {
+		if infos[i-1].Name >= infos[i].Name {
+			t.Errorf("ListStrategies not sorted by name: %q before %q", infos[i-1].Name, infos[i].Name)
+		}
+	}
+
+	if err := ValidateSwitchRule(""); err != nil {
+		t.Errorf("empty switch rule rejected: %v", err)
+	}
+	if err := ValidateSwitchRule("unknown"); err == nil {
+		t.Error("unknown switch rule accepted")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a duplicate strategy should panic")
+		}
+	}()
+	RegisterStrategy(randomStrategy{})
+}
+
+func TestRandomStrategySelectsCandidate(t *testing.T) {
+	candidates := []models.StaticQRCode{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}}
+	seen := map[uint]bool{}
+	for i := 0; i < 100; i++ {
+		selected, err := randomStrategy{}.Select(&StrategyContext{}, candidates)
+		if err != nil || selected == nil {
+			t.Fatalf("Select = %v, %v", selected, err)
+		}
+		seen[selected.ID] = true
+	}
+	if len(seen) != 2 {
+		t.Errorf("random strategy selected %v in 100 tries, want both candidates", seen)
+	}
+
+	if selected, _ := (randomStrategy{}).Select(&StrategyContext{}, nil); selected != nil {
+		t.Errorf("Select without candidates = %v, want nil", selected)
+	}
+}
+
+func TestWeightStrategy(t *testing.T) {
+	// 权重为0的静态码不会被选中
+	candidates := []models.StaticQRCode{{ID: 1, Weight: 0}, {ID: 2, Weight: 3}}
+	for i := 0; i < 50; i++ {
+		selected, _ := weightStrategy{}.Select(&StrategyContext{}, candidates)
+		if selected.ID != 2 {
+			t.Fatalf("selected zero-weight candidate %d", selected.ID)
+		}
+	}
+
+	// 权重全为0时随机选择
+	zero := []models.StaticQRCode{{ID: 1}, {ID: 2}}
+	seen := map[uint]bool{}
+	for i := 0; i < 100; i++ {
+		selected, _ := weightStrategy{}.Select(&StrategyContext{}, zero)
+		seen[selected.ID] = true
+	}
+	if len(seen) != 2 {
+		t.Errorf("all-zero weights selected %v in 100 tries, want both candidates", seen)
+	}
+}
+
+func TestTimeStrategyRotatesByHour(t *testing.T) {
+	candidates := []models.StaticQRCode{{ID: 1}, {ID: 2}, {ID: 3}}
+	for hour, want := range map[int]uint{0: 1, 1: 2, 2: 3, 3: 1, 23: 3} {
+		ctx := &StrategyContext{Scan: &ScanContext{Time: time.Date(2024, 1, 1, hour, 30, 0, 0, time.UTC)}}
+		selected, err := timeStrategy{}.Select(ctx, candidates)
+		if err != nil {
+			t.Fatalf("Select: %v", err)
+		}
+		if selected.ID != want {
+			t.Errorf("hour %d selected %d, want %d", hour, selected.ID, want)
+		}
+	}
+}
+
+func TestRoundRobinRotatesByID(t *testing.T) {
+	_, db := newTestService(t)
+	activeQR := createTestActiveQR(t, db, &models.ActiveQRCode{Name: "rr", SwitchRule: "round_robin"},
+		models.StaticQRCode{Name: "a", TargetURL: "https://a.example.com"},
+		models.StaticQRCode{Name: "b", TargetURL: "https://b.example.com"},
+		models.StaticQRCode{Name: "c", TargetURL: "https://c.example.com"},
+	)
+	candidates := activeQR.StaticQRCodes
+	ctx := &StrategyContext{DB: db, ActiveQR: activeQR, Scan: &ScanContext{Time: time.Now()}}
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		selected, err := roundRobinStrategy{}.Select(ctx, candidates)
+		if err != nil {
+			t.Fatalf("Select: %v", err)
+		}
+		got = append(got, selected.Name)
+	}
+	if want := []string{"a", "b", "c", "a"}; !equalStrings(got, want) {
+		t.Errorf("round robin order = %v, want %v", got, want)
+	}
+
+	// 排除的静态码被跳过，游标位置保持不变
+	selected, _ := roundRobinStrategy{}.Select(ctx, []models.StaticQRCode{candidates[0], candidates[2]})
+	if selected.Name != "c" {
+		t.Errorf("after skipping b got %s, want c", selected.Name)
+	}
+}
+
+func TestRoundRobinFallsBackWhenCursorUnavailable(t *testing.T) {
+	_, db := newTestService(t)
+	activeQR := createTestActiveQR(t, db, &models.ActiveQRCode{Name: "rr", SwitchRule: "round_robin"},
+		models.StaticQRCode{Name: "a", TargetURL: "https://a.example.com"},
+		models.StaticQRCode{Name: "b", TargetURL: "https://b.example.com"},
+	)
+	if err := db.Migrator().DropTable(&models.RoundRobinCursor{}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := &StrategyContext{DB: db, ActiveQR: activeQR, Scan: &ScanContext{Time: time.Now()}}
+	selected, err := roundRobinStrategy{}.Select(ctx, activeQR.StaticQRCodes)
+	if err != nil {
+		t.Fatalf("Select should not fail the scan: %v", err)
+	}
+	if selected == nil {
+		t.Fatal("Select should fall back to a candidate")
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
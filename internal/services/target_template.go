@@ -0,0 +1,146 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"wechat-active-qrcode/internal/models"
+	"wechat-active-qrcode/pkg/utils"
+)
+
+// 目标链接支持的占位符，跳转时替换为本次扫码的信息
+const (
+	PlaceholderScanID          = "scan_id"          // 扫描记录ID
+	PlaceholderConversionToken = "conversion_token" // 带签名的转化凭证，用于落地页回传转化
+	PlaceholderShortCode       = "short_code"       // 活码短码
+	PlaceholderStaticID        = "static_id"        // 静态码ID
+	PlaceholderDevice          = "device"           // 设备类型
+	PlaceholderRegion          = "region"           // 地区
+	PlaceholderTimestamp       = "ts"               // 扫码时间（Unix秒）
+)
+
+var placeholderPattern = regexp.MustCompile(`\{([A-Za-z_]+)\}`)
+
+var supportedPlaceholders = []string{
+	PlaceholderScanID,
+	PlaceholderConversionToken,
+	PlaceholderShortCode,
+	PlaceholderStaticID,
+	PlaceholderDevice,
+	PlaceholderRegion,
+	PlaceholderTimestamp,
+}
+
+// hasPlaceholder 判断目标链接是否包含指定占位符
+func hasPlaceholder(targetURL, name string) bool {
+	return strings.Contains(targetURL, "{"+name+"}")
+}
+
+// expandTargetURL 替换目标链接中的占位符，取值经过URL转义
+//
+// 路径中的占位符按路径转义，查询参数和锚点中的占位符按查询参数转义，避免取值中的 &、=、+ 改变参数结构。
+func expandTargetURL(targetURL string, values map[string]string) string {
+	if !strings.Contains(targetURL, "{") {
+		return targetURL
+	}
+	queryStart := strings.IndexAny(targetURL, "?#")
+	var b strings.Builder
+	last := 0
+	for _, loc := range placeholderPattern.FindAllStringSubmatchIndex(targetURL, -1) {
+		value, ok := values[targetURL[loc[2]:loc[3]]]
+		if !ok {
+			continue
+		}
+		b.WriteString(targetURL[last:loc[0]])
+		if queryStart >= 0 && loc[0] > queryStart {
+			b.WriteString(url.QueryEscape(value))
+		} else {
+			b.WriteString(url.PathEscape(value))
+		}
+		last = loc[1]
+	}
+	b.WriteString(targetURL[last:])
+	return b.String()
+}
+
+// needsScanRecordID 判断目标链接是否需要先写入扫描记录以获得ID
+func needsScanRecordID(targetURL string) bool {
+	return hasPlaceholder(targetURL, PlaceholderScanID) || hasPlaceholder(targetURL, PlaceholderConversionToken)
+}
+
+// placeholderValues 根据本次扫码生成占位符取值，scanID 为0（未写入扫描记录）时转化凭证为空
+func (s *ActiveQRCodeService) placeholderValues(activeQR *models.ActiveQRCode, staticQR *models.StaticQRCode, scan *ScanContext, scanID uint) map[string]string {
+	token := ""
+	if scanID > 0 {
+		token = s.conversionToken(scanID)
+	}
+	return map[string]string{
+		PlaceholderScanID:          strconv.FormatUint(uint64(scanID), 10),
+		PlaceholderConversionToken: token,
+		PlaceholderShortCode:       activeQR.ShortCode,
+		PlaceholderStaticID:        strconv.FormatUint(uint64(staticQR.ID), 10),
+		PlaceholderDevice:          scan.Device,
+		PlaceholderRegion:          scan.Region.Name(),
+		PlaceholderTimestamp:       strconv.FormatInt(scan.Time.Unix(), 10),
+	}
+}
+
+// validateTargetURL 校验目标链接模板：只能使用支持的占位符，且替换后必须是有效的http(s)链接
+func validateTargetURL(targetURL string) error {
+	targetURL = strings.TrimSpace(targetURL)
+	if targetURL == "" {
+		return &models.AppError{
+			Code:    "INVALID_TARGET_URL",
+			Message: "目标链接不能为空",
+		}
+	}
+
+	for _, match := range placeholderPattern.FindAllStringSubmatch(targetURL, -1) {
+		if !contains(supportedPlaceholders, match[1]) {
+			return &models.AppError{
+				Code:    "INVALID_TARGET_URL",
+				Message: fmt.Sprintf("不支持的占位符: {%s}，可用占位符: {%s}", match[1], strings.Join(supportedPlaceholders, "}, {")),
+			}
+		}
+	}
+
+	// 协议部分不允许使用占位符，保证替换后仍以http(s)://开头
+	if !utils.IsValidURL(targetURL) {
+		return &models.AppError{
+			Code:    "INVALID_TARGET_URL",
+			Message: "目标链接必须以http://或https://开头",
+		}
+	}
+
+	// 使用示例值替换后检查链接格式，包括占位符取值为空的情况
+	samples := []map[string]string{
+		{
+			PlaceholderScanID:          "1",
+			PlaceholderConversionToken: "1.c2lnbmF0dXJl",
+			PlaceholderShortCode:       "abcd1234",
+			PlaceholderStaticID:        "1",
+			PlaceholderDevice:          "mobile",
+			PlaceholderRegion:          "广东省",
+			PlaceholderTimestamp:       "1700000000",
+		},
+		{},
+	}
+	for _, sample := range samples {
+		values := make(map[string]string, len(supportedPlaceholders))
+		for _, name := range supportedPlaceholders {
+			values[name] = sample[name]
+		}
+		expanded := expandTargetURL(targetURL, values)
+		u, err := url.Parse(expanded)
+		if err != nil || u.Host == "" || !utils.IsValidURL(expanded) {
+			return &models.AppError{
+				Code:    "INVALID_TARGET_URL",
+				Message: fmt.Sprintf("目标链接替换占位符后不是有效的链接: %s", expanded),
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,25 @@
+package services
+
+import "testing"
+
+func TestExpandTargetURLEscaping(t *testing.T) {
+	values := map[string]string{
+		PlaceholderRegion:    "a&b=c+d /e",
+		PlaceholderShortCode: "abcd1234",
+	}
+	tests := []struct {
+		targetURL string
+		want      string
+	}{
+		{"https://example.com/{region}/{short_code}", "https://example.com/a&b=c+d%20%2Fe/abcd1234"},
+		{"https://example.com/?r={region}&c={short_code}", "https://example.com/?r=a%26b%3Dc%2Bd+%2Fe&c=abcd1234"},
+		{"https://example.com/{short_code}?r={region}", "https://example.com/abcd1234?r=a%26b%3Dc%2Bd+%2Fe"},
+		{"https://example.com/#r={region}", "https://example.com/#r=a%26b%3Dc%2Bd+%2Fe"},
+		{"https://example.com/?r={unknown}", "https://example.com/?r={unknown}"},
+	}
+	for _, tt := range tests {
+		if got := expandTargetURL(tt.targetURL, values); got != tt.want {
+			t.Errorf("expandTargetURL(%q) = %q, want %q", tt.targetURL, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"wechat-active-qrcode/internal/models"
+)
+
+// channelParam 投放渠道参数，如 /r/abc?src=poster_a
+const channelParam = "src"
+
+// trackingParams 提取扫码链接中的 src 和 utm_* 参数（每个参数取第一个值）
+func trackingParams(query url.Values) map[string]string {
+	params := make(map[string]string)
+	for key, values := range query {
+		if len(values) == 0 || values[0] == "" {
+			continue
+		}
+		if key == channelParam || strings.HasPrefix(key, "utm_") {
+			params[key] = values[0]
+		}
+	}
+	return params
+}
+
+// scanChannel 返回扫码的投放渠道：优先 src，其次 utm_source
+func scanChannel(params map[string]string) string {
+	if channel := params[channelParam]; channel != "" {
+		return channel
+	}
+	return params["utm_source"]
+}
+
+// encodeTrackingParams 将跟踪参数编码为JSON，无参数时返回空字符串
+func encodeTrackingParams(params map[string]string) string {
+	if len(params) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(params)
+	return string(data)
+}
+
+// parseUTMParams 解析静态码固定追加的UTM参数（JSON对象）
+func parseUTMParams(value string) (map[string]string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "null" {
+		return nil, nil
+	}
+	var params map[string]string
+	if err := json.Unmarshal([]byte(value), &params); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+// validateUTMParams 校验静态码的固定UTM参数，参数名必须以utm_开头
+func validateUTMParams(value string) error {
+	params, err := parseUTMParams(value)
+	if err != nil {
+		return &models.AppError{
+			Code:    "INVALID_UTM_PARAMS",
+			Message: fmt.Sprintf("UTM参数格式错误，应为JSON对象: %v", err),
+		}
+	}
+	for key := range params {
+		if !strings.HasPrefix(key, "utm_") {
+			return &models.AppError{
+				Code:    "INVALID_UTM_PARAMS",
+				Message: fmt.Sprintf("UTM参数名必须以utm_开头: %s", key),
+			}
+		}
+	}
+	return nil
+}
+
+// buildTargetURL 生成最终跳转链接
+//
+// 参数优先级：静态码固定UTM参数 > 目标链接自带参数 > 扫码链接透传参数。
+func buildTargetURL(targetURL string, passQuery bool, query url.Values, utmParams string) string {
+	fixed, _ := parseUTMParams(utmParams)
+	if (!passQuery || len(query) == 0) && len(fixed) == 0 {
+		return targetURL
+	}
+
+	u, err := url.Parse(targetURL)
+	if err != nil {
+		return targetURL
+	}
+
+	params := u.Query()
+	if passQuery {
+		for key, values := range query {
+			if _, exists := params[key]; !exists {
+				params[key] = values
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(fixed))
+	for key := range fixed {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		params.Set(key, fixed[key])
+	}
+
+	u.RawQuery = params.Encode()
+	return u.String()
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestBuildTargetURL(t *testing.T) {
+	query := url.Values{"src": {"poster"}, "utm_campaign": {"scan"}, "ref": {"qr"}}
+	tests := []struct {
+		name      string
+		targetURL string
+		passQuery bool
+		utmParams string
+		want      string
+	}{
+		{"unchanged", "https://example.com/a?x=1", false, "", "https://example.com/a?x=1"},
+		{"pass query", "https://example.com/a", true, "", "https://example.com/a?ref=qr&src=poster&utm_campaign=scan"},
+		// 目标链接自带的参数不被透传参数覆盖
+		{"target wins over query", "https://example.com/a?ref=site", true, "", "https://example.com/a?ref=site&src=poster&utm_campaign=scan"},
+		// 静态码固定UTM参数覆盖目标链接和透传参数
+		{"fixed utm wins", "https://example.com/a?utm_campaign=site", true, `{"utm_campaign":"fixed","utm_medium":"qr"}`, "https://example.com/a?ref=qr&src=poster&utm_campaign=fixed&utm_medium=qr"},
+		{"fixed utm without pass", "https://example.com/a", false, `{"utm_source":"qr"}`, "https://example.com/a?utm_source=qr"},
+		{"invalid utm ignored", "https://example.com/a", false, "not json", "https://example.com/a"},
+	}
+	for _, tt := range tests {
+		if got := buildTargetURL(tt.targetURL, tt.passQuery, query, tt.utmParams); got != tt.want {
+			t.Errorf("%s: buildTargetURL = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestScanChannel(t *testing.T) {
+	tests := []struct {
+		query url.Values
+		want  string
+	}{
+		{url.Values{"src": {"poster"}, "utm_source": {"wechat"}}, "poster"},
+		{url.Values{"src": {""}, "utm_source": {"wechat"}}, "wechat"},
+		{url.Values{"utm_source": {"wechat", "other"}}, "wechat"},
+		{url.Values{"ref": {"qr"}}, ""},
+	}
+	for _, tt := range tests {
+		params := trackingParams(tt.query)
+		if got := scanChannel(params); got != tt.want {
+			t.Errorf("scanChannel(%v) = %q, want %q", tt.query, got, tt.want)
+		}
+		if _, ok := params["ref"]; ok {
+			t.Errorf("trackingParams(%v) kept a non-tracking parameter", tt.query)
+		}
+	}
+}
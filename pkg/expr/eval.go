@@ -0,0 +1,177 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// node 语法树节点，类型在编译时确定
+type node interface {
+	typ() Type
+	eval(env *Env) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+	t     Type
+}
+
+func (n *literalNode) typ() Type { return n.t }
+
+func (n *literalNode) eval(env *Env) (interface{}, error) { return n.value, nil }
+
+type listNode struct {
+	values []interface{}
+	elem   Type
+}
+
+func (n *listNode) typ() Type { return TypeList }
+
+func (n *listNode) eval(env *Env) (interface{}, error) { return n.values, nil }
+
+// varNode 普通变量，未提供取值时为对应类型的零值
+type varNode struct {
+	name string
+	t    Type
+}
+
+func (n *varNode) typ() Type { return n.t }
+
+func (n *varNode) eval(env *Env) (interface{}, error) {
+	value, ok := env.Vars[n.name]
+	if !ok || value == nil {
+		switch n.t {
+		case TypeNumber:
+			return float64(0), nil
+		case TypeBool:
+			return false, nil
+		}
+		return "", nil
+	}
+
+	switch v := value.(type) {
+	case string:
+		if n.t == TypeString {
+			return v, nil
+		}
+	case float64:
+		if n.t == TypeNumber {
+			return v, nil
+		}
+	case int:
+		if n.t == TypeNumber {
+			return float64(v), nil
+		}
+	case bool:
+		if n.t == TypeBool {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("变量 %s 的取值类型错误: %T", n.name, value)
+}
+
+// mapNode 映射取值，不存在的键为空字符串
+type mapNode struct {
+	name string
+	key  string
+}
+
+func (n *mapNode) typ() Type { return TypeString }
+
+func (n *mapNode) eval(env *Env) (interface{}, error) {
+	return env.Maps[n.name][n.key], nil
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n *notNode) typ() Type { return TypeBool }
+
+func (n *notNode) eval(env *Env) (interface{}, error) {
+	value, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return !value.(bool), nil
+}
+
+// logicalNode && 和 ||，短路求值
+type logicalNode struct {
+	or          bool
+	left, right node
+}
+
+func (n *logicalNode) typ() Type { return TypeBool }
+
+func (n *logicalNode) eval(env *Env) (interface{}, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if left.(bool) == n.or {
+		return n.or, nil
+	}
+	return n.right.eval(env)
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n *compareNode) typ() Type { return TypeBool }
+
+func (n *compareNode) eval(env *Env) (interface{}, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "<":
+		return left.(float64) < right.(float64), nil
+	case "<=":
+		return left.(float64) <= right.(float64), nil
+	case ">":
+		return left.(float64) > right.(float64), nil
+	case ">=":
+		return left.(float64) >= right.(float64), nil
+	case "in":
+		for _, item := range right.([]interface{}) {
+			if equal(left, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "contains":
+		return strings.Contains(strings.ToLower(left.(string)), strings.ToLower(right.(string))), nil
+	}
+	return nil, fmt.Errorf("不支持的运算符: %s", n.op)
+}
+
+// equal 比较两个同类型的值，字符串不区分大小写
+func equal(a, b interface{}) bool {
+	if as, ok := a.(string); ok {
+		bs, _ := b.(string)
+		return strings.EqualFold(as, bs)
+	}
+	return a == b
+}
+
+func contains(list []string, item string) bool {
+	for _, s := range list {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
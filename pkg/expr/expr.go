@@ -0,0 +1,124 @@
+// Package expr 实现用于静态码定向条件的小型表达式语言
+//
+// 表达式只能读取调用方声明的变量，不支持函数调用和循环，求值时间与表达式长度成正比。
+//
+// 语法示例：
+//
+//	(os == "iOS" && city contains "上海" && weekday in [0, 6]) || device == "tablet"
+//	query.src == "poster" and not cookie.vip == "1"
+//
+// 支持的运算：
+//   - 逻辑：&&（and）、||（or）、!（not）
+//   - 比较：== !=（字符串不区分大小写），< <= > >=（仅数字）
+//   - in：左侧值是否在右侧列表中，如 hour in [9, 10, 11]
+//   - contains：左侧字符串是否包含右侧字符串（不区分大小写）
+//   - 映射取值：query.src 或 query["utm-source"]，不存在的键为空字符串
+package expr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxLength 表达式最大长度（字符数）
+const MaxLength = 1000
+
+// maxDepth 表达式最大嵌套深度
+const maxDepth = 32
+
+// Type 值类型
+type Type int
+
+const (
+	TypeString Type = iota
+	TypeNumber
+	TypeBool
+	TypeList
+)
+
+// String 返回类型名称，用于错误提示
+func (t Type) String() string {
+	switch t {
+	case TypeString:
+		return "字符串"
+	case TypeNumber:
+		return "数字"
+	case TypeBool:
+		return "布尔值"
+	case TypeList:
+		return "列表"
+	}
+	return "未知类型"
+}
+
+// Schema 表达式可以使用的变量
+type Schema struct {
+	Vars map[string]Type // 普通变量及其类型
+	Maps []string        // 字符串映射变量，如 query、cookie，通过 name.key 或 name["key"] 取值
+}
+
+// Env 求值时的变量取值，值的类型应与 Schema 一致：字符串为string，数字为float64或int
+type Env struct {
+	Vars map[string]interface{}
+	Maps map[string]map[string]string
+}
+
+// Error 表达式编译错误，Pos 为出错位置（从0开始的字符序号）
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("第%d个字符: %s", e.Pos+1, e.Msg)
+}
+
+// Program 编译后的表达式，可并发求值
+type Program struct {
+	source string
+	root   node
+}
+
+// String 返回表达式源码
+func (p *Program) String() string {
+	return p.source
+}
+
+// Compile 解析表达式并做类型检查，表达式的结果必须是布尔值
+func Compile(source string, schema Schema) (*Program, error) {
+	runes := []rune(source)
+	if len(runes) > MaxLength {
+		return nil, fmt.Errorf("表达式过长，最多%d个字符", MaxLength)
+	}
+	if strings.TrimSpace(source) == "" {
+		return nil, fmt.Errorf("表达式为空")
+	}
+
+	tokens, err := lex(runes)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens, schema: schema}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("存在多余的内容 %q", tok.text)}
+	}
+	if root.typ() != TypeBool {
+		return nil, &Error{Pos: 0, Msg: fmt.Sprintf("表达式的结果应为布尔值，实际为%s", root.typ())}
+	}
+	return &Program{source: source, root: root}, nil
+}
+
+// Eval 对表达式求值
+func (p *Program) Eval(env Env) (bool, error) {
+	value, err := p.root.eval(&env)
+	if err != nil {
+		return false, err
+	}
+	result, _ := value.(bool)
+	return result, nil
+}
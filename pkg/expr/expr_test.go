@@ -0,0 +1,218 @@
+package expr
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+var testSchema = Schema{
+	Vars: map[string]Type{
+		"os":      TypeString,
+		"device":  TypeString,
+		"city":    TypeString,
+		"hour":    TypeNumber,
+		"weekday": TypeNumber,
+	},
+	Maps: []string{"query", "cookie"},
+}
+
+var testEnv = Env{
+	Vars: map[string]interface{}{
+		"os":      "iOS",
+		"device":  "mobile",
+		"city":    "上海市",
+		"hour":    10,
+		"weekday": float64(6),
+	},
+	Maps: map[string]map[string]string{
+		"query":  {"src": "poster", "utm-source": "wechat"},
+		"cookie": {"vip": "1"},
+	},
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		// 比较
+		{`os == "iOS"`, true},
+		{`os == "ios"`, true},
+		{`os != "Android"`, true},
+		{`hour >= 9 && hour < 18`, true},
+		{`hour > 10`, false},
+		{`hour <= 10`, true},
+		{`weekday == 6`, true},
+		{`hour == -1`, false},
+		// 优先级：! 高于 &&，&& 高于 ||，比较高于 !
+		{`not os == "Android"`, true},
+		{`!os == "iOS"`, false},
+		{`not (os == "iOS" or device == "tablet")`, false},
+		{`os == "Android" && device == "mobile" || hour == 10`, true},
+		{`os == "Android" && (device == "mobile" || hour == 10)`, false},
+		{`hour == 10 || os == "Android" && device == "tablet"`, true},
+		{`!!(os == "iOS")`, true},
+		{`true and not false`, true},
+		// in 和 contains
+		{`weekday in [0, 6]`, true},
+		{`hour in [9, 11]`, false},
+		{`os in ["Android", "ios"]`, true},
+		{`city contains "上海"`, true},
+		{`city contains "北京"`, false},
+		{`os contains "IO"`, true},
+		// 映射取值，不存在的键为空字符串
+		{`query.src == "poster"`, true},
+		{`query["utm-source"] == "WeChat"`, true},
+		{`query.missing == ""`, true},
+		{`cookie.vip == "1" and query.src in ["poster", "flyer"]`, true},
+		{`not cookie.vip == "1"`, false},
+		// 单引号、转义
+		{`os == 'iOS'`, true},
+		{`"a\"b" contains "\""`, true},
+	}
+	for _, tt := range tests {
+		program, err := Compile(tt.source, testSchema)
+		if err != nil {
+			t.Errorf("Compile(%s): %v", tt.source, err)
+			continue
+		}
+		got, err := program.Eval(testEnv)
+		if err != nil {
+			t.Errorf("Eval(%s): %v", tt.source, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Eval(%s) = %v, want %v", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		msg    string // 错误信息中应包含的内容
+	}{
+		// 类型错误
+		{`os == 1`, "类型不一致"},
+		{`hour == "10"`, "类型不一致"},
+		{`os < "b"`, "只能比较数字"},
+		{`hour contains "1"`, "contains 两侧应为字符串"},
+		{`os`, "结果应为布尔值"},
+		{`hour + 1`, "无法识别的字符"},
+		{`os && hour == 1`, "两侧应为条件"},
+		{`!os`, "只能用于布尔值"},
+		{`hour == [1, 2]`, "不能比较列表"},
+		// in
+		{`os in "iOS"`, "右侧应为列表"},
+		{`hour in ["9"]`, "左侧为数字"},
+		{`os in []`, "列表不能为空"},
+		{`os in ["a", 1]`, "类型必须一致"},
+		{`os in [os]`, "只能是字符串或数字"},
+		// 变量和映射
+		{`region == "x"`, "未知变量"},
+		{`query == "x"`, "需要指定参数名"},
+		{`query.1 == "x"`, "后应为参数名"},
+		{`query[src] == "x"`, "应为字符串参数名"},
+		// 语法
+		{``, "表达式为空"},
+		{`os == `, "表达式不完整"},
+		{`os = "iOS"`, "无效的运算符"},
+		{`os == "iOS" & hour == 1`, "无效的运算符"},
+		{`os == "iOS`, "缺少结束引号"},
+		{`(os == "iOS"`, "应为"},
+		{`os == "iOS")`, "多余的内容"},
+		{`hour == 1.2.3`, "无效的数字"},
+	}
+	for _, tt := range tests {
+		_, err := Compile(tt.source, testSchema)
+		if err == nil {
+			t.Errorf("Compile(%s) should fail", tt.source)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("Compile(%s) error = %q, want it to contain %q", tt.source, err, tt.msg)
+		}
+	}
+}
+
+func TestCompileErrorPosition(t *testing.T) {
+	_, err := Compile(`os == "iOS" && city == 1`, testSchema)
+	var exprErr *Error
+	if !errors.As(err, &exprErr) {
+		t.Fatalf("error should be *Error, got %T", err)
+	}
+	if exprErr.Pos != 20 {
+		t.Errorf("error position = %d, want 20 (the == operator)", exprErr.Pos)
+	}
+}
+
+func TestCompileLengthLimit(t *testing.T) {
+	base := `os == "iOS"`
+	source := base + strings.Repeat(" ", MaxLength-len(base))
+	if _, err := Compile(source, testSchema); err != nil {
+		t.Errorf("expression of exactly %d characters should compile: %v", MaxLength, err)
+	}
+	if _, err := Compile(source+" ", testSchema); err == nil {
+		t.Errorf("expression longer than %d characters should be rejected", MaxLength)
+	}
+
+	// 长度按字符而不是字节计算
+	chinese := `city == "` + strings.Repeat("上", MaxLength-len(`city == ""`)) + `"`
+	if _, err := Compile(chinese, testSchema); err != nil {
+		t.Errorf("length should be counted in characters: %v", err)
+	}
+}
+
+func TestCompileDepthLimit(t *testing.T) {
+	nested := func(depth int) string {
+		return strings.Repeat("(", depth) + `os == "iOS"` + strings.Repeat(")", depth)
+	}
+	if _, err := Compile(nested(maxDepth), testSchema); err != nil {
+		t.Errorf("nesting of %d levels should compile: %v", maxDepth, err)
+	}
+	if _, err := Compile(nested(maxDepth+1), testSchema); err == nil || !strings.Contains(err.Error(), "嵌套层数") {
+		t.Errorf("nesting of %d levels should be rejected, got %v", maxDepth+1, err)
+	}
+
+	nots := strings.Repeat("!", maxDepth+1) + `(os == "iOS")`
+	if _, err := Compile(nots, testSchema); err == nil || !strings.Contains(err.Error(), "嵌套层数") {
+		t.Errorf("%d nested ! should be rejected, got %v", maxDepth+1, err)
+	}
+
+	// 同一层的长链不受深度限制
+	chain := strings.TrimSuffix(strings.Repeat(`hour == 1 || `, 60), " || ")
+	if _, err := Compile(chain, testSchema); err != nil {
+		t.Errorf("long flat chain should compile: %v", err)
+	}
+}
+
+func TestEvalMissingAndMistypedVars(t *testing.T) {
+	program, err := Compile(`os == "" && hour == 0 && query.src == ""`, testSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := program.Eval(Env{}); err != nil || !got {
+		t.Errorf("missing variables should be zero values, got %v, %v", got, err)
+	}
+
+	program, err = Compile(`hour == 1`, testSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := program.Eval(Env{Vars: map[string]interface{}{"hour": "1"}}); err == nil {
+		t.Error("Eval should reject a value of the wrong type")
+	}
+}
+
+func TestEvalShortCircuit(t *testing.T) {
+	// 右侧的变量类型错误，短路时不应被求值
+	program, err := Compile(`os == "iOS" || hour == 1`, testSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := Env{Vars: map[string]interface{}{"os": "iOS", "hour": "bad"}}
+	if got, err := program.Eval(env); err != nil || !got {
+		t.Errorf("|| should short-circuit, got %v, %v", got, err)
+	}
+}
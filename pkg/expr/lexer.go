@@ -0,0 +1,114 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOp // 运算符和标点
+)
+
+type token struct {
+	kind tokenKind
+	text string  // 标识符、运算符原文，或字符串字面量的值
+	num  float64 // 数字字面量的值
+	pos  int
+}
+
+// keywordOps 单词形式的运算符，统一转换为符号形式
+var keywordOps = map[string]string{
+	"and":      "&&",
+	"or":       "||",
+	"not":      "!",
+	"in":       "in",
+	"contains": "contains",
+}
+
+// symbolOps 符号运算符，长的在前
+var symbolOps = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")", "[", "]", ",", "."}
+
+// lex 将表达式拆分为记号
+func lex(src []rune) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(src) {
+		r := src[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '"' || r == '\'':
+			start := i
+			var sb strings.Builder
+			i++
+			for {
+				if i >= len(src) {
+					return nil, &Error{Pos: start, Msg: "字符串缺少结束引号"}
+				}
+				if src[i] == r {
+					i++
+					break
+				}
+				if src[i] == '\\' && i+1 < len(src) {
+					i++
+				}
+				sb.WriteRune(src[i])
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenString, text: sb.String(), pos: start})
+
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(src) && unicode.IsDigit(src[i+1])):
+			start := i
+			i++
+			for i < len(src) && (unicode.IsDigit(src[i]) || src[i] == '.') {
+				i++
+			}
+			text := string(src[start:i])
+			num, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, &Error{Pos: start, Msg: fmt.Sprintf("无效的数字 %q", text)}
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: text, num: num, pos: start})
+
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(src) && (unicode.IsLetter(src[i]) || unicode.IsDigit(src[i]) || src[i] == '_') {
+				i++
+			}
+			text := string(src[start:i])
+			if op, ok := keywordOps[strings.ToLower(text)]; ok {
+				tokens = append(tokens, token{kind: tokenOp, text: op, pos: start})
+			} else {
+				tokens = append(tokens, token{kind: tokenIdent, text: text, pos: start})
+			}
+
+		default:
+			matched := false
+			for _, op := range symbolOps {
+				if strings.HasPrefix(string(src[i:min(i+len(op), len(src))]), op) {
+					tokens = append(tokens, token{kind: tokenOp, text: op, pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				if r == '=' || r == '&' || r == '|' {
+					return nil, &Error{Pos: i, Msg: fmt.Sprintf("无效的运算符 %q，比较请使用==，逻辑运算请使用&&或||", string(r))}
+				}
+				return nil, &Error{Pos: i, Msg: fmt.Sprintf("无法识别的字符 %q", string(r))}
+			}
+		}
+	}
+	tokens = append(tokens, token{kind: tokenEOF, text: "结尾", pos: len(src)})
+	return tokens, nil
+}
@@ -0,0 +1,308 @@
+package expr
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// parser 递归下降解析器，解析的同时完成变量和类型检查
+//
+// 优先级从低到高：|| → && → ! → 比较（== != < <= > >= in contains）→ 基本项
+type parser struct {
+	tokens []token
+	index  int
+	depth  int
+	schema Schema
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.index]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.index]
+	if tok.kind != tokenEOF {
+		p.index++
+	}
+	return tok
+}
+
+// accept 下一个记号是指定运算符时消耗它
+func (p *parser) accept(op string) bool {
+	if tok := p.peek(); tok.kind == tokenOp && tok.text == op {
+		p.index++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.accept(op) {
+		tok := p.peek()
+		return &Error{Pos: tok.pos, Msg: fmt.Sprintf("应为 %q，实际为 %q", op, tok.text)}
+	}
+	return nil
+}
+
+// enter 限制嵌套深度，防止恶意构造的表达式导致栈溢出
+func (p *parser) enter() error {
+	p.depth++
+	if p.depth > maxDepth {
+		return &Error{Pos: p.peek().pos, Msg: fmt.Sprintf("嵌套层数超过%d层", maxDepth)}
+	}
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		if !p.accept("||") {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if err := checkLogical(tok, left, right); err != nil {
+			return nil, err
+		}
+		left = &logicalNode{or: true, left: left, right: right}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		if !p.accept("&&") {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if err := checkLogical(tok, left, right); err != nil {
+			return nil, err
+		}
+		left = &logicalNode{left: left, right: right}
+	}
+}
+
+func (p *parser) parseNot() (node, error) {
+	tok := p.peek()
+	if !p.accept("!") {
+		return p.parseComparison()
+	}
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	operand, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	if operand.typ() != TypeBool {
+		return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("! 只能用于布尔值，实际为%s", operand.typ())}
+	}
+	return &notNode{operand: operand}, nil
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	tok := p.peek()
+	if tok.kind != tokenOp {
+		return left, nil
+	}
+	switch tok.text {
+	case "==", "!=", "<", "<=", ">", ">=", "in", "contains":
+	default:
+		return left, nil
+	}
+	p.next()
+
+	right, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkComparison(tok, left, right); err != nil {
+		return nil, err
+	}
+	return &compareNode{op: tok.text, left: left, right: right}, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenString:
+		return &literalNode{value: tok.text, t: TypeString}, nil
+
+	case tokenNumber:
+		return &literalNode{value: tok.num, t: TypeNumber}, nil
+
+	case tokenIdent:
+		switch tok.text {
+		case "true":
+			return &literalNode{value: true, t: TypeBool}, nil
+		case "false":
+			return &literalNode{value: false, t: TypeBool}, nil
+		}
+		return p.parseVariable(tok)
+
+	case tokenOp:
+		switch tok.text {
+		case "(":
+			if err := p.enter(); err != nil {
+				return nil, err
+			}
+			defer p.leave()
+
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return inner, nil
+		case "[":
+			return p.parseList(tok)
+		}
+	}
+
+	if tok.kind == tokenEOF {
+		return nil, &Error{Pos: tok.pos, Msg: "表达式不完整"}
+	}
+	return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("此处不应出现 %q", tok.text)}
+}
+
+// parseVariable 解析变量或映射取值（name.key、name["key"]）
+func (p *parser) parseVariable(tok token) (node, error) {
+	if t, ok := p.schema.Vars[tok.text]; ok {
+		return &varNode{name: tok.text, t: t}, nil
+	}
+	if !contains(p.schema.Maps, tok.text) {
+		return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("未知变量 %q，可用变量: %s", tok.text, p.variableNames())}
+	}
+
+	var key string
+	switch {
+	case p.accept("."):
+		keyTok := p.next()
+		if keyTok.kind != tokenIdent {
+			return nil, &Error{Pos: keyTok.pos, Msg: fmt.Sprintf("%s. 后应为参数名", tok.text)}
+		}
+		key = keyTok.text
+	case p.accept("["):
+		keyTok := p.next()
+		if keyTok.kind != tokenString {
+			return nil, &Error{Pos: keyTok.pos, Msg: fmt.Sprintf("%s[] 中应为字符串参数名", tok.text)}
+		}
+		key = keyTok.text
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("%s 需要指定参数名，如 %s.src 或 %s[\"src\"]", tok.text, tok.text, tok.text)}
+	}
+	return &mapNode{name: tok.text, key: key}, nil
+}
+
+// parseList 解析列表字面量，元素必须是同一类型的字符串或数字
+func (p *parser) parseList(open token) (node, error) {
+	list := &listNode{elem: -1}
+	if p.accept("]") {
+		return nil, &Error{Pos: open.pos, Msg: "列表不能为空"}
+	}
+	for {
+		tok := p.next()
+		var value interface{}
+		var t Type
+		switch tok.kind {
+		case tokenString:
+			value, t = tok.text, TypeString
+		case tokenNumber:
+			value, t = tok.num, TypeNumber
+		default:
+			return nil, &Error{Pos: tok.pos, Msg: "列表元素只能是字符串或数字"}
+		}
+		if list.elem >= 0 && t != list.elem {
+			return nil, &Error{Pos: tok.pos, Msg: "列表元素的类型必须一致"}
+		}
+		list.elem = t
+		list.values = append(list.values, value)
+
+		if p.accept("]") {
+			return list, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// variableNames 列出可用变量，用于错误提示
+func (p *parser) variableNames() string {
+	var names []string
+	for name := range p.schema.Vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range p.schema.Maps {
+		names = append(names, name+".参数名")
+	}
+	return strings.Join(names, ", ")
+}
+
+func checkLogical(op token, left, right node) error {
+	if left.typ() != TypeBool || right.typ() != TypeBool {
+		return &Error{Pos: op.pos, Msg: fmt.Sprintf("%s 两侧应为条件（布尔值），实际为%s和%s", op.text, left.typ(), right.typ())}
+	}
+	return nil
+}
+
+func checkComparison(op token, left, right node) error {
+	lt, rt := left.typ(), right.typ()
+	switch op.text {
+	case "==", "!=":
+		if lt == TypeList || rt == TypeList {
+			return &Error{Pos: op.pos, Msg: fmt.Sprintf("%s 不能比较列表，判断是否在列表中请使用in", op.text)}
+		}
+		if lt != rt {
+			return &Error{Pos: op.pos, Msg: fmt.Sprintf("%s 两侧类型不一致：%s和%s", op.text, lt, rt)}
+		}
+	case "<", "<=", ">", ">=":
+		if lt != TypeNumber || rt != TypeNumber {
+			return &Error{Pos: op.pos, Msg: fmt.Sprintf("%s 只能比较数字，实际为%s和%s", op.text, lt, rt)}
+		}
+	case "in":
+		list, ok := right.(*listNode)
+		if !ok {
+			return &Error{Pos: op.pos, Msg: "in 右侧应为列表，如 [\"iOS\", \"Android\"]"}
+		}
+		if lt != list.elem {
+			return &Error{Pos: op.pos, Msg: fmt.Sprintf("in 左侧为%s，列表元素为%s", lt, list.elem)}
+		}
+	case "contains":
+		if lt != TypeString || rt != TypeString {
+			return &Error{Pos: op.pos, Msg: fmt.Sprintf("contains 两侧应为字符串，实际为%s和%s", lt, rt)}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package geoip
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNotCovered IP不在数据库覆盖范围内，如数据库不支持的IPv6地址或无效地址，属于正常情况
+var ErrNotCovered = errors.New("ip address not covered by geoip database")
+
+// 地区匹配精确度
+const (
+	MatchNone     = 0
+	MatchCountry  = 1
+	MatchProvince = 2
+	MatchCity     = 3
+)
+
+// Region IP解析出的地区信息
+type Region struct {
+	Country  string `json:"country"`
+	Province string `json:"province"`
+	City     string `json:"city"`
+	ISP      string `json:"isp,omitempty"`
+}
+
+// IsEmpty 是否未解析出任何地区信息
+func (r Region) IsEmpty() bool {
+	return r.Country == "" && r.Province == "" && r.City == ""
+}
+
+// Name 返回用于统计的地区名称（省份优先，其次国家）
+func (r Region) Name() string {
+	if r.Province != "" {
+		return r.Province
+	}
+	return r.Country
+}
+
+// String 返回完整的地区描述，如"中国 广东省 深圳市"
+func (r Region) String() string {
+	var parts []string
+	for _, part := range []string{r.Country, r.Province, r.City} {
+		if part != "" && !contains(parts, part) {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// MatchLevel 返回地区规则与当前地区的匹配精确度：城市 > 省份 > 国家
+func (r Region) MatchLevel(rule string) int {
+	rule = normalizeName(rule)
+	if rule == "" {
+		return MatchNone
+	}
+	switch rule {
+	case normalizeName(r.City):
+		return MatchCity
+	case normalizeName(r.Province):
+		return MatchProvince
+	case normalizeName(r.Country):
+		return MatchCountry
+	}
+	return MatchNone
+}
+
+// BestMatchLevel 返回一组地区规则中的最高匹配精确度
+func (r Region) BestMatchLevel(rules []string) int {
+	best := MatchNone
+	for _, rule := range rules {
+		if level := r.MatchLevel(rule); level > best {
+			best = level
+		}
+	}
+	return best
+}
+
+// Resolver IP地区解析器
+type Resolver interface {
+	Resolve(ip string) (Region, error)
+	Close() error
+}
+
+// NewResolver 根据配置创建地区解析器，provider为空时返回不解析的空实现
+func NewResolver(provider, dbPath, language string) (Resolver, error) {
+	switch provider {
+	case "", "none":
+		return NopResolver{}, nil
+	case "mmdb", "maxmind":
+		return NewMMDBResolver(dbPath, language)
+	case "ip2region":
+		return NewIP2RegionResolver(dbPath)
+	default:
+		return nil, fmt.Errorf("unsupported geoip provider: %s", provider)
+	}
+}
+
+// NopResolver 不做任何解析的地区解析器
+type NopResolver struct{}
+
+// Resolve 始终返回空地区
+func (NopResolver) Resolve(ip string) (Region, error) {
+	return Region{}, nil
+}
+
+// Close 无需释放资源
+func (NopResolver) Close() error {
+	return nil
+}
+
+// regionSuffixes 地区名称中可忽略的后缀，使"广东"与"广东省"能够匹配
+var regionSuffixes = []string{"特别行政区", "维吾尔自治区", "壮族自治区", "回族自治区", "自治区", "省", "市"}
+
+// normalizeName 统一地区名称格式
+func normalizeName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || name == "0" {
+		return ""
+	}
+	for _, suffix := range regionSuffixes {
+		if trimmed := strings.TrimSuffix(name, suffix); trimmed != name && trimmed != "" {
+			return trimmed
+		}
+	}
+	return name
+}
+
+func contains(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package geoip
+
+import "testing"
+
+func TestMatchLevel(t *testing.T) {
+	region := Region{Country: "中国", Province: "广东省", City: "深圳市"}
+
+	tests := []struct {
+		rule string
+		want int
+	}{
+		{"深圳", MatchCity},
+		{"深圳市", MatchCity},
+		{"广东", MatchProvince},
+		{"广东省", MatchProvince},
+		{"中国", MatchCountry},
+		{" 中国 ", MatchCountry},
+		{"北京", MatchNone},
+		{"", MatchNone},
+		{"0", MatchNone},
+		{"省", MatchNone},
+	}
+	for _, tt := range tests {
+		if got := region.MatchLevel(tt.rule); got != tt.want {
+			t.Errorf("MatchLevel(%q) = %d, want %d", tt.rule, got, tt.want)
+		}
+	}
+}
+
+func TestMatchLevelAutonomousRegion(t *testing.T) {
+	region := Region{Country: "中国", Province: "新疆维吾尔自治区", City: "乌鲁木齐市"}
+	if got := region.MatchLevel("新疆"); got != MatchProvince {
+		t.Errorf("MatchLevel(新疆) = %d, want %d", got, MatchProvince)
+	}
+	if got := (Region{Country: "United States"}).MatchLevel("united states"); got != MatchCountry {
+		t.Errorf("MatchLevel is case sensitive: got %d", got)
+	}
+}
+
+func TestMatchLevelEmptyRegion(t *testing.T) {
+	// 未解析出的字段不能与任何规则匹配
+	if got := (Region{}).MatchLevel("中国"); got != MatchNone {
+		t.Errorf("empty region MatchLevel = %d, want %d", got, MatchNone)
+	}
+}
+
+func TestBestMatchLevel(t *testing.T) {
+	region := Region{Country: "中国", Province: "广东省", City: "深圳市"}
+
+	tests := []struct {
+		name  string
+		rules []string
+		want  int
+	}{
+		{"no rules", nil, MatchNone},
+		{"no match", []string{"北京", "上海"}, MatchNone},
+		{"country only", []string{"北京", "中国"}, MatchCountry},
+		{"city beats country", []string{"中国", "深圳"}, MatchCity},
+		{"province beats country", []string{"中国", "广东", "上海"}, MatchProvince},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := region.BestMatchLevel(tt.rules); got != tt.want {
+				t.Errorf("BestMatchLevel(%v) = %d, want %d", tt.rules, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegionString(t *testing.T) {
+	region := Region{Country: "中国", Province: "北京市", City: "北京市"}
+	if got := region.String(); got != "中国 北京市" {
+		t.Errorf("String() = %q", got)
+	}
+	if got := region.Name(); got != "北京市" {
+		t.Errorf("Name() = %q", got)
+	}
+	if got := (Region{Country: "日本"}).Name(); got != "日本" {
+		t.Errorf("Name() without province = %q", got)
+	}
+}
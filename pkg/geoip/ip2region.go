@@ -0,0 +1,116 @@
+package geoip
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// ip2region xdb 文件结构常量
+const (
+	xdbHeaderLength      = 256
+	xdbVectorIndexCols   = 256
+	xdbVectorIndexSize   = 8
+	xdbSegmentIndexSize  = 14
+	xdbVectorIndexLength = xdbVectorIndexCols * xdbVectorIndexCols * xdbVectorIndexSize
+)
+
+// IP2RegionResolver 基于ip2region xdb格式数据库的地区解析器
+//
+// 整个数据库文件在启动时加载到内存，查询过程只读，可并发使用。
+type IP2RegionResolver struct {
+	content []byte
+}
+
+// NewIP2RegionResolver 加载ip2region.xdb数据库文件
+func NewIP2RegionResolver(dbPath string) (*IP2RegionResolver, error) {
+	content, err := os.ReadFile(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ip2region database: %v", err)
+	}
+	if len(content) < xdbHeaderLength+xdbVectorIndexLength {
+		return nil, fmt.Errorf("invalid ip2region database: %s", dbPath)
+	}
+	return &IP2RegionResolver{
+		content: content,
+	}, nil
+}
+
+// Resolve 解析IP对应的地区（仅支持IPv4）
+func (r *IP2RegionResolver) Resolve(ip string) (Region, error) {
+	parsed := net.ParseIP(ip).To4()
+	if parsed == nil {
+		return Region{}, fmt.Errorf("%w: %s", ErrNotCovered, ip)
+	}
+
+	data, err := r.search(binary.BigEndian.Uint32(parsed))
+	if err != nil {
+		return Region{}, err
+	}
+	return parseIP2RegionData(data), nil
+}
+
+// Close 释放内存中的数据库
+func (r *IP2RegionResolver) Close() error {
+	r.content = nil
+	return nil
+}
+
+// search 通过向量索引定位段索引区间，再二分查找IP所在的数据段
+func (r *IP2RegionResolver) search(ip uint32) (string, error) {
+	il0 := (ip >> 24) & 0xFF
+	il1 := (ip >> 16) & 0xFF
+	offset := xdbHeaderLength + int(il0*xdbVectorIndexCols*xdbVectorIndexSize+il1*xdbVectorIndexSize)
+	startPtr := binary.LittleEndian.Uint32(r.content[offset:])
+	endPtr := binary.LittleEndian.Uint32(r.content[offset+4:])
+	if endPtr < startPtr || int(endPtr) > len(r.content) {
+		return "", fmt.Errorf("corrupted ip2region database")
+	}
+
+	low, high := 0, int((endPtr-startPtr)/xdbSegmentIndexSize)
+	for low <= high {
+		mid := (low + high) >> 1
+		p := int(startPtr) + mid*xdbSegmentIndexSize
+		if p+xdbSegmentIndexSize > len(r.content) {
+			break
+		}
+
+		segment := r.content[p : p+xdbSegmentIndexSize]
+		startIP := binary.LittleEndian.Uint32(segment)
+		endIP := binary.LittleEndian.Uint32(segment[4:])
+		switch {
+		case ip < startIP:
+			high = mid - 1
+		case ip > endIP:
+			low = mid + 1
+		default:
+			dataLen := int(binary.LittleEndian.Uint16(segment[8:]))
+			dataPtr := int(binary.LittleEndian.Uint32(segment[10:]))
+			if dataPtr+dataLen > len(r.content) {
+				return "", fmt.Errorf("corrupted ip2region database")
+			}
+			return string(r.content[dataPtr : dataPtr+dataLen]), nil
+		}
+	}
+
+	return "", ErrNotCovered
+}
+
+// parseIP2RegionData 解析"国家|区域|省份|城市|ISP"格式的地区数据，"0"表示未知
+func parseIP2RegionData(data string) Region {
+	fields := strings.Split(data, "|")
+	get := func(i int) string {
+		if i >= len(fields) || fields[i] == "0" {
+			return ""
+		}
+		return fields[i]
+	}
+	return Region{
+		Country:  get(0),
+		Province: get(2),
+		City:     get(3),
+		ISP:      get(4),
+	}
+}
@@ -0,0 +1,157 @@
+package geoip
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type xdbSegment struct {
+	start, end string
+	data       string
+}
+
+// buildXDB 按 xdb 格式生成测试数据库：头部、向量索引、地区数据、段索引
+//
+// 每个段须位于同一个 /16 网段内，便于直接填写向量索引。
+func buildXDB(t *testing.T, segments []xdbSegment) []byte {
+	t.Helper()
+	content := make([]byte, xdbHeaderLength+xdbVectorIndexLength)
+
+	dataPtrs := make([]int, len(segments))
+	for i, seg := range segments {
+		dataPtrs[i] = len(content)
+		content = append(content, seg.data...)
+	}
+
+	indexStart := len(content)
+	for i, seg := range segments {
+		entry := make([]byte, xdbSegmentIndexSize)
+		binary.LittleEndian.PutUint32(entry, ipToUint32(t, seg.start))
+		binary.LittleEndian.PutUint32(entry[4:], ipToUint32(t, seg.end))
+		binary.LittleEndian.PutUint16(entry[8:], uint16(len(seg.data)))
+		binary.LittleEndian.PutUint32(entry[10:], uint32(dataPtrs[i]))
+		content = append(content, entry...)
+	}
+
+	for i, seg := range segments {
+		ip := ipToUint32(t, seg.start)
+		ptr := uint32(indexStart + i*xdbSegmentIndexSize)
+		offset := vectorOffset(ip)
+		if binary.LittleEndian.Uint32(content[offset:]) == 0 {
+			binary.LittleEndian.PutUint32(content[offset:], ptr)
+		}
+		binary.LittleEndian.PutUint32(content[offset+4:], ptr)
+	}
+	return content
+}
+
+func vectorOffset(ip uint32) int {
+	return xdbHeaderLength + int((ip>>24)&0xFF)*xdbVectorIndexCols*xdbVectorIndexSize + int((ip>>16)&0xFF)*xdbVectorIndexSize
+}
+
+func ipToUint32(t *testing.T, ip string) uint32 {
+	t.Helper()
+	parsed := net.ParseIP(ip).To4()
+	if parsed == nil {
+		t.Fatalf("invalid test ip: %s", ip)
+	}
+	return binary.BigEndian.Uint32(parsed)
+}
+
+func TestIP2RegionResolve(t *testing.T) {
+	content := buildXDB(t, []xdbSegment{
+		{"1.2.0.0", "1.2.0.255", "中国|0|广东省|深圳市|电信"},
+		{"1.2.1.0", "1.2.3.255", "中国|0|北京|北京市|联通"},
+		{"1.2.8.0", "1.2.8.255", "美国|0|0|0|0"},
+	})
+	resolver := &IP2RegionResolver{content: content}
+
+	tests := []struct {
+		ip   string
+		want Region
+	}{
+		{"1.2.0.0", Region{Country: "中国", Province: "广东省", City: "深圳市", ISP: "电信"}},
+		{"1.2.0.255", Region{Country: "中国", Province: "广东省", City: "深圳市", ISP: "电信"}},
+		{"1.2.2.17", Region{Country: "中国", Province: "北京", City: "北京市", ISP: "联通"}},
+		{"1.2.8.8", Region{Country: "美国"}},
+	}
+	for _, tt := range tests {
+		got, err := resolver.Resolve(tt.ip)
+		if err != nil {
+			t.Errorf("Resolve(%s) error: %v", tt.ip, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Resolve(%s) = %+v, want %+v", tt.ip, got, tt.want)
+		}
+	}
+
+	for _, ip := range []string{"1.2.5.1", "1.3.0.1", "9.9.9.9"} {
+		if _, err := resolver.Resolve(ip); !errors.Is(err, ErrNotCovered) {
+			t.Errorf("Resolve(%s) = %v, want ErrNotCovered for an ip outside all segments", ip, err)
+		}
+	}
+	if _, err := resolver.Resolve("2001:db8::1"); !errors.Is(err, ErrNotCovered) {
+		t.Errorf("Resolve(IPv6) = %v, want ErrNotCovered", err)
+	}
+}
+
+func TestIP2RegionCorruptedDatabase(t *testing.T) {
+	ip := ipToUint32(t, "1.2.0.1")
+
+	t.Run("data pointer out of range", func(t *testing.T) {
+		content := buildXDB(t, []xdbSegment{{"1.2.0.0", "1.2.0.255", "中国|0|广东省|深圳市|电信"}})
+		segment := binary.LittleEndian.Uint32(content[vectorOffset(ip):])
+		binary.LittleEndian.PutUint32(content[segment+10:], uint32(len(content)))
+		if _, err := (&IP2RegionResolver{content: content}).search(ip); err == nil {
+			t.Error("search should fail when the data pointer is past the end")
+		}
+	})
+
+	t.Run("segment pointer out of range", func(t *testing.T) {
+		content := buildXDB(t, []xdbSegment{{"1.2.0.0", "1.2.0.255", "中国|0|广东省|深圳市|电信"}})
+		offset := vectorOffset(ip)
+		binary.LittleEndian.PutUint32(content[offset:], uint32(len(content)))
+		binary.LittleEndian.PutUint32(content[offset+4:], uint32(len(content)))
+		if _, err := (&IP2RegionResolver{content: content}).search(ip); err == nil {
+			t.Error("search should fail when the segment index is past the end")
+		}
+	})
+
+	t.Run("end pointer before start pointer", func(t *testing.T) {
+		content := buildXDB(t, []xdbSegment{{"1.2.0.0", "1.2.0.255", "中国|0|广东省|深圳市|电信"}})
+		offset := vectorOffset(ip)
+		start := binary.LittleEndian.Uint32(content[offset:])
+		binary.LittleEndian.PutUint32(content[offset+4:], start-xdbSegmentIndexSize)
+		if _, err := (&IP2RegionResolver{content: content}).search(ip); err == nil {
+			t.Error("search should fail when the vector index range is reversed")
+		}
+	})
+}
+
+func TestNewIP2RegionResolverRejectsShortFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "short.xdb")
+	if err := os.WriteFile(path, make([]byte, xdbHeaderLength), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewIP2RegionResolver(path); err == nil {
+		t.Error("NewIP2RegionResolver should reject a file without a full vector index")
+	}
+	if _, err := NewIP2RegionResolver(filepath.Join(t.TempDir(), "missing.xdb")); err == nil {
+		t.Error("NewIP2RegionResolver should fail for a missing file")
+	}
+}
+
+func TestParseIP2RegionData(t *testing.T) {
+	got := parseIP2RegionData("中国|0|0|0|0")
+	if got != (Region{Country: "中国"}) {
+		t.Errorf("parseIP2RegionData = %+v", got)
+	}
+	if got := parseIP2RegionData("中国"); got != (Region{Country: "中国"}) {
+		t.Errorf("parseIP2RegionData with missing fields = %+v", got)
+	}
+}
@@ -0,0 +1,77 @@
+package geoip
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// MMDBResolver 基于MaxMind格式数据库（如GeoLite2-City.mmdb）的地区解析器
+type MMDBResolver struct {
+	reader   *maxminddb.Reader
+	language string
+}
+
+// mmdbRecord MaxMind城市库中需要的字段
+type mmdbRecord struct {
+	Country struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+// NewMMDBResolver 打开mmdb数据库文件，language指定地区名称语言（如zh-CN、en）
+func NewMMDBResolver(dbPath, language string) (*MMDBResolver, error) {
+	reader, err := maxminddb.Open(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mmdb database: %v", err)
+	}
+	if language == "" {
+		language = "zh-CN"
+	}
+	return &MMDBResolver{
+		reader:   reader,
+		language: language,
+	}, nil
+}
+
+// Resolve 解析IP对应的地区
+func (r *MMDBResolver) Resolve(ip string) (Region, error) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return Region{}, fmt.Errorf("%w: %s", ErrNotCovered, ip)
+	}
+
+	var record mmdbRecord
+	if err := r.reader.Lookup(parsed, &record); err != nil {
+		return Region{}, err
+	}
+
+	region := Region{
+		Country: r.pickName(record.Country.Names),
+		City:    r.pickName(record.City.Names),
+	}
+	if len(record.Subdivisions) > 0 {
+		region.Province = r.pickName(record.Subdivisions[0].Names)
+	}
+	return region, nil
+}
+
+// Close 关闭数据库
+func (r *MMDBResolver) Close() error {
+	return r.reader.Close()
+}
+
+// pickName 按配置语言选取名称，缺失时回退到英文
+func (r *MMDBResolver) pickName(names map[string]string) string {
+	if name, ok := names[r.language]; ok {
+		return name
+	}
+	return names["en"]
+}
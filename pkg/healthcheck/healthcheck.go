@@ -0,0 +1,131 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 默认配置
+const (
+	DefaultMethod  = http.MethodGet
+	DefaultTimeout = 10 * time.Second
+)
+
+// maxBodyRead 探测GET请求时最多读取的响应体字节数，读完后连接可复用
+const maxBodyRead = 64 << 10
+
+// Result 一次探测的结果
+type Result struct {
+	StatusCode int
+	Latency    time.Duration
+	Healthy    bool
+	Err        error
+}
+
+// Checker 目标链接可用性探测器
+//
+// Client 可注入（如测试中使用 httptest 服务器的客户端），为空时使用 http.DefaultClient。
+type Checker struct {
+	Client         *http.Client
+	Method         string        // GET 或 HEAD，HEAD 不被支持（405/501）时自动改用 GET
+	Timeout        time.Duration // 单次探测超时
+	ExpectedStatus []int         // 视为正常的状态码，为空时 2xx 和 3xx 均视为正常
+	UserAgent      string
+}
+
+// New 创建探测器，method 和 timeout 为空时使用默认值
+func New(client *http.Client, method string, timeout time.Duration, expectedStatus []int) *Checker {
+	method = strings.ToUpper(strings.TrimSpace(method))
+	if method == "" {
+		method = DefaultMethod
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Checker{
+		Client:         client,
+		Method:         method,
+		Timeout:        timeout,
+		ExpectedStatus: expectedStatus,
+		UserAgent:      "wechat-active-qrcode-healthcheck/1.0",
+	}
+}
+
+// ValidateMethod 校验探测方法
+func ValidateMethod(method string) error {
+	switch strings.ToUpper(strings.TrimSpace(method)) {
+	case "", http.MethodGet, http.MethodHead:
+		return nil
+	}
+	return fmt.Errorf("不支持的探测方法: %s，可选值: GET, HEAD", method)
+}
+
+// Check 探测目标链接
+func (c *Checker) Check(ctx context.Context, targetURL string) Result {
+	start := time.Now()
+	statusCode, err := c.do(ctx, c.Method, targetURL)
+	if err == nil && c.Method == http.MethodHead &&
+		(statusCode == http.StatusMethodNotAllowed || statusCode == http.StatusNotImplemented) {
+		statusCode, err = c.do(ctx, http.MethodGet, targetURL)
+	}
+
+	result := Result{
+		StatusCode: statusCode,
+		Latency:    time.Since(start),
+		Err:        err,
+	}
+	if err == nil {
+		result.Healthy = c.isExpected(statusCode)
+		if !result.Healthy {
+			result.Err = fmt.Errorf("unexpected status code: %d", statusCode)
+		}
+	}
+	return result
+}
+
+// do 发送一次请求并返回状态码
+func (c *Checker) do(ctx context.Context, method, targetURL string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, targetURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxBodyRead))
+
+	return resp.StatusCode, nil
+}
+
+// isExpected 判断状态码是否视为正常
+func (c *Checker) isExpected(statusCode int) bool {
+	if len(c.ExpectedStatus) == 0 {
+		return statusCode >= 200 && statusCode < 400
+	}
+	for _, expected := range c.ExpectedStatus {
+		if statusCode == expected {
+			return true
+		}
+	}
+	return false
+}
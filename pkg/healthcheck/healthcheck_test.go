@@ -0,0 +1,98 @@
+package healthcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCheckStatus(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/created", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		path     string
+		expected []int
+		healthy  bool
+		status   int
+	}{
+		{"default accepts 2xx", "/ok", nil, true, http.StatusOK},
+		{"default rejects 404", "/missing", nil, false, http.StatusNotFound},
+		{"expected status matches", "/created", []int{201}, true, http.StatusCreated},
+		{"expected status mismatch", "/ok", []int{201}, false, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := New(server.Client(), "GET", time.Second, tt.expected)
+			result := checker.Check(context.Background(), server.URL+tt.path)
+			if result.Healthy != tt.healthy {
+				t.Errorf("Healthy = %v, want %v (err: %v)", result.Healthy, tt.healthy, result.Err)
+			}
+			if result.StatusCode != tt.status {
+				t.Errorf("StatusCode = %d, want %d", result.StatusCode, tt.status)
+			}
+		})
+	}
+}
+
+func TestCheckHeadFallsBackToGet(t *testing.T) {
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	result := New(server.Client(), "head", time.Second, nil).Check(context.Background(), server.URL)
+	if !result.Healthy {
+		t.Fatalf("expected healthy after GET fallback, got %v", result.Err)
+	}
+	if len(methods) != 2 || methods[0] != http.MethodHead || methods[1] != http.MethodGet {
+		t.Errorf("methods = %v, want [HEAD GET]", methods)
+	}
+}
+
+func TestCheckTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	result := New(server.Client(), "GET", 50*time.Millisecond, nil).Check(context.Background(), server.URL)
+	if result.Healthy || result.Err == nil {
+		t.Fatalf("expected timeout error, got healthy=%v err=%v", result.Healthy, result.Err)
+	}
+}
+
+func TestCheckConnectionRefused(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	result := New(nil, "", time.Second, nil).Check(context.Background(), url)
+	if result.Healthy || result.Err == nil || result.StatusCode != 0 {
+		t.Fatalf("expected connection error, got %+v", result)
+	}
+}
@@ -0,0 +1,158 @@
+package language
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// tagPattern BCP 47 语言标签（简化）：主语言2-3个字母，后接若干子标签，如 zh、zh-CN、zh-Hans-CN
+var tagPattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{1,8})*$`)
+
+// Range Accept-Language 中的一项语言范围及其权重
+type Range struct {
+	Tag string  // 规范化后的语言标签，*表示任意语言
+	Q   float64 // 权重，0表示不接受
+}
+
+// IsValid 判断是否为合法的语言标签（不含通配符）
+func IsValid(tag string) bool {
+	return tagPattern.MatchString(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+}
+
+// Normalize 规范化语言标签：主语言小写，4字母文字子标签首字母大写，2字母地区子标签大写，如 zh-hans-cn → zh-Hans-CN
+func Normalize(tag string) string {
+	tag = strings.ReplaceAll(strings.TrimSpace(tag), "_", "-")
+	if tag == "*" {
+		return tag
+	}
+	parts := strings.Split(tag, "-")
+	for i, part := range parts {
+		switch {
+		case i == 0:
+			parts[i] = strings.ToLower(part)
+		case len(part) == 4:
+			parts[i] = strings.ToUpper(part[:1]) + strings.ToLower(part[1:])
+		case len(part) == 2:
+			parts[i] = strings.ToUpper(part)
+		default:
+			parts[i] = strings.ToLower(part)
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// ParseAcceptLanguage 解析Accept-Language请求头，按权重从高到低排序，权重相同时保持原顺序
+//
+// 格式错误的项被忽略，q=0 的项保留（表示明确不接受该语言）。
+func ParseAcceptLanguage(header string) []Range {
+	var ranges []Range
+	for _, item := range strings.Split(header, ",") {
+		fields := strings.Split(item, ";")
+		tag := Normalize(fields[0])
+		if tag != "*" && !IsValid(tag) {
+			continue
+		}
+
+		q := 1.0
+		valid := true
+		for _, param := range fields[1:] {
+			name, value, found := strings.Cut(strings.TrimSpace(param), "=")
+			if !found || strings.ToLower(strings.TrimSpace(name)) != "q" {
+				continue
+			}
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || parsed < 0 || parsed > 1 {
+				valid = false
+				break
+			}
+			q = parsed
+		}
+		if valid {
+			ranges = append(ranges, Range{Tag: tag, Q: q})
+		}
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].Q > ranges[j].Q
+	})
+	return ranges
+}
+
+// Preferred 返回请求头中权重最高的具体语言，没有时返回空字符串
+func Preferred(header string) string {
+	for _, r := range ParseAcceptLanguage(header) {
+		if r.Tag != "*" && r.Q > 0 {
+			return r.Tag
+		}
+	}
+	return ""
+}
+
+// 语言范围与语言标签的匹配程度，数值越大越精确
+const (
+	matchNone     = iota
+	matchWildcard // *
+	matchFallback // 范围比标签更具体，如 zh-CN 回退匹配 zh
+	matchPrefix   // 范围是标签的前缀，如 zh 匹配 zh-CN
+	matchExact    // 完全相同
+)
+
+// matchLevel 计算语言范围与语言标签的匹配程度（不区分大小写）
+func matchLevel(rangeTag, tag string) int {
+	r := strings.ToLower(rangeTag)
+	t := strings.ToLower(tag)
+	switch {
+	case r == "*":
+		return matchWildcard
+	case r == t:
+		return matchExact
+	case strings.HasPrefix(t, r+"-"):
+		return matchPrefix
+	case strings.HasPrefix(r, t+"-"):
+		return matchFallback
+	}
+	return matchNone
+}
+
+// Negotiate 根据Accept-Language从支持的语言中选出最合适的一个，都不可接受时返回空字符串
+//
+// 每个支持的语言取匹配最精确的语言范围的权重（如 "en;q=0.8, en-GB;q=0" 中 en-GB 不可接受），
+// 选择权重最高者；权重相同时依次比较语言范围在请求头中的顺序、匹配精确程度、支持列表中的顺序。
+func Negotiate(header string, supported []string) string {
+	ranges := ParseAcceptLanguage(header)
+
+	best := ""
+	bestQ, bestPos, bestLevel := 0.0, 0, matchNone
+	for _, tag := range supported {
+		tag = Normalize(tag)
+
+		q, pos, level := 0.0, 0, matchNone
+		for i, r := range ranges {
+			if l := matchLevel(r.Tag, tag); l > level {
+				q, pos, level = r.Q, i, l
+			}
+		}
+		if level == matchNone || q <= 0 {
+			continue
+		}
+
+		if best == "" || q > bestQ ||
+			(q == bestQ && (pos < bestPos || (pos == bestPos && level > bestLevel))) {
+			best, bestQ, bestPos, bestLevel = tag, q, pos, level
+		}
+	}
+	return best
+}
+
+// Contains 判断语言列表中是否包含指定语言（规范化后比较）
+func Contains(tags []string, tag string) bool {
+	tag = Normalize(tag)
+	for _, t := range tags {
+		if Normalize(t) == tag {
+			return true
+		}
+	}
+	return false
+}
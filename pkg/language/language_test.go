@@ -0,0 +1,126 @@
+package language
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []Range
+	}{
+		{"", nil},
+		{"da, en-gb;q=0.8, en;q=0.7", []Range{{"da", 1}, {"en-GB", 0.8}, {"en", 0.7}}},
+		{"fr;q=0.5, de", []Range{{"de", 1}, {"fr", 0.5}}},
+		// 权重相同时保持原顺序
+		{"en;q=0.5, fr;q=0.5, de;q=0.5", []Range{{"en", 0.5}, {"fr", 0.5}, {"de", 0.5}}},
+		{"zh_cn, *;q=0.1", []Range{{"zh-CN", 1}, {"*", 0.1}}},
+		{"en; Q=0.3", []Range{{"en", 0.3}}},
+		// q=0 表示明确不接受，保留
+		{"en;q=0", []Range{{"en", 0}}},
+		// 格式错误的项被忽略
+		{"en;q=2, fr;q=abc, not a tag, de;q=-1, ja", []Range{{"ja", 1}}},
+		{"es-419;q=0.9", []Range{{"es-419", 0.9}}},
+	}
+	for _, tt := range tests {
+		got := ParseAcceptLanguage(tt.header)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseAcceptLanguage(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestPreferred(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"zh-CN,zh;q=0.9,en;q=0.8", "zh-CN"},
+		{"en;q=0.5, fr", "fr"},
+		{"*, fr;q=0.5", "fr"},
+		{"en;q=0", ""},
+	}
+	for _, tt := range tests {
+		if got := Preferred(tt.header); got != tt.want {
+			t.Errorf("Preferred(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name      string
+		header    string
+		supported []string
+		want      string
+	}{
+		{"exact match", "zh-CN,zh;q=0.9,en;q=0.8", []string{"en", "zh-CN"}, "zh-CN"},
+		{"higher q wins", "en;q=0.8, zh;q=0.9", []string{"en", "zh"}, "zh"},
+		{"range more specific than tag", "en-US", []string{"zh-CN", "en"}, "en"},
+		{"range prefix of tag", "zh", []string{"en", "zh-TW", "zh-CN"}, "zh-TW"},
+		{"specific refusal beats prefix", "en;q=0.8, en-GB;q=0", []string{"en-GB", "en"}, "en"},
+		{"header order breaks ties", "fr;q=0.5, en;q=0.5", []string{"en", "fr"}, "fr"},
+		{"exact beats prefix at same position", "zh", []string{"zh-CN", "zh"}, "zh"},
+		{"wildcard", "*;q=0.5, fr", []string{"zh", "en"}, "zh"},
+		{"wildcard loses to explicit", "*;q=0.5, en;q=0.6", []string{"zh", "en"}, "en"},
+		{"wildcard refused", "*;q=0", []string{"zh"}, ""},
+		{"case insensitive", "ZH-cn", []string{"zh-cn"}, "zh-CN"},
+		{"no acceptable language", "fr, de", []string{"zh", "en"}, ""},
+		{"empty header", "", []string{"en"}, ""},
+		{"no supported languages", "en", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Negotiate(tt.header, tt.supported); got != tt.want {
+				t.Errorf("Negotiate(%q, %v) = %q, want %q", tt.header, tt.supported, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"zh-hans-cn": "zh-Hans-CN",
+		"EN_us":      "en-US",
+		" fr ":       "fr",
+		"es-419":     "es-419",
+		"*":          "*",
+	}
+	for input, want := range tests {
+		if got := Normalize(input); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestIsValid(t *testing.T) {
+	tests := map[string]bool{
+		"zh-CN":      true,
+		"zh_CN":      true,
+		"zh-Hans-CN": true,
+		"yue":        true,
+		"":           false,
+		"*":          false,
+		"c":          false,
+		"english":    false,
+		"zh--CN":     false,
+		"zh-CN!":     false,
+	}
+	for tag, want := range tests {
+		if got := IsValid(tag); got != want {
+			t.Errorf("IsValid(%q) = %v, want %v", tag, got, want)
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	tags := []string{"zh-cn", "en"}
+	if !Contains(tags, "zh_CN") {
+		t.Error("Contains should compare normalized tags")
+	}
+	if Contains(tags, "zh") {
+		t.Error("Contains should not match a prefix")
+	}
+}
@@ -0,0 +1,138 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Webhook消息格式
+const (
+	FormatJSON  = "json"  // {"title": "...", "content": "..."}
+	FormatWeCom = "wecom" // 企业微信群机器人
+)
+
+// Notifier 通知渠道
+type Notifier interface {
+	Notify(title, content string) error
+}
+
+// LogNotifier 将通知输出到服务日志
+type LogNotifier struct{}
+
+// Notify 输出通知到日志
+func (LogNotifier) Notify(title, content string) error {
+	log.Printf("[NOTIFY] %s\n%s", title, content)
+	return nil
+}
+
+// Webhook默认重试配置
+const (
+	defaultWebhookRetries = 3
+	defaultWebhookBackoff = 2 * time.Second
+)
+
+// WebhookNotifier 通过HTTP POST发送通知，网络错误和5xx、429响应按指数退避重试
+type WebhookNotifier struct {
+	URL     string
+	Format  string
+	Client  *http.Client
+	Retries int           // 失败后的重试次数
+	Backoff time.Duration // 第一次重试前的等待时间，之后每次翻倍
+}
+
+// NewWebhookNotifier 创建Webhook通知渠道，format 为空时使用json
+func NewWebhookNotifier(url, format string) (*WebhookNotifier, error) {
+	switch format {
+	case "":
+		format = FormatJSON
+	case FormatJSON, FormatWeCom:
+	default:
+		return nil, fmt.Errorf("unsupported webhook format: %s", format)
+	}
+	return &WebhookNotifier{
+		URL:     url,
+		Format:  format,
+		Client:  &http.Client{Timeout: 10 * time.Second},
+		Retries: defaultWebhookRetries,
+		Backoff: defaultWebhookBackoff,
+	}, nil
+}
+
+// Notify 发送通知到Webhook地址
+func (w *WebhookNotifier) Notify(title, content string) error {
+	var payload interface{}
+	switch w.Format {
+	case FormatWeCom:
+		payload = map[string]interface{}{
+			"msgtype": "markdown",
+			"markdown": map[string]string{
+				"content": fmt.Sprintf("**%s**\n%s", title, content),
+			},
+		}
+	default:
+		payload = map[string]string{
+			"title":   title,
+			"content": content,
+		}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	backoff := w.Backoff
+	for attempt := 0; ; attempt++ {
+		retryable, err := w.post(body)
+		if err == nil {
+			return nil
+		}
+		if !retryable || attempt >= w.Retries {
+			return err
+		}
+		log.Printf("Webhook attempt %d failed, retrying in %s: %v", attempt+1, backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// post 发送一次请求，返回失败时是否值得重试
+func (w *WebhookNotifier) post(body []byte) (bool, error) {
+	resp, err := w.Client.Post(w.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return true, fmt.Errorf("failed to send webhook: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		retryable := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retryable, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return false, nil
+}
+
+// MultiNotifier 同时发送到多个通知渠道
+type MultiNotifier []Notifier
+
+// Notify 依次发送到所有渠道，至少一个渠道送达即视为成功，其他渠道的错误只记录日志；全部失败时返回错误
+func (m MultiNotifier) Notify(title, content string) error {
+	var errs []string
+	for _, n := range m {
+		if err := n.Notify(title, content); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	if len(errs) < len(m) {
+		log.Printf("Notify failed on %d of %d channels: %s", len(errs), len(m), strings.Join(errs, "; "))
+		return nil
+	}
+	return fmt.Errorf("notify failed: %s", strings.Join(errs, "; "))
+}
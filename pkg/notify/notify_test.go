@@ -0,0 +1,110 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWebhookPayload(t *testing.T) {
+	tests := []struct {
+		format string
+		check  func(t *testing.T, payload map[string]interface{})
+	}{
+		{FormatJSON, func(t *testing.T, payload map[string]interface{}) {
+			if payload["title"] != "标题" || payload["content"] != "内容" {
+				t.Errorf("json payload = %v", payload)
+			}
+		}},
+		{FormatWeCom, func(t *testing.T, payload map[string]interface{}) {
+			markdown, _ := payload["markdown"].(map[string]interface{})
+			if payload["msgtype"] != "markdown" || markdown["content"] != "**标题**\n内容" {
+				t.Errorf("wecom payload = %v", payload)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+					t.Errorf("request = %s %s", r.Method, r.Header.Get("Content-Type"))
+				}
+				var payload map[string]interface{}
+				if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+					t.Errorf("decode payload: %v", err)
+				}
+				tt.check(t, payload)
+			}))
+			defer server.Close()
+
+			webhook, err := NewWebhookNotifier(server.URL, tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := webhook.Notify("标题", "内容"); err != nil {
+				t.Fatalf("Notify: %v", err)
+			}
+		})
+	}
+
+	if _, err := NewWebhookNotifier("http://localhost", "xml"); err == nil {
+		t.Error("unsupported format accepted")
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int // 依次返回的状态码，用完后重复最后一个
+		wantErr  bool
+		wantHits int32
+	}{
+		{"recovers", []int{500, 503, 200}, false, 3},
+		{"gives up", []int{502}, true, 3},
+		{"client error", []int{400}, true, 1},
+		{"rate limited", []int{429, 200}, false, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				i := int(atomic.AddInt32(&hits, 1)) - 1
+				if i >= len(tt.statuses) {
+					i = len(tt.statuses) - 1
+				}
+				w.WriteHeader(tt.statuses[i])
+			}))
+			defer server.Close()
+
+			webhook, _ := NewWebhookNotifier(server.URL, FormatJSON)
+			webhook.Retries = 2
+			webhook.Backoff = time.Millisecond
+			err := webhook.Notify("标题", "内容")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Notify error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if hits != tt.wantHits {
+				t.Errorf("webhook called %d times, want %d", hits, tt.wantHits)
+			}
+		})
+	}
+}
+
+type failingNotifier struct{}
+
+func (failingNotifier) Notify(title, content string) error {
+	return errors.New("unavailable")
+}
+
+func TestMultiNotifierSucceedsIfAnyChannelDelivers(t *testing.T) {
+	if err := (MultiNotifier{failingNotifier{}, LogNotifier{}}).Notify("标题", "内容"); err != nil {
+		t.Errorf("partial failure returned %v", err)
+	}
+	if err := (MultiNotifier{failingNotifier{}, failingNotifier{}}).Notify("标题", "内容"); err == nil {
+		t.Error("all channels failed but no error returned")
+	}
+}
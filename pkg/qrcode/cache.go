@@ -0,0 +1,91 @@
+package qrcode
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultCacheSize 默认缓存的图片数量
+const DefaultCacheSize = 256
+
+// Cache 按内容和参数缓存生成的图片，超出容量时淘汰最久未使用的
+type Cache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List
+}
+
+type cacheEntry struct {
+	key     string
+	content string // 二维码内容，用于按内容失效
+	data    []byte
+}
+
+// NewCache 创建缓存，capacity 不大于0时使用默认容量
+func NewCache(capacity int) *Cache {
+	if capacity <= 0 {
+		capacity = DefaultCacheSize
+	}
+	return &Cache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get 获取缓存的图片
+func (c *Cache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*cacheEntry).data, true
+}
+
+// Put 缓存内容为 content 的图片
+func (c *Cache) Put(key, content string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*cacheEntry).data = data
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, content: content, data: data})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// RemoveContent 删除内容为 content 的所有图片，返回删除的数量
+func (c *Cache) RemoveContent(content string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+	for elem := c.order.Front(); elem != nil; {
+		next := elem.Next()
+		if entry := elem.Value.(*cacheEntry); entry.content == content {
+			c.order.Remove(elem)
+			delete(c.entries, entry.key)
+			removed++
+		}
+		elem = next
+	}
+	return removed
+}
+
+// Len 返回缓存的图片数量
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
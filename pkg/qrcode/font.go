@@ -0,0 +1,155 @@
+package qrcode
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+var (
+	defaultFont     *Font
+	defaultFontOnce sync.Once
+)
+
+// Font 绘制边框文字的字体
+type Font struct {
+	sfnt *sfnt.Font
+	id   string // 字体内容摘要，用于缓存键
+}
+
+// LoadFont 解析 TTF/OTF 字体，字体集（TTC）使用其中的第一个字体
+func LoadFont(data []byte) (*Font, error) {
+	var f *sfnt.Font
+	c, err := sfnt.ParseCollection(data)
+	if err == nil {
+		f, err = c.Font(0)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("字体无法解析: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	return &Font{sfnt: f, id: hex.EncodeToString(sum[:8])}, nil
+}
+
+// LoadFontFile 从文件加载字体
+func LoadFontFile(path string) (*Font, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return LoadFont(data)
+}
+
+// DefaultFont 返回内置字体 Go Regular，只包含拉丁字母等西文字形
+//
+// 中文文字需要通过 SetFont 设置包含中文字形的字体，如 Noto Sans CJK。
+func DefaultFont() *Font {
+	defaultFontOnce.Do(func() {
+		f, err := LoadFont(goregular.TTF)
+		if err != nil {
+			panic("qrcode: invalid bundled font: " + err.Error())
+		}
+		defaultFont = f
+	})
+	return defaultFont
+}
+
+// SetFont 设置边框文字使用的字体，为空时使用内置字体
+func (g *Generator) SetFont(f *Font) {
+	g.font = f
+}
+
+// frameFont 返回边框文字使用的字体
+func (g *Generator) frameFont() *Font {
+	if g.font != nil {
+		return g.font
+	}
+	return DefaultFont()
+}
+
+// checkGlyphs 确认字体包含文字中的全部字形，缺少的字形会被绘制为空白方框
+func (f *Font) checkGlyphs(text string) error {
+	var buf sfnt.Buffer
+	for _, r := range text {
+		if r == '\n' || r == '\t' {
+			continue
+		}
+		if idx, err := f.sfnt.GlyphIndex(&buf, r); err != nil || idx == 0 {
+			return designErrorf("字体不包含文字「%c」，请配置包含该字形的海报字体", r)
+		}
+	}
+	return nil
+}
+
+// face 创建指定字号（像素）的字形，font.Face 不能并发使用，每次绘制单独创建
+func (f *Font) face(size float64) (font.Face, error) {
+	return opentype.NewFace(f.sfnt, &opentype.FaceOptions{
+		Size:    size,
+		DPI:     72,
+		Hinting: font.HintingNone,
+	})
+}
+
+// writeTextPath 把文字的字形轮廓写为 SVG 路径，(x, y) 为基线起点
+//
+// 输出轮廓而不是 <text> 元素，保证在没有安装该字体的设备上显示一致。
+func (f *Font) writeTextPath(buf *bytes.Buffer, text string, x, y, size float64) error {
+	var sbuf sfnt.Buffer
+	ppem := fixed.Int26_6(size * 64)
+	pen := x
+	for _, r := range text {
+		idx, err := f.sfnt.GlyphIndex(&sbuf, r)
+		if err != nil {
+			return err
+		}
+		segments, err := f.sfnt.LoadGlyph(&sbuf, idx, ppem, nil)
+		if err != nil {
+			return err
+		}
+		for _, seg := range segments {
+			op, points := "", 0
+			switch seg.Op {
+			case sfnt.SegmentOpMoveTo:
+				op, points = "M", 1
+			case sfnt.SegmentOpLineTo:
+				op, points = "L", 1
+			case sfnt.SegmentOpQuadTo:
+				op, points = "Q", 2
+			case sfnt.SegmentOpCubeTo:
+				op, points = "C", 3
+			}
+			buf.WriteString(op)
+			for i := 0; i < points; i++ {
+				if i > 0 {
+					buf.WriteByte(' ')
+				}
+				fmt.Fprintf(buf, "%s %s", formatCoord(pen+fixedToFloat(seg.Args[i].X)), formatCoord(y+fixedToFloat(seg.Args[i].Y)))
+			}
+		}
+		advance, err := f.sfnt.GlyphAdvance(&sbuf, idx, ppem, font.HintingNone)
+		if err != nil {
+			return err
+		}
+		pen += fixedToFloat(advance)
+	}
+	return nil
+}
+
+func fixedToFloat(v fixed.Int26_6) float64 {
+	return float64(v) / 64
+}
+
+// formatCoord 格式化字形坐标，保留两位小数以减小文件体积
+func formatCoord(v float64) string {
+	return formatFloat(math.Round(v*100) / 100)
+}
@@ -0,0 +1,53 @@
+package qrcode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Format 二维码输出格式
+type Format string
+
+// 支持的输出格式，SVG、PDF 和 EPS 为矢量格式，适合海报和印刷
+const (
+	FormatPNG Format = "png"
+	FormatSVG Format = "svg"
+	FormatPDF Format = "pdf"
+	FormatEPS Format = "eps"
+)
+
+// ParseFormat 解析输出格式，为空时返回 PNG
+func ParseFormat(s string) (Format, error) {
+	switch Format(strings.ToLower(strings.TrimSpace(s))) {
+	case "", FormatPNG:
+		return FormatPNG, nil
+	case FormatSVG:
+		return FormatSVG, nil
+	case FormatPDF:
+		return FormatPDF, nil
+	case FormatEPS:
+		return FormatEPS, nil
+	}
+	return "", fmt.Errorf("不支持的图片格式: %s，可选值: png, svg, pdf, eps", s)
+}
+
+// ContentType 返回格式对应的MIME类型
+func (f Format) ContentType() string {
+	switch f {
+	case FormatSVG:
+		return "image/svg+xml"
+	case FormatPDF:
+		return "application/pdf"
+	case FormatEPS:
+		return "application/postscript"
+	}
+	return "image/png"
+}
+
+// Extension 返回格式对应的文件扩展名（不含点）
+func (f Format) Extension() string {
+	if f == "" {
+		return string(FormatPNG)
+	}
+	return string(f)
+}
@@ -0,0 +1,314 @@
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// 文字位置
+const (
+	CaptionBottom = "bottom"
+	CaptionTop    = "top"
+)
+
+// MaxFramePadding 边框留白的最大值（像素）
+const MaxFramePadding = 512
+
+// MaxCaptionLength 说明文字的最大字数，避免换行后生成过高的图片
+const MaxCaptionLength = 64
+
+// Frame 海报边框：二维码四周留白并描边，在二维码下方或上方绘制标题和说明文字
+//
+// 文字和边框使用品牌样式的前景色，背景使用品牌样式的背景色。
+type Frame struct {
+	Title    string `json:"title,omitempty"`    // 标题，如活码名称
+	Caption  string `json:"caption,omitempty"`  // 说明文字，如"扫码加入社群"
+	Position string `json:"position,omitempty"` // 文字位置: bottom（默认）、top
+	Padding  int    `json:"padding,omitempty"`  // 留白（像素），为0时为二维码尺寸的1/16
+}
+
+// frameLine 一行文字
+type frameLine struct {
+	text     string
+	size     float64 // 字号（像素）
+	width    float64
+	baseline float64 // 基线纵坐标
+}
+
+// frameLayout 边框布局（单位均为像素）
+type frameLayout struct {
+	width, height int
+	qrX, qrY      int
+	border        float64 // 描边宽度
+	lines         []frameLine
+}
+
+// key 边框的缓存键
+func (f *Frame) key(fontID string) string {
+	return strings.Join([]string{f.Title, f.Caption, f.Position, strconv.Itoa(f.Padding), fontID}, "|")
+}
+
+// Validate 校验边框配置
+func (f *Frame) Validate() error {
+	switch f.Position {
+	case "", CaptionBottom, CaptionTop:
+	default:
+		return designErrorf("不支持的文字位置: %s，可选值: bottom, top", f.Position)
+	}
+	if f.Padding < 0 || f.Padding > MaxFramePadding {
+		return designErrorf("边框留白应在 0 到 %d 之间", MaxFramePadding)
+	}
+	if utf8.RuneCountInString(f.Caption) > MaxCaptionLength {
+		return designErrorf("说明文字不能超过 %d 个字", MaxCaptionLength)
+	}
+	return nil
+}
+
+// RenderFramed 生成带边框和文字的二维码海报，frame 为空时等同于 RenderStyled
+//
+// 仅支持 PNG 和 SVG 格式。SVG 中的文字输出为字形轮廓，不依赖查看设备上安装的字体。
+func (g *Generator) RenderFramed(content string, format Format, opts Options, style *Style, frame *Frame) ([]byte, error) {
+	if frame == nil {
+		return g.RenderStyled(content, format, opts, style)
+	}
+	if format != "" && format != FormatPNG && format != FormatSVG {
+		return nil, designErrorf("带边框的图片仅支持 PNG 和 SVG 格式")
+	}
+	if err := frame.Validate(); err != nil {
+		return nil, err
+	}
+	if style == nil {
+		style = &Style{}
+	}
+	fnt := g.frameFont()
+
+	key := opts.cacheKey(content, format) + "|" + style.key() + "|" + frame.key(fnt.id)
+	if g.cache != nil {
+		if data, ok := g.cache.Get(key); ok {
+			return data, nil
+		}
+	}
+	d, err := style.compile()
+	if err != nil {
+		return nil, err
+	}
+
+	// 二维码部分已在 RenderStyled 中确认能被识别
+	qrPNG, err := g.RenderStyled(content, FormatPNG, opts, style)
+	if err != nil {
+		return nil, err
+	}
+	qrImg, err := png.Decode(bytes.NewReader(qrPNG))
+	if err != nil {
+		return nil, err
+	}
+	fl, err := newFrameLayout(fnt, frame, qrImg.Bounds().Dx())
+	if err != nil {
+		return nil, err
+	}
+
+	// 加上文字后再确认一次，避免文字被误认为定位点
+	img, err := fl.rasterize(fnt, d, qrImg)
+	if err != nil {
+		return nil, err
+	}
+	if text, err := NewParser().parseFromImage(img); err != nil || text != content {
+		return nil, designErrorf("加上边框后无法被识别，请增大留白")
+	}
+
+	var data []byte
+	if format == FormatSVG {
+		qrSVG, err := g.RenderStyled(content, FormatSVG, opts, style)
+		if err != nil {
+			return nil, err
+		}
+		if data, err = fl.renderSVG(fnt, d, qrSVG); err != nil {
+			return nil, err
+		}
+	} else {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+		data = buf.Bytes()
+	}
+
+	if g.cache != nil {
+		g.cache.Put(key, content, data)
+	}
+	return data, nil
+}
+
+// newFrameLayout 计算边框布局，文字超过二维码宽度时自动换行
+func newFrameLayout(fnt *Font, frame *Frame, qrSize int) (*frameLayout, error) {
+	padding := frame.Padding
+	if padding == 0 {
+		padding = qrSize / 16
+	}
+	titleSize := math.Max(14, float64(qrSize)/12)
+	captionSize := math.Max(12, float64(qrSize)/16)
+
+	var lines []frameLine
+	addLines := func(text string, size float64) error {
+		if strings.TrimSpace(text) == "" {
+			return nil
+		}
+		if err := fnt.checkGlyphs(text); err != nil {
+			return err
+		}
+		face, err := fnt.face(size)
+		if err != nil {
+			return err
+		}
+		defer face.Close()
+		for _, line := range wrapText(face, text, float64(qrSize)) {
+			lines = append(lines, frameLine{
+				text:  line,
+				size:  size,
+				width: fixedToFloat(font.MeasureString(face, line)),
+			})
+		}
+		return nil
+	}
+	if err := addLines(frame.Title, titleSize); err != nil {
+		return nil, err
+	}
+	if err := addLines(frame.Caption, captionSize); err != nil {
+		return nil, err
+	}
+
+	fl := &frameLayout{
+		width:  qrSize + 2*padding,
+		qrX:    padding,
+		qrY:    padding,
+		border: math.Max(2, float64(qrSize)/128),
+	}
+	textHeight := 0.0
+	for _, line := range lines {
+		textHeight += line.size * 1.25
+	}
+	textTop := float64(padding + qrSize + padding/2)
+	fl.height = qrSize + 2*padding
+	if len(lines) > 0 {
+		fl.height = int(math.Ceil(textTop+textHeight)) + padding
+		if frame.Position == CaptionTop {
+			textTop = float64(padding)
+			fl.qrY = int(math.Ceil(textTop+textHeight)) + padding/2
+		}
+	}
+
+	// 基线位于行高的80%处，字号的1.25倍行高为上下留出间距
+	y := textTop
+	for i := range lines {
+		lines[i].baseline = y + lines[i].size
+		y += lines[i].size * 1.25
+	}
+	fl.lines = lines
+	return fl, nil
+}
+
+// wrapText 按最大宽度拆分文字，保留原有的换行
+func wrapText(face font.Face, text string, maxWidth float64) []string {
+	limit := fixed.Int26_6(maxWidth * 64)
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		var line []rune
+		var width fixed.Int26_6
+		for _, r := range strings.TrimSpace(paragraph) {
+			advance, _ := face.GlyphAdvance(r)
+			if len(line) > 0 && width+advance > limit {
+				lines = append(lines, string(line))
+				line, width = nil, 0
+			}
+			line = append(line, r)
+			width += advance
+		}
+		if len(line) > 0 {
+			lines = append(lines, string(line))
+		}
+	}
+	return lines
+}
+
+// rasterize 绘制带边框和文字的位图
+func (fl *frameLayout) rasterize(fnt *Font, d *design, qrImg image.Image) (*image.RGBA, error) {
+	img := image.NewRGBA(image.Rect(0, 0, fl.width, fl.height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: d.background}, image.Point{}, draw.Src)
+
+	// 描边
+	fg := &image.Uniform{C: d.foreground}
+	b := int(math.Round(fl.border))
+	for _, r := range []image.Rectangle{
+		image.Rect(0, 0, fl.width, b),
+		image.Rect(0, fl.height-b, fl.width, fl.height),
+		image.Rect(0, 0, b, fl.height),
+		image.Rect(fl.width-b, 0, fl.width, fl.height),
+	} {
+		draw.Draw(img, r, fg, image.Point{}, draw.Src)
+	}
+
+	qrRect := qrImg.Bounds().Add(image.Pt(fl.qrX, fl.qrY))
+	draw.Draw(img, qrRect, qrImg, qrImg.Bounds().Min, draw.Src)
+
+	for _, line := range fl.lines {
+		face, err := fnt.face(line.size)
+		if err != nil {
+			return nil, err
+		}
+		drawer := &font.Drawer{
+			Dst:  img,
+			Src:  fg,
+			Face: face,
+			Dot:  fixed.Point26_6{X: fixed.Int26_6((float64(fl.width) - line.width) / 2 * 64), Y: fixed.Int26_6(line.baseline * 64)},
+		}
+		drawer.DrawString(line.text)
+		face.Close()
+	}
+	return img, nil
+}
+
+// renderSVG 生成带边框和文字的SVG，二维码作为嵌套的 <svg> 元素
+func (fl *frameLayout) renderSVG(fnt *Font, d *design, qrSVG []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" version="1.1" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		fl.width, fl.height, fl.width, fl.height)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="%s"/>`+"\n", fl.width, fl.height, hexColor(d.background))
+	half := fl.border / 2
+	fmt.Fprintf(&buf, `<rect x="%s" y="%s" width="%s" height="%s" fill="none" stroke="%s" stroke-width="%s"/>`+"\n",
+		formatFloat(half), formatFloat(half), formatFloat(float64(fl.width)-fl.border), formatFloat(float64(fl.height)-fl.border),
+		hexColor(d.foreground), formatFloat(fl.border))
+
+	// 去掉XML声明，并在根元素上加上位置
+	inner := qrSVG
+	if i := bytes.Index(inner, []byte("<svg ")); i >= 0 {
+		inner = inner[i:]
+	}
+	buf.WriteString(fmt.Sprintf(`<svg x="%d" y="%d" `, fl.qrX, fl.qrY))
+	buf.Write(bytes.TrimPrefix(inner, []byte("<svg ")))
+	if !bytes.HasSuffix(inner, []byte("\n")) {
+		buf.WriteString("\n")
+	}
+
+	if len(fl.lines) > 0 {
+		fmt.Fprintf(&buf, `<path class="caption" fill="%s" d="`, hexColor(d.foreground))
+		for _, line := range fl.lines {
+			if err := fnt.writeTextPath(&buf, line.text, (float64(fl.width)-line.width)/2, line.baseline, line.size); err != nil {
+				return nil, err
+			}
+		}
+		buf.WriteString(`"/>` + "\n")
+	}
+	buf.WriteString("</svg>\n")
+	return buf.Bytes(), nil
+}
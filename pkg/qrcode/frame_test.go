@@ -0,0 +1,171 @@
+package qrcode
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"image/png"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// testFont 加载测试用的中文字体，取自 GNU Unifont（SIL OFL 1.1，见 testdata/OFL.txt），只保留测试文字用到的字形
+func testFont(t *testing.T) *Font {
+	t.Helper()
+	data, err := os.ReadFile("testdata/unifont-subset.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	font, err := LoadFont(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return font
+}
+
+func TestRenderFramedIsScannable(t *testing.T) {
+	g := &Generator{font: testFont(t)}
+	style := &Style{Foreground: "#1A73E8", ModuleShape: ShapeRounded}
+	for _, position := range []string{CaptionBottom, CaptionTop} {
+		t.Run(position, func(t *testing.T) {
+			frame := &Frame{Title: "会员福利群", Caption: "扫码加入社群，领取新人专属优惠券和每周活动通知", Position: position}
+			data, err := g.RenderFramed(testContent, FormatPNG, DefaultOptions(), style, frame)
+			if err != nil {
+				t.Fatal(err)
+			}
+			img, err := png.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := decode(t, img); got != testContent {
+				t.Errorf("decoded %q, want %q", got, testContent)
+			}
+
+			// 二维码与四周留白相同，多出的高度是文字区域，应有前景色像素（避开描边）
+			b := img.Bounds()
+			if b.Dx() <= DefaultSize || b.Dy() <= b.Dx() {
+				t.Fatalf("framed image size = %dx%d", b.Dx(), b.Dy())
+			}
+			textTop, textBottom := b.Dx(), b.Dy()-10
+			if position == CaptionTop {
+				textTop, textBottom = 10, b.Dy()-b.Dx()
+			}
+			inked := 0
+			for y := textTop; y < textBottom; y++ {
+				for x := 10; x < b.Dx()-10; x++ {
+					if r, _, _, _ := img.At(x, y).RGBA(); r < 0x8000 {
+						inked++
+					}
+				}
+			}
+			if inked == 0 {
+				t.Error("caption text was not drawn")
+			}
+		})
+	}
+}
+
+func TestRenderFramedSVG(t *testing.T) {
+	g := &Generator{font: testFont(t)}
+	frame := &Frame{Title: "会员福利群", Caption: "扫码加入社群", Position: CaptionTop, Padding: 24}
+	data, err := g.RenderFramed(testContent, FormatSVG, DefaultOptions(), nil, frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		if _, err := decoder.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("invalid svg: %v", err)
+		}
+	}
+
+	// 嵌套的二维码与 Render 的输出相同
+	nested := regexp.MustCompile(`<svg x="(\d+)" y="(\d+)" `).FindSubmatch(data)
+	if nested == nil {
+		t.Fatal("missing nested qr code")
+	}
+	qrX, _ := strconv.Atoi(string(nested[1]))
+	qrY, _ := strconv.Atoi(string(nested[2]))
+	if qrX != frame.Padding {
+		t.Errorf("qr x = %d, want padding %d", qrX, frame.Padding)
+	}
+	qr, _ := qrcode.New(testContent, qrcode.Medium)
+	qr.DisableBorder = true
+	modules := len(qr.Bitmap()) + 2*DefaultMargin
+	if got := decode(t, rasterize(t, data, svgRunPattern, modules, false)); got != testContent {
+		t.Errorf("decoded %q, want %q", got, testContent)
+	}
+
+	// 文字轮廓应位于二维码上方
+	text := regexp.MustCompile(`<path class="caption" fill="#000000" d="(M[^"]+)"`).FindSubmatch(data)
+	if text == nil {
+		t.Fatal("missing caption outlines")
+	}
+	coords := regexp.MustCompile(`(-?[\d.]+) (-?[\d.]+)`).FindAllSubmatch(text[1], -1)
+	for _, c := range coords {
+		x, _ := strconv.ParseFloat(string(c[1]), 64)
+		y, _ := strconv.ParseFloat(string(c[2]), 64)
+		if x < 0 || x > float64(DefaultSize+2*frame.Padding) || y < 0 || y > float64(qrY) {
+			t.Fatalf("glyph point (%v, %v) outside the caption area above y=%d", x, y, qrY)
+		}
+	}
+}
+
+func TestRenderFramedRejectsInvalidFrames(t *testing.T) {
+	tests := map[string]struct {
+		frame  *Frame
+		format Format
+	}{
+		"pdf":      {&Frame{Caption: "扫码"}, FormatPDF},
+		"position": {&Frame{Caption: "扫码", Position: "left"}, FormatPNG},
+		"padding":  {&Frame{Caption: "扫码", Padding: -1}, FormatPNG},
+		"caption":  {&Frame{Caption: strings.Repeat("扫", MaxCaptionLength+1)}, FormatPNG},
+		"glyph":    {&Frame{Caption: "扫码"}, FormatPNG}, // 内置字体不包含中文字形
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := (&Generator{}).RenderFramed(testContent, tt.format, DefaultOptions(), nil, tt.frame)
+			var designErr *DesignError
+			if !errors.As(err, &designErr) {
+				t.Fatalf("expected DesignError, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadFont(t *testing.T) {
+	if _, err := LoadFont([]byte("not a font")); err == nil {
+		t.Error("invalid font data should be rejected")
+	}
+
+	// 更换字体后缓存的图片不再使用
+	g := NewGenerator(t.TempDir())
+	frame := &Frame{Caption: "Scan to join"}
+	first, err := g.RenderFramed(testContent, FormatPNG, DefaultOptions(), nil, frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.SetFont(testFont(t))
+	second, _ := g.RenderFramed(testContent, FormatPNG, DefaultOptions(), nil, frame)
+	if &first[0] == &second[0] {
+		t.Error("changing the font should not reuse cached images")
+	}
+}
+
+func TestFrameValidateCaptionLength(t *testing.T) {
+	// 按字数而不是字节数限制
+	if err := (&Frame{Caption: strings.Repeat("扫", MaxCaptionLength)}).Validate(); err != nil {
+		t.Errorf("caption of %d runes rejected: %v", MaxCaptionLength, err)
+	}
+	if err := (&Frame{Caption: strings.Repeat("a", MaxCaptionLength+1)}).Validate(); err == nil {
+		t.Errorf("caption of %d runes accepted", MaxCaptionLength+1)
+	}
+}
@@ -6,11 +6,13 @@ import (
 	"os"
 	"path/filepath"
 	"time"
-	"github.com/skip2/go-qrcode"
 )
 
 type Generator struct {
 	StoragePath string
+	Defaults    Options // 默认图片参数，为空时使用 DefaultOptions()
+	cache       *Cache
+	font        *Font   // 边框文字字体，为空时使用内置字体
 }
 
 func NewGenerator(storagePath string) *Generator {
@@ -21,13 +23,15 @@ func NewGenerator(storagePath string) *Generator {
 	
 	return &Generator{
 		StoragePath: storagePath,
+		Defaults:    DefaultOptions(),
+		cache:       NewCache(DefaultCacheSize),
 	}
 }
 
 // GenerateQRCode 生成二维码并保存到文件
 func (g *Generator) GenerateQRCode(content string, filename string) (string, error) {
 	// 生成二维码
-	qr, err := qrcode.Encode(content, qrcode.Medium, 256)
+	qr, err := g.Render(content, FormatPNG, g.Options())
 	if err != nil {
 		return "", err
 	}
@@ -43,7 +47,7 @@ func (g *Generator) GenerateQRCode(content string, filename string) (string, err
 
 // GenerateQRCodeBase64 生成二维码并返回base64编码
 func (g *Generator) GenerateQRCodeBase64(content string) (string, error) {
-	qr, err := qrcode.Encode(content, qrcode.Medium, 256)
+	qr, err := g.Render(content, FormatPNG, g.Options())
 	if err != nil {
 		return "", err
 	}
@@ -71,4 +75,13 @@ func (g *Generator) DeleteQRCode(filename string) error {
 // ReadQRCodeFile 读取二维码文件
 func (g *Generator) ReadQRCodeFile(filePath string) ([]byte, error) {
 	return os.ReadFile(filePath)
-} 
\ No newline at end of file
+} 
+// SaveImage 保存上传的图片到二维码存储目录
+func (g *Generator) SaveImage(data []byte, filename string) (string, error) {
+	filePath := filepath.Join(g.StoragePath, filename)
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		return "", err
+	}
+
+	return filePath, nil
+}
@@ -0,0 +1,108 @@
+package qrcode
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// Level 纠错等级，等级越高可被遮挡（如加Logo）的面积越大，二维码也越密
+type Level string
+
+// 纠错等级，分别可恢复约7%、15%、25%、30%的数据
+const (
+	LevelL Level = "L"
+	LevelM Level = "M"
+	LevelQ Level = "Q"
+	LevelH Level = "H"
+)
+
+// 尺寸和静区的取值范围
+const (
+	MinSize       = 64
+	MaxSize       = 4096
+	MaxMargin     = 16
+	DefaultMargin = 4 // 二维码标准建议的静区宽度（模块数）
+)
+
+// Options 二维码图片参数
+type Options struct {
+	Size   int   // 图片边长，PNG 为像素，PDF/EPS 为点
+	Margin int   // 静区宽度（模块数）
+	Level  Level // 纠错等级
+}
+
+// DefaultOptions 返回默认参数：256像素、4模块静区、M级纠错
+func DefaultOptions() Options {
+	return Options{
+		Size:   DefaultSize,
+		Margin: DefaultMargin,
+		Level:  LevelM,
+	}
+}
+
+// ParseLevel 解析纠错等级，不区分大小写
+func ParseLevel(s string) (Level, error) {
+	switch Level(strings.ToUpper(strings.TrimSpace(s))) {
+	case LevelL:
+		return LevelL, nil
+	case LevelM:
+		return LevelM, nil
+	case LevelQ:
+		return LevelQ, nil
+	case LevelH:
+		return LevelH, nil
+	}
+	return "", fmt.Errorf("不支持的纠错等级: %s，可选值: L, M, Q, H", s)
+}
+
+// Override 在当前参数上覆盖非空的设置，margin 为 nil 时保持不变（0 表示无静区）
+func (o Options) Override(size int, margin *int, level string) (Options, error) {
+	if size != 0 {
+		o.Size = size
+	}
+	if margin != nil {
+		o.Margin = *margin
+	}
+	if strings.TrimSpace(level) != "" {
+		parsed, err := ParseLevel(level)
+		if err != nil {
+			return o, err
+		}
+		o.Level = parsed
+	}
+	return o, o.Validate()
+}
+
+// Validate 校验参数范围
+func (o Options) Validate() error {
+	if o.Size < MinSize || o.Size > MaxSize {
+		return fmt.Errorf("图片尺寸应在 %d 到 %d 之间", MinSize, MaxSize)
+	}
+	if o.Margin < 0 || o.Margin > MaxMargin {
+		return fmt.Errorf("静区宽度应在 0 到 %d 个模块之间", MaxMargin)
+	}
+	if _, err := ParseLevel(string(o.Level)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// recoveryLevel 转换为 go-qrcode 的纠错等级
+func (l Level) recoveryLevel() qrcode.RecoveryLevel {
+	switch l {
+	case LevelL:
+		return qrcode.Low
+	case LevelQ:
+		return qrcode.High
+	case LevelH:
+		return qrcode.Highest
+	}
+	return qrcode.Medium
+}
+
+// cacheKey 同一内容、格式和参数生成的图片相同，可以复用
+func (o Options) cacheKey(content string, format Format) string {
+	return fmt.Sprintf("%s|%d|%d|%s|%s", format.Extension(), o.Size, o.Margin, o.Level, content)
+}
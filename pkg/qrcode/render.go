@@ -0,0 +1,233 @@
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// DefaultSize 默认输出尺寸，PNG 为像素，SVG 为 CSS 像素，PDF/EPS 为点（1/72英寸）
+const DefaultSize = 256
+
+// run 二维码一行中连续的深色模块，矢量格式按行合并为矩形以减小文件体积
+type run struct {
+	x, y, width int
+}
+
+// Options 返回生成器的默认图片参数
+func (g *Generator) Options() Options {
+	if g.Defaults == (Options{}) {
+		return DefaultOptions()
+	}
+	return g.Defaults
+}
+
+// Configure 设置默认图片参数和缓存容量，cacheSize 小于0时不缓存
+func (g *Generator) Configure(defaults Options, cacheSize int) error {
+	if err := defaults.Validate(); err != nil {
+		return err
+	}
+	g.Defaults = defaults
+	g.cache = nil
+	if cacheSize >= 0 {
+		g.cache = NewCache(cacheSize)
+	}
+	return nil
+}
+
+// Invalidate 清除内容为 content 的缓存图片，样式变化后调用以便重新生成
+func (g *Generator) Invalidate(content string) {
+	if g.cache != nil {
+		g.cache.RemoveContent(content)
+	}
+}
+
+// Render 按指定格式和参数生成二维码，所有格式使用同一个二维码矩阵
+//
+// 相同内容和参数的结果会被缓存，返回的数据在调用方之间共享，不可修改。
+func (g *Generator) Render(content string, format Format, opts Options) ([]byte, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	key := opts.cacheKey(content, format)
+	if g.cache != nil {
+		if data, ok := g.cache.Get(key); ok {
+			return data, nil
+		}
+	}
+
+	qr, err := qrcode.New(content, opts.Level.recoveryLevel())
+	if err != nil {
+		return nil, err
+	}
+	qr.DisableBorder = true
+	bitmap := withMargin(qr.Bitmap(), opts.Margin)
+
+	var data []byte
+	switch format {
+	case "", FormatPNG:
+		data, err = renderPNG(bitmap, opts.Size)
+	case FormatSVG:
+		data = renderSVG(bitmap, opts.Size)
+	case FormatPDF:
+		data = renderPDF(bitmap, opts.Size)
+	case FormatEPS:
+		data = renderEPS(bitmap, opts.Size)
+	default:
+		err = fmt.Errorf("不支持的图片格式: %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if g.cache != nil {
+		g.cache.Put(key, content, data)
+	}
+	return data, nil
+}
+
+// withMargin 在矩阵四周加上指定宽度的静区
+func withMargin(bitmap [][]bool, margin int) [][]bool {
+	n := len(bitmap) + 2*margin
+	padded := make([][]bool, n)
+	for y := range padded {
+		padded[y] = make([]bool, n)
+		if y >= margin && y < n-margin {
+			copy(padded[y][margin:], bitmap[y-margin])
+		}
+	}
+	return padded
+}
+
+// renderPNG 生成PNG，每个模块使用相同的整数像素宽度以保证清晰，
+// 剩余的像素平均分布在四周；尺寸小于模块数时按每模块1像素输出
+func renderPNG(bitmap [][]bool, size int) ([]byte, error) {
+	n := len(bitmap)
+	if size < n {
+		size = n
+	}
+	scale := size / n
+	offset := (size - scale*n) / 2
+
+	img := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{color.White, color.Black})
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			for py := offset + y*scale; py < offset+(y+1)*scale; py++ {
+				for px := offset + x*scale; px < offset+(x+1)*scale; px++ {
+					img.SetColorIndex(px, py, 1)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// darkRuns 提取矩阵中每行连续的深色模块
+func darkRuns(bitmap [][]bool) []run {
+	var runs []run
+	for y, row := range bitmap {
+		for x := 0; x < len(row); {
+			if !row[x] {
+				x++
+				continue
+			}
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			runs = append(runs, run{x: start, y: y, width: x - start})
+		}
+	}
+	return runs
+}
+
+// renderSVG 生成SVG，viewBox 以模块为单位，缩放时保持清晰
+func renderSVG(bitmap [][]bool, size int) []byte {
+	n := len(bitmap)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<?xml version="1.0" encoding="UTF-8"?>`+"\n")
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" version="1.1" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n", size, size, n, n)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#FFFFFF"/>`+"\n", n, n)
+	buf.WriteString(`<path fill="#000000" d="`)
+	for _, r := range darkRuns(bitmap) {
+		fmt.Fprintf(&buf, "M%d %dh%dv1h-%dz", r.x, r.y, r.width, r.width)
+	}
+	buf.WriteString(`"/>` + "\n</svg>\n")
+	return buf.Bytes()
+}
+
+// renderPDF 生成单页PDF，页面尺寸为 size 点，内容流不压缩
+func renderPDF(bitmap [][]bool, size int) []byte {
+	n := len(bitmap)
+	scale := float64(size) / float64(n)
+
+	// PDF 坐标原点在左下角，需要翻转 y 轴
+	var content bytes.Buffer
+	fmt.Fprintf(&content, "1 1 1 rg\n0 0 %d %d re f\n", size, size)
+	fmt.Fprintf(&content, "%s 0 0 %s 0 0 cm\n0 0 0 rg\n", formatFloat(scale), formatFloat(scale))
+	for _, r := range darkRuns(bitmap) {
+		fmt.Fprintf(&content, "%d %d %d 1 re\n", r.x, n-r.y-1, r.width)
+	}
+	content.WriteString("f\n")
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Contents 4 0 R /Resources << >> >>", size, size),
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+// renderEPS 生成EPS，边界框为 size 点
+func renderEPS(bitmap [][]bool, size int) []byte {
+	n := len(bitmap)
+	scale := float64(size) / float64(n)
+
+	var buf bytes.Buffer
+	buf.WriteString("%!PS-Adobe-3.0 EPSF-3.0\n")
+	fmt.Fprintf(&buf, "%%%%BoundingBox: 0 0 %d %d\n", size, size)
+	buf.WriteString("%%Creator: wechat-active-qrcode\n%%EndComments\n")
+	buf.WriteString("gsave\n")
+	fmt.Fprintf(&buf, "1 setgray 0 0 %d %d rectfill\n", size, size)
+	fmt.Fprintf(&buf, "%s %s scale\n0 setgray\n", formatFloat(scale), formatFloat(scale))
+	for _, r := range darkRuns(bitmap) {
+		fmt.Fprintf(&buf, "%d %d %d 1 rectfill\n", r.x, n-r.y-1, r.width)
+	}
+	buf.WriteString("grestore\nshowpage\n%%EOF\n")
+	return buf.Bytes()
+}
+
+// formatFloat 输出最多6位小数的数字，PDF 和 PostScript 不支持科学计数法
+func formatFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'f', 6, 64)
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+}
@@ -0,0 +1,283 @@
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/skip2/go-qrcode"
+)
+
+const testContent = "https://example.com/r/AbC123?src=poster"
+
+// modulePixels 光栅化时每个模块的像素数
+const modulePixels = 8
+
+var (
+	svgRunPattern = regexp.MustCompile(`M(\d+) (\d+)h(\d+)v1h-\d+z`)
+	pdfRunPattern = regexp.MustCompile(`(?m)^(\d+) (\d+) (\d+) 1 re$`)
+	epsRunPattern = regexp.MustCompile(`(?m)^(\d+) (\d+) (\d+) 1 rectfill$`)
+)
+
+// rasterize 把矢量输出中的矩形画到位图上，flipY 表示坐标原点在左下角（PDF/EPS）
+func rasterize(t *testing.T, data []byte, pattern *regexp.Regexp, modules int, flipY bool) image.Image {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, modules*modulePixels, modules*modulePixels))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+
+	matches := pattern.FindAllSubmatch(data, -1)
+	if len(matches) == 0 {
+		t.Fatal("no dark modules found in output")
+	}
+	for _, m := range matches {
+		x, _ := strconv.Atoi(string(m[1]))
+		y, _ := strconv.Atoi(string(m[2]))
+		width, _ := strconv.Atoi(string(m[3]))
+		if flipY {
+			y = modules - y - 1
+		}
+		for px := x * modulePixels; px < (x+width)*modulePixels; px++ {
+			for py := y * modulePixels; py < (y+1)*modulePixels; py++ {
+				img.SetGray(px, py, color.Gray{Y: 0})
+			}
+		}
+	}
+	return img
+}
+
+// decode 使用 Parser 解析光栅化后的图片
+func decode(t *testing.T, img image.Image) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	text, err := NewParser().ParseFromReader(&buf)
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	return text
+}
+
+func TestRenderFormatsAreScannable(t *testing.T) {
+	qr, err := qrcode.New(testContent, qrcode.Medium)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modules := len(qr.Bitmap())
+	g := &Generator{}
+
+	tests := []struct {
+		format  Format
+		prefix  string
+		pattern *regexp.Regexp
+		flipY   bool
+	}{
+		{FormatSVG, "<?xml", svgRunPattern, false},
+		{FormatPDF, "%PDF-1.4", pdfRunPattern, true},
+		{FormatEPS, "%!PS-Adobe-3.0 EPSF-3.0", epsRunPattern, true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			data, err := g.Render(testContent, tt.format, DefaultOptions())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(data, []byte(tt.prefix)) {
+				t.Fatalf("output should start with %q", tt.prefix)
+			}
+			img := rasterize(t, data, tt.pattern, modules, tt.flipY)
+			if got := decode(t, img); got != testContent {
+				t.Errorf("decoded %q, want %q", got, testContent)
+			}
+		})
+	}
+
+	t.Run("png", func(t *testing.T) {
+		data, err := g.Render(testContent, FormatPNG, DefaultOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+		text, err := NewParser().ParseFromReader(bytes.NewReader(data))
+		if err != nil || text != testContent {
+			t.Errorf("decoded %q (%v), want %q", text, err, testContent)
+		}
+	})
+}
+
+func TestRenderPDFCrossReference(t *testing.T) {
+	data, err := (&Generator{}).Render(testContent, FormatPDF, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(data)
+	idx := strings.LastIndex(s, "startxref\n")
+	if idx < 0 {
+		t.Fatal("missing startxref")
+	}
+	offset, err := strconv.Atoi(strings.Fields(s[idx+len("startxref\n"):])[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(s[offset:], "xref\n") {
+		t.Errorf("startxref %d does not point to xref table", offset)
+	}
+
+	// 每个对象的偏移量都应指向 "n 0 obj"
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllStringSubmatch(s, -1)
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(entry[1])
+		if want := strconv.Itoa(i+1) + " 0 obj"; !strings.HasPrefix(s[offset:], want) {
+			t.Errorf("xref entry %d points to %q, want %q", i+1, s[offset:offset+8], want)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Format
+		ok   bool
+	}{
+		{"", FormatPNG, true},
+		{"PNG", FormatPNG, true},
+		{" svg ", FormatSVG, true},
+		{"pdf", FormatPDF, true},
+		{"eps", FormatEPS, true},
+		{"gif", "", false},
+	}
+	for _, tt := range tests {
+		got, err := ParseFormat(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseFormat(%q) = %q, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestRenderOptions(t *testing.T) {
+	g := &Generator{}
+	for _, level := range []Level{LevelL, LevelM, LevelQ, LevelH} {
+		for _, margin := range []int{1, 4, 10} {
+			for _, size := range []int{MinSize * 2, 500} {
+				opts := Options{Size: size, Margin: margin, Level: level}
+				name := fmt.Sprintf("%s/margin%d/size%d", level, margin, size)
+				t.Run(name, func(t *testing.T) {
+					data, err := g.Render(testContent, FormatPNG, opts)
+					if err != nil {
+						t.Fatal(err)
+					}
+					img, err := png.Decode(bytes.NewReader(data))
+					if err != nil {
+						t.Fatal(err)
+					}
+					if b := img.Bounds(); b.Dx() != size || b.Dy() != size {
+						t.Errorf("image size = %dx%d, want %d", b.Dx(), b.Dy(), size)
+					}
+					if got := decode(t, img); got != testContent {
+						t.Errorf("decoded %q, want %q", got, testContent)
+					}
+
+					// 矢量输出使用同一矩阵，静区和纠错等级同样生效
+					qr, _ := qrcode.New(testContent, level.recoveryLevel())
+					qr.DisableBorder = true
+					modules := len(qr.Bitmap()) + 2*margin
+					svg, err := g.Render(testContent, FormatSVG, opts)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if got := decode(t, rasterize(t, svg, svgRunPattern, modules, false)); got != testContent {
+						t.Errorf("svg decoded %q, want %q", got, testContent)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestRenderRejectsInvalidOptions(t *testing.T) {
+	g := &Generator{}
+	invalid := []Options{
+		{Size: MinSize - 1, Margin: 4, Level: LevelM},
+		{Size: MaxSize + 1, Margin: 4, Level: LevelM},
+		{Size: 256, Margin: -1, Level: LevelM},
+		{Size: 256, Margin: MaxMargin + 1, Level: LevelM},
+		{Size: 256, Margin: 4, Level: "X"},
+	}
+	for _, opts := range invalid {
+		if _, err := g.Render(testContent, FormatPNG, opts); err == nil {
+			t.Errorf("Render(%+v) should fail", opts)
+		}
+	}
+}
+
+func TestOptionsOverride(t *testing.T) {
+	zero := 0
+	opts, err := DefaultOptions().Override(512, &zero, "h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts != (Options{Size: 512, Margin: 0, Level: LevelH}) {
+		t.Errorf("Override = %+v", opts)
+	}
+
+	opts, err = DefaultOptions().Override(0, nil, "")
+	if err != nil || opts != DefaultOptions() {
+		t.Errorf("empty Override = %+v, %v", opts, err)
+	}
+
+	if _, err := DefaultOptions().Override(10, nil, ""); err == nil {
+		t.Error("size below minimum should fail")
+	}
+}
+
+func TestRenderCache(t *testing.T) {
+	g := NewGenerator(t.TempDir())
+	if err := g.Configure(DefaultOptions(), 2); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := g.Render(testContent, FormatSVG, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := g.Render(testContent, FormatSVG, DefaultOptions())
+	if &first[0] != &second[0] {
+		t.Error("same parameters should be served from cache")
+	}
+
+	large := DefaultOptions()
+	large.Size = 1024
+	g.Render(testContent, FormatSVG, large)
+	g.Render(testContent, FormatPNG, large)
+	if n := g.cache.Len(); n != 2 {
+		t.Errorf("cache len = %d, want 2", n)
+	}
+	if _, ok := g.cache.Get(DefaultOptions().cacheKey(testContent, FormatSVG)); ok {
+		t.Error("least recently used entry should be evicted")
+	}
+}
+
+func TestRenderCacheInvalidate(t *testing.T) {
+	g := NewGenerator(t.TempDir())
+	other := testContent + "&v=2"
+	g.Render(testContent, FormatPNG, DefaultOptions())
+	g.Render(testContent, FormatSVG, DefaultOptions())
+	g.RenderStyled(testContent, FormatPNG, DefaultOptions(), &Style{Foreground: "#1A73E8"})
+	g.Render(other, FormatPNG, DefaultOptions())
+
+	g.Invalidate(testContent)
+	if n := g.cache.Len(); n != 1 {
+		t.Errorf("cache len = %d, want 1", n)
+	}
+	if _, ok := g.cache.Get(DefaultOptions().cacheKey(other, FormatPNG)); !ok {
+		t.Error("other content should stay cached")
+	}
+}
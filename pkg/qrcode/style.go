@@ -0,0 +1,243 @@
+package qrcode
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 模块形状
+const (
+	ShapeSquare  = "square"
+	ShapeRounded = "rounded" // 圆角，相邻模块连成一体
+	ShapeDot     = "dot"     // 圆点
+	ShapeCircle  = "circle"  // 仅用于定位点
+)
+
+// 渐变类型
+const (
+	GradientLinear = "linear" // 左上到右下
+	GradientRadial = "radial" // 中心向外
+)
+
+// 品牌样式的取值范围
+const (
+	DefaultLogoScale = 0.2
+	MaxLogoScale     = 0.3 // Logo 遮挡的面积需在H级纠错能力范围内
+	MinContrast      = 3.0 // 前景色与背景色的最小对比度
+)
+
+// Style 品牌二维码样式，零值表示黑白方形的标准二维码
+type Style struct {
+	Foreground   string  `json:"foreground,omitempty"`    // 前景色，如 #1A73E8，默认黑色
+	Background   string  `json:"background,omitempty"`    // 背景色，默认白色
+	GradientEnd  string  `json:"gradient_end,omitempty"`  // 渐变终止色，设置后前景色从 Foreground 渐变到该颜色
+	GradientType string  `json:"gradient_type,omitempty"` // linear（默认）或 radial
+	ModuleShape  string  `json:"module_shape,omitempty"`  // square（默认）、rounded、dot
+	FinderShape  string  `json:"finder_shape,omitempty"`  // 定位点样式: square（默认）、rounded、circle
+	FinderColor  string  `json:"finder_color,omitempty"`  // 定位点颜色，默认与前景色相同
+	LogoScale    float64 `json:"logo_scale,omitempty"`    // Logo 边长占码区的比例，默认0.2，最大0.3
+	Logo         []byte  `json:"-"`                       // 居中显示的Logo（PNG或JPEG）
+	LogoFile     string  `json:"-"`                       // Logo 文件路径，Logo 为空时在未命中缓存时才读取
+}
+
+// DesignError 样式配置错误或设计无法被识别
+type DesignError struct {
+	Msg string
+}
+
+func (e *DesignError) Error() string {
+	return e.Msg
+}
+
+func designErrorf(format string, args ...interface{}) error {
+	return &DesignError{Msg: fmt.Sprintf(format, args...)}
+}
+
+// design 解析后的样式
+type design struct {
+	foreground  color.RGBA
+	background  color.RGBA
+	gradientEnd *color.RGBA
+	radial      bool
+	moduleShape string
+	finderShape string
+	finderColor *color.RGBA
+	logo        image.Image
+	logoData    []byte
+	logoScale   float64
+}
+
+// IsZero 判断是否为标准样式
+func (s *Style) IsZero() bool {
+	return s == nil || (s.Foreground == "" && s.Background == "" && s.GradientEnd == "" &&
+		s.GradientType == "" && s.ModuleShape == "" && s.FinderShape == "" &&
+		s.FinderColor == "" && s.LogoScale == 0 && !s.hasLogo())
+}
+
+// hasLogo 判断是否设置了Logo
+func (s *Style) hasLogo() bool {
+	return len(s.Logo) > 0 || s.LogoFile != ""
+}
+
+// Validate 校验样式配置（不包括能否被识别，需渲染后才能确定）
+func (s *Style) Validate() error {
+	_, err := s.compile()
+	return err
+}
+
+// key 样式的缓存键，Logo 使用内容摘要，Logo 文件使用路径（上传的Logo文件名唯一，替换时使用新文件）
+func (s *Style) key() string {
+	logoSum := ""
+	if len(s.Logo) > 0 {
+		sum := sha256.Sum256(s.Logo)
+		logoSum = hex.EncodeToString(sum[:8])
+	} else if s.LogoFile != "" {
+		logoSum = "file:" + s.LogoFile
+	}
+	return strings.Join([]string{s.Foreground, s.Background, s.GradientEnd, s.GradientType,
+		s.ModuleShape, s.FinderShape, s.FinderColor, strconv.FormatFloat(s.LogoScale, 'f', -1, 64), logoSum}, "|")
+}
+
+// compile 解析颜色、形状和Logo，并检查颜色对比度
+func (s *Style) compile() (*design, error) {
+	d := &design{
+		foreground:  color.RGBA{A: 0xFF},
+		background:  color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF},
+		moduleShape: ShapeSquare,
+		finderShape: ShapeSquare,
+		logoScale:   DefaultLogoScale,
+	}
+
+	var err error
+	if s.Foreground != "" {
+		if d.foreground, err = parseHexColor(s.Foreground); err != nil {
+			return nil, designErrorf("前景色%v", err)
+		}
+	}
+	if s.Background != "" {
+		if d.background, err = parseHexColor(s.Background); err != nil {
+			return nil, designErrorf("背景色%v", err)
+		}
+	}
+	if s.GradientEnd != "" {
+		end, err := parseHexColor(s.GradientEnd)
+		if err != nil {
+			return nil, designErrorf("渐变终止色%v", err)
+		}
+		d.gradientEnd = &end
+	}
+	switch s.GradientType {
+	case "", GradientLinear:
+	case GradientRadial:
+		d.radial = true
+	default:
+		return nil, designErrorf("不支持的渐变类型: %s，可选值: linear, radial", s.GradientType)
+	}
+	if s.FinderColor != "" {
+		finder, err := parseHexColor(s.FinderColor)
+		if err != nil {
+			return nil, designErrorf("定位点颜色%v", err)
+		}
+		d.finderColor = &finder
+	}
+
+	switch s.ModuleShape {
+	case "", ShapeSquare:
+	case ShapeRounded, ShapeDot:
+		d.moduleShape = s.ModuleShape
+	default:
+		return nil, designErrorf("不支持的模块形状: %s，可选值: square, rounded, dot", s.ModuleShape)
+	}
+	switch s.FinderShape {
+	case "", ShapeSquare:
+	case ShapeRounded, ShapeCircle:
+		d.finderShape = s.FinderShape
+	default:
+		return nil, designErrorf("不支持的定位点样式: %s，可选值: square, rounded, circle", s.FinderShape)
+	}
+
+	if s.LogoScale != 0 {
+		if s.LogoScale < 0 || s.LogoScale > MaxLogoScale {
+			return nil, designErrorf("Logo比例应在 0 到 %.1f 之间", MaxLogoScale)
+		}
+		d.logoScale = s.LogoScale
+	}
+	logoData := s.Logo
+	if len(logoData) == 0 && s.LogoFile != "" {
+		if logoData, err = os.ReadFile(s.LogoFile); err != nil {
+			return nil, fmt.Errorf("failed to read logo: %v", err)
+		}
+	}
+	if len(logoData) > 0 {
+		logo, _, err := image.Decode(bytes.NewReader(logoData))
+		if err != nil {
+			return nil, designErrorf("Logo图片无法解析，仅支持PNG或JPEG: %v", err)
+		}
+		d.logo = logo
+		d.logoData = logoData
+	}
+
+	// 深色前景、浅色背景才能被大多数扫码器识别
+	type colorCheck struct {
+		name string
+		c    color.RGBA
+	}
+	checks := []colorCheck{{"前景色", d.foreground}}
+	if d.gradientEnd != nil {
+		checks = append(checks, colorCheck{"渐变终止色", *d.gradientEnd})
+	}
+	if d.finderColor != nil {
+		checks = append(checks, colorCheck{"定位点颜色", *d.finderColor})
+	}
+	for _, check := range checks {
+		fg, bg := luminance(check.c), luminance(d.background)
+		if fg >= bg {
+			return nil, designErrorf("%s应比背景色深，否则无法被识别", check.name)
+		}
+		if ratio := (bg + 0.05) / (fg + 0.05); ratio < MinContrast {
+			return nil, designErrorf("%s与背景色的对比度为 %.1f:1，至少需要 %.0f:1", check.name, ratio, MinContrast)
+		}
+	}
+	return d, nil
+}
+
+// parseHexColor 解析 #RGB 或 #RRGGBB 格式的颜色
+func parseHexColor(s string) (color.RGBA, error) {
+	hexStr := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(hexStr) == 3 {
+		hexStr = string([]byte{hexStr[0], hexStr[0], hexStr[1], hexStr[1], hexStr[2], hexStr[2]})
+	}
+	if len(hexStr) != 6 {
+		return color.RGBA{}, fmt.Errorf("格式错误: %s，应为 #RRGGBB", s)
+	}
+	v, err := strconv.ParseUint(hexStr, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("格式错误: %s，应为 #RRGGBB", s)
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xFF}, nil
+}
+
+// hexColor 格式化为 #RRGGBB
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B)
+}
+
+// luminance 计算相对亮度（WCAG 2.0）
+func luminance(c color.RGBA) float64 {
+	channel := func(v uint8) float64 {
+		f := float64(v) / 255
+		if f <= 0.03928 {
+			return f / 12.92
+		}
+		return math.Pow((f+0.055)/1.055, 2.4)
+	}
+	return 0.2126*channel(c.R) + 0.7152*channel(c.G) + 0.0722*channel(c.B)
+}
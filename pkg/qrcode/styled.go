@@ -0,0 +1,422 @@
+package qrcode
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"net/http"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// minCheckModulePixels 校验能否识别时每个模块至少使用的像素数
+const minCheckModulePixels = 4
+
+// layout 二维码矩阵的布局：静区、定位点和Logo区域（单位均为模块）
+type layout struct {
+	bitmap  [][]bool
+	n       int       // 含静区的边长
+	finders [3][2]int // 三个定位点左上角坐标
+	clear   [4]int    // Logo 区域 x0, y0, x1, y1（不含 x1, y1），该区域内不绘制模块
+	logo    [4]float64
+}
+
+// newLayout 计算布局，logoScale 为0表示没有Logo
+func newLayout(bitmap [][]bool, margin int, logoScale float64) *layout {
+	n := len(bitmap)
+	code := n - 2*margin
+	l := &layout{
+		bitmap: bitmap,
+		n:      n,
+		finders: [3][2]int{
+			{margin, margin},
+			{margin + code - 7, margin},
+			{margin, margin + code - 7},
+		},
+	}
+	if logoScale > 0 {
+		// Logo 四周留出1个模块的空白，区域与码区同奇偶以保证居中
+		side := int(math.Ceil(float64(code)*logoScale)) + 2
+		if side%2 != code%2 {
+			side++
+		}
+		start := margin + (code-side)/2
+		l.clear = [4]int{start, start, start + side, start + side}
+		l.logo = [4]float64{float64(start + 1), float64(start + 1), float64(side - 2), float64(side - 2)}
+	}
+	return l
+}
+
+// isFinder 判断模块是否属于定位点
+func (l *layout) isFinder(x, y int) bool {
+	for _, f := range l.finders {
+		if x >= f[0] && x < f[0]+7 && y >= f[1] && y < f[1]+7 {
+			return true
+		}
+	}
+	return false
+}
+
+// isCleared 判断模块是否位于Logo区域
+func (l *layout) isCleared(x, y int) bool {
+	return x >= l.clear[0] && x < l.clear[2] && y >= l.clear[1] && y < l.clear[3]
+}
+
+// dark 判断需要按模块形状绘制的深色模块，定位点和Logo区域单独处理
+func (l *layout) dark(x, y int) bool {
+	if x < 0 || y < 0 || x >= l.n || y >= l.n {
+		return false
+	}
+	return l.bitmap[y][x] && !l.isFinder(x, y) && !l.isCleared(x, y)
+}
+
+// RenderStyled 按品牌样式生成二维码，样式为空时等同于 Render
+//
+// 设置Logo时自动使用H级纠错。生成后使用 Parser 识别，无法识别的设计返回 *DesignError。
+// 品牌样式支持 PNG 和 SVG 格式。
+func (g *Generator) RenderStyled(content string, format Format, opts Options, style *Style) ([]byte, error) {
+	if style.IsZero() {
+		return g.Render(content, format, opts)
+	}
+	if format != "" && format != FormatPNG && format != FormatSVG {
+		return nil, designErrorf("品牌样式仅支持 PNG 和 SVG 格式")
+	}
+	if style.hasLogo() {
+		opts.Level = LevelH
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	// 先查缓存，命中时不需要读取和解析Logo
+	key := opts.cacheKey(content, format) + "|" + style.key()
+	if g.cache != nil {
+		if data, ok := g.cache.Get(key); ok {
+			return data, nil
+		}
+	}
+	d, err := style.compile()
+	if err != nil {
+		return nil, err
+	}
+
+	qr, err := qrcode.New(content, opts.Level.recoveryLevel())
+	if err != nil {
+		return nil, err
+	}
+	qr.DisableBorder = true
+	logoScale := 0.0
+	if d.logo != nil {
+		logoScale = d.logoScale
+	}
+	l := newLayout(withMargin(qr.Bitmap(), opts.Margin), opts.Margin, logoScale)
+
+	// 确认设计能被识别，矢量格式按不低于输出尺寸的分辨率检查
+	checkSize := opts.Size
+	if format == FormatSVG && checkSize < l.n*minCheckModulePixels {
+		checkSize = l.n * minCheckModulePixels
+	}
+	img := d.rasterize(l, checkSize)
+	if text, err := NewParser().parseFromImage(img); err != nil || text != content {
+		return nil, designErrorf("该设计无法被识别，请提高颜色对比度、缩小Logo或使用方形模块")
+	}
+
+	var data []byte
+	if format == FormatSVG {
+		data = d.renderSVG(l, opts.Size)
+	} else {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+		data = buf.Bytes()
+	}
+
+	if g.cache != nil {
+		g.cache.Put(key, content, data)
+	}
+	return data, nil
+}
+
+// rasterize 绘制位图，每个模块使用相同的整数像素宽度，剩余像素平均分布在四周
+func (d *design) rasterize(l *layout, size int) *image.RGBA {
+	if size < l.n {
+		size = l.n
+	}
+	scale := size / l.n
+	offset := (size - scale*l.n) / 2
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: d.background}, image.Point{}, draw.Src)
+
+	fill := func(x0, y0, w, h int, inside func(u, v float64) bool, override *color.RGBA) {
+		for py := y0; py < y0+h; py++ {
+			for px := x0; px < x0+w; px++ {
+				if !inside((float64(px-x0)+0.5)/float64(scale), (float64(py-y0)+0.5)/float64(scale)) {
+					continue
+				}
+				c := d.foregroundAt(float64(px)/float64(size), float64(py)/float64(size))
+				if override != nil {
+					c = *override
+				}
+				img.SetRGBA(px, py, c)
+			}
+		}
+	}
+
+	for y := 0; y < l.n; y++ {
+		for x := 0; x < l.n; x++ {
+			if !l.dark(x, y) {
+				continue
+			}
+			x, y := x, y
+			fill(offset+x*scale, offset+y*scale, scale, scale, func(u, v float64) bool {
+				return d.moduleContains(l, x, y, u, v)
+			}, nil)
+		}
+	}
+	for _, f := range l.finders {
+		fill(offset+f[0]*scale, offset+f[1]*scale, 7*scale, 7*scale, d.finderContains, d.finderColor)
+	}
+
+	if d.logo != nil {
+		box := image.Rect(
+			offset+int(l.logo[0]*float64(scale)), offset+int(l.logo[1]*float64(scale)),
+			offset+int((l.logo[0]+l.logo[2])*float64(scale)), offset+int((l.logo[1]+l.logo[3])*float64(scale)),
+		)
+		drawLogo(img, d.logo, box)
+	}
+	return img
+}
+
+// foregroundAt 返回相对位置 (x, y)（0-1）处的前景色
+func (d *design) foregroundAt(x, y float64) color.RGBA {
+	if d.gradientEnd == nil {
+		return d.foreground
+	}
+	var t float64
+	if d.radial {
+		t = math.Hypot(x-0.5, y-0.5) / math.Sqrt2 * 2
+	} else {
+		t = (x + y) / 2
+	}
+	t = math.Max(0, math.Min(1, t))
+	lerp := func(a, b uint8) uint8 {
+		return uint8(math.Round(float64(a) + (float64(b)-float64(a))*t))
+	}
+	end := *d.gradientEnd
+	return color.RGBA{R: lerp(d.foreground.R, end.R), G: lerp(d.foreground.G, end.G), B: lerp(d.foreground.B, end.B), A: 0xFF}
+}
+
+// moduleContains 判断模块内的点 (u, v)（0-1）是否需要着色
+//
+// 圆角模块只在两侧相邻模块都为空的角上做圆角，连续的模块连成一体。
+func (d *design) moduleContains(l *layout, x, y int, u, v float64) bool {
+	switch d.moduleShape {
+	case ShapeDot:
+		return math.Hypot(u-0.5, v-0.5) <= 0.45
+	case ShapeRounded:
+		dx, dy := 1, 1
+		if u < 0.5 {
+			dx = -1
+		}
+		if v < 0.5 {
+			dy = -1
+		}
+		if l.dark(x+dx, y) || l.dark(x, y+dy) {
+			return true
+		}
+		return math.Hypot(u-0.5, v-0.5) <= 0.5
+	}
+	return true
+}
+
+// finderContains 判断定位点内的点 (u, v)（0-7）是否需要着色：外框、空白环和中心块
+func (d *design) finderContains(u, v float64) bool {
+	switch d.finderShape {
+	case ShapeRounded:
+		return (inRoundRect(u, v, 0, 0, 7, 7, 2) && !inRoundRect(u, v, 1, 1, 5, 5, 1.5)) ||
+			inRoundRect(u, v, 2, 2, 3, 3, 1)
+	case ShapeCircle:
+		r := math.Hypot(u-3.5, v-3.5)
+		return (r <= 3.5 && r >= 2.5) || r <= 1.5
+	}
+	outer := u >= 0 && u < 7 && v >= 0 && v < 7
+	ring := u >= 1 && u < 6 && v >= 1 && v < 6
+	center := u >= 2 && u < 5 && v >= 2 && v < 5
+	return (outer && !ring) || center
+}
+
+// inRoundRect 判断点是否在圆角矩形内
+func inRoundRect(u, v, x, y, w, h, r float64) bool {
+	if u < x || u > x+w || v < y || v > y+h {
+		return false
+	}
+	cx := math.Max(x+r, math.Min(u, x+w-r))
+	cy := math.Max(y+r, math.Min(v, y+h-r))
+	return math.Hypot(u-cx, v-cy) <= r
+}
+
+// drawLogo 把Logo按比例缩放（区域平均）后居中绘制到 box 内
+func drawLogo(dst *image.RGBA, logo image.Image, box image.Rectangle) {
+	src := logo.Bounds()
+	if src.Empty() || box.Empty() {
+		return
+	}
+	ratio := math.Min(float64(box.Dx())/float64(src.Dx()), float64(box.Dy())/float64(src.Dy()))
+	w := int(math.Max(1, math.Round(float64(src.Dx())*ratio)))
+	h := int(math.Max(1, math.Round(float64(src.Dy())*ratio)))
+
+	scaled := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		sy0 := src.Min.Y + y*src.Dy()/h
+		sy1 := int(math.Max(float64(sy0+1), float64(src.Min.Y+(y+1)*src.Dy()/h)))
+		for x := 0; x < w; x++ {
+			sx0 := src.Min.X + x*src.Dx()/w
+			sx1 := int(math.Max(float64(sx0+1), float64(src.Min.X+(x+1)*src.Dx()/w)))
+			var r, g, b, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					cr, cg, cb, ca := logo.At(sx, sy).RGBA()
+					r, g, b, a, n = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca), n+1
+				}
+			}
+			scaled.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)})
+		}
+	}
+
+	at := image.Pt(box.Min.X+(box.Dx()-w)/2, box.Min.Y+(box.Dy()-h)/2)
+	draw.Draw(dst, image.Rectangle{Min: at, Max: at.Add(image.Pt(w, h))}, scaled, image.Point{}, draw.Over)
+}
+
+// renderSVG 生成与位图相同几何形状的SVG，坐标以模块为单位
+func (d *design) renderSVG(l *layout, size int) []byte {
+	n := l.n
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" version="1.1" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", size, size, n, n)
+
+	fill := hexColor(d.foreground)
+	if d.gradientEnd != nil {
+		fill = "url(#fg)"
+		buf.WriteString("<defs>")
+		if d.radial {
+			fmt.Fprintf(&buf, `<radialGradient id="fg" gradientUnits="userSpaceOnUse" cx="%s" cy="%s" r="%s">`,
+				formatFloat(float64(n)/2), formatFloat(float64(n)/2), formatFloat(float64(n)/math.Sqrt2))
+		} else {
+			fmt.Fprintf(&buf, `<linearGradient id="fg" gradientUnits="userSpaceOnUse" x1="0" y1="0" x2="%d" y2="%d">`, n, n)
+		}
+		fmt.Fprintf(&buf, `<stop offset="0" stop-color="%s"/><stop offset="1" stop-color="%s"/>`, hexColor(d.foreground), hexColor(*d.gradientEnd))
+		if d.radial {
+			buf.WriteString("</radialGradient>")
+		} else {
+			buf.WriteString("</linearGradient>")
+		}
+		buf.WriteString("</defs>\n")
+	}
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="%s"/>`+"\n", n, n, hexColor(d.background))
+
+	// 数据模块
+	fmt.Fprintf(&buf, `<path fill="%s" d="`, fill)
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			if l.dark(x, y) {
+				d.writeModulePath(&buf, l, x, y)
+			}
+		}
+	}
+	buf.WriteString(`"/>` + "\n")
+
+	// 定位点，使用奇偶填充规则形成外框、空白环和中心块
+	finderFill := fill
+	if d.finderColor != nil {
+		finderFill = hexColor(*d.finderColor)
+	}
+	fmt.Fprintf(&buf, `<path fill="%s" fill-rule="evenodd" d="`, finderFill)
+	for _, f := range l.finders {
+		x, y := float64(f[0]), float64(f[1])
+		switch d.finderShape {
+		case ShapeRounded:
+			writeRoundRectPath(&buf, x, y, 7, 2)
+			writeRoundRectPath(&buf, x+1, y+1, 5, 1.5)
+			writeRoundRectPath(&buf, x+2, y+2, 3, 1)
+		case ShapeCircle:
+			writeCirclePath(&buf, x+3.5, y+3.5, 3.5)
+			writeCirclePath(&buf, x+3.5, y+3.5, 2.5)
+			writeCirclePath(&buf, x+3.5, y+3.5, 1.5)
+		default:
+			fmt.Fprintf(&buf, "M%d %dh7v7h-7zM%d %dh5v5h-5zM%d %dh3v3h-3z", f[0], f[1], f[0]+1, f[1]+1, f[0]+2, f[1]+2)
+		}
+	}
+	buf.WriteString(`"/>` + "\n")
+
+	if d.logo != nil {
+		fmt.Fprintf(&buf, `<image x="%s" y="%s" width="%s" height="%s" preserveAspectRatio="xMidYMid meet" xlink:href="data:%s;base64,%s"/>`+"\n",
+			formatFloat(l.logo[0]), formatFloat(l.logo[1]), formatFloat(l.logo[2]), formatFloat(l.logo[3]),
+			http.DetectContentType(d.logoData), base64.StdEncoding.EncodeToString(d.logoData))
+	}
+
+	buf.WriteString("</svg>\n")
+	return buf.Bytes()
+}
+
+// writeModulePath 输出单个模块的路径
+func (d *design) writeModulePath(buf *bytes.Buffer, l *layout, x, y int) {
+	switch d.moduleShape {
+	case ShapeDot:
+		writeCirclePath(buf, float64(x)+0.5, float64(y)+0.5, 0.45)
+	case ShapeRounded:
+		// 与 moduleContains 一致：两侧相邻模块都为空的角做半径0.5的圆角
+		tl := !l.dark(x-1, y) && !l.dark(x, y-1)
+		tr := !l.dark(x+1, y) && !l.dark(x, y-1)
+		br := !l.dark(x+1, y) && !l.dark(x, y+1)
+		bl := !l.dark(x-1, y) && !l.dark(x, y+1)
+		corner := func(rounded bool) float64 {
+			if rounded {
+				return 0.5
+			}
+			return 0
+		}
+		fx, fy := float64(x), float64(y)
+		fmt.Fprintf(buf, "M%s %s", formatFloat(fx+corner(tl)), formatFloat(fy))
+		fmt.Fprintf(buf, "H%s", formatFloat(fx+1-corner(tr)))
+		if tr {
+			buf.WriteString("a.5 .5 0 0 1 .5 .5")
+		}
+		fmt.Fprintf(buf, "V%s", formatFloat(fy+1-corner(br)))
+		if br {
+			buf.WriteString("a.5 .5 0 0 1 -.5 .5")
+		}
+		fmt.Fprintf(buf, "H%s", formatFloat(fx+corner(bl)))
+		if bl {
+			buf.WriteString("a.5 .5 0 0 1 -.5 -.5")
+		}
+		fmt.Fprintf(buf, "V%s", formatFloat(fy+corner(tl)))
+		if tl {
+			buf.WriteString("a.5 .5 0 0 1 .5 -.5")
+		}
+		buf.WriteString("z")
+	default:
+		fmt.Fprintf(buf, "M%d %dh1v1h-1z", x, y)
+	}
+}
+
+// writeRoundRectPath 输出正方形圆角矩形路径
+func writeRoundRectPath(buf *bytes.Buffer, x, y, side, r float64) {
+	edge := formatFloat(side - 2*r)
+	rs := formatFloat(r)
+	fmt.Fprintf(buf, "M%s %sh%sa%s %s 0 0 1 %s %sv%sa%s %s 0 0 1 -%s %sh-%sa%s %s 0 0 1 -%s -%sv-%sa%s %s 0 0 1 %s -%sz",
+		formatFloat(x+r), formatFloat(y), edge, rs, rs, rs, rs, edge, rs, rs, rs, rs, edge, rs, rs, rs, rs, edge, rs, rs, rs, rs)
+}
+
+// writeCirclePath 输出圆形路径
+func writeCirclePath(buf *bytes.Buffer, cx, cy, r float64) {
+	rs := formatFloat(r)
+	fmt.Fprintf(buf, "M%s %sa%s %s 0 1 0 %s 0a%s %s 0 1 0 -%s 0z",
+		formatFloat(cx-r), formatFloat(cy), rs, rs, formatFloat(2*r), rs, rs, formatFloat(2*r))
+}
@@ -0,0 +1,190 @@
+package qrcode
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// testLogo 生成一个带透明边缘的彩色Logo
+func testLogo(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if x < 2 || y < 2 || x >= w-2 || y >= h-2 {
+				continue
+			}
+			img.Set(x, y, color.RGBA{R: 0xE5, G: uint8(x * 255 / w), B: 0x30, A: 0xFF})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestRenderStyledIsScannable(t *testing.T) {
+	logo := testLogo(t, 120, 80)
+	styles := map[string]*Style{
+		"colors":          {Foreground: "#1A73E8", Background: "#FFF8E1"},
+		"linear gradient": {Foreground: "#0D47A1", GradientEnd: "#6A1B9A"},
+		"radial gradient": {Foreground: "#004D40", GradientEnd: "#1B5E20", GradientType: GradientRadial},
+		"rounded":         {ModuleShape: ShapeRounded, FinderShape: ShapeRounded},
+		"dots":            {ModuleShape: ShapeDot, FinderShape: ShapeCircle, FinderColor: "#C62828"},
+		"logo":            {Foreground: "#202124", Logo: logo},
+		"logo max scale":  {ModuleShape: ShapeRounded, Logo: logo, LogoScale: MaxLogoScale},
+	}
+
+	for name, style := range styles {
+		t.Run(name, func(t *testing.T) {
+			g := &Generator{}
+			data, err := g.RenderStyled(testContent, FormatPNG, DefaultOptions(), style)
+			if err != nil {
+				t.Fatal(err)
+			}
+			text, err := NewParser().ParseFromReader(bytes.NewReader(data))
+			if err != nil || text != testContent {
+				t.Errorf("decoded %q (%v), want %q", text, err, testContent)
+			}
+
+			svg, err := g.RenderStyled(testContent, FormatSVG, DefaultOptions(), style)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoder := xml.NewDecoder(bytes.NewReader(svg))
+			for {
+				if _, err := decoder.Token(); err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatalf("invalid svg: %v", err)
+				}
+			}
+			if len(style.Logo) > 0 && !strings.Contains(string(svg), "data:image/png;base64,") {
+				t.Error("svg should embed the logo")
+			}
+			if style.GradientEnd != "" && !strings.Contains(string(svg), `fill="url(#fg)"`) {
+				t.Error("svg should use the gradient")
+			}
+		})
+	}
+}
+
+func TestRenderStyledRaisesLevelWithLogo(t *testing.T) {
+	g := &Generator{}
+	plain, err := g.RenderStyled(testContent, FormatSVG, DefaultOptions(), &Style{Foreground: "#333333"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	withLogo, err := g.RenderStyled(testContent, FormatSVG, DefaultOptions(), &Style{Foreground: "#333333", Logo: testLogo(t, 32, 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Logo 会遮挡部分模块，必须使用H级纠错
+	qr, _ := qrcode.New(testContent, qrcode.Highest)
+	qr.DisableBorder = true
+	modules := len(qr.Bitmap()) + 2*DefaultMargin
+	viewBox := fmt.Sprintf(`viewBox="0 0 %d %d"`, modules, modules)
+	if !strings.Contains(string(withLogo), viewBox) {
+		t.Errorf("logo design should use level H (%s)", viewBox)
+	}
+	if strings.Contains(string(plain), viewBox) {
+		t.Error("design without logo should keep the requested level")
+	}
+}
+
+func TestRenderStyledRejectsBadDesigns(t *testing.T) {
+	tests := map[string]struct {
+		style  *Style
+		format Format
+		want   string
+	}{
+		"low contrast":   {&Style{Foreground: "#AAAAAA"}, FormatPNG, "对比度"},
+		"inverted":       {&Style{Foreground: "#FFFFFF", Background: "#000000"}, FormatPNG, "应比背景色深"},
+		"light gradient": {&Style{Foreground: "#000000", GradientEnd: "#EEEEEE"}, FormatPNG, "渐变终止色"},
+		"bad color":      {&Style{Foreground: "blue"}, FormatPNG, "格式错误"},
+		"bad shape":      {&Style{ModuleShape: "star"}, FormatPNG, "模块形状"},
+		"logo too large": {&Style{Logo: testLogo(t, 10, 10), LogoScale: 0.5}, FormatPNG, "Logo比例"},
+		"bad logo":       {&Style{Logo: []byte("not an image")}, FormatPNG, "Logo图片"},
+		"pdf":            {&Style{Foreground: "#1A73E8"}, FormatPDF, "PNG 和 SVG"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := (&Generator{}).RenderStyled(testContent, tt.format, DefaultOptions(), tt.style)
+			var designErr *DesignError
+			if !errors.As(err, &designErr) {
+				t.Fatalf("expected DesignError, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q should mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderStyledRejectsUnscannable(t *testing.T) {
+	// 内容较长、尺寸很小时圆点模块只剩一两个像素，扫码器无法识别
+	content := strings.Repeat(testContent, 6)
+	opts := Options{Size: 100, Margin: 0, Level: LevelL}
+	if _, err := (&Generator{}).Render(content, FormatPNG, opts); err != nil {
+		t.Fatal(err)
+	}
+	_, err := (&Generator{}).RenderStyled(content, FormatPNG, opts, &Style{ModuleShape: ShapeDot, FinderShape: ShapeCircle})
+	var designErr *DesignError
+	if !errors.As(err, &designErr) || !strings.Contains(err.Error(), "无法被识别") {
+		t.Fatalf("expected unscannable design error, got %v", err)
+	}
+}
+
+func TestRenderStyledLogoFileReadOnCacheMiss(t *testing.T) {
+	dir := t.TempDir()
+	g := NewGenerator(dir)
+	g.SetFont(testFont(t))
+	logoPath, err := g.SaveImage(testLogo(t, 32, 32), "logo.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	style := &Style{Foreground: "#202124", LogoFile: logoPath}
+	frame := &Frame{Title: "会员福利群"}
+
+	fromFile, err := g.RenderStyled(testContent, FormatPNG, DefaultOptions(), style)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.RenderFramed(testContent, FormatPNG, DefaultOptions(), style, frame); err != nil {
+		t.Fatal(err)
+	}
+	fromBytes, err := NewGenerator(dir).RenderStyled(testContent, FormatPNG, DefaultOptions(), &Style{Foreground: "#202124", Logo: testLogo(t, 32, 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(fromFile, fromBytes) {
+		t.Error("logo file and logo bytes should render the same image")
+	}
+
+	// 命中缓存时不再读取Logo文件
+	if err := os.Remove(logoPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.RenderStyled(testContent, FormatPNG, DefaultOptions(), style); err != nil {
+		t.Errorf("cached styled image should not read the logo: %v", err)
+	}
+	if _, err := g.RenderFramed(testContent, FormatPNG, DefaultOptions(), style, frame); err != nil {
+		t.Errorf("cached framed image should not read the logo: %v", err)
+	}
+	if _, err := g.RenderStyled(testContent, FormatSVG, DefaultOptions(), style); err == nil {
+		t.Error("cache miss with a missing logo file should fail")
+	}
+}
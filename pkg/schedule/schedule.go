@@ -0,0 +1,223 @@
+package schedule
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DateLayout 例外日期格式
+const DateLayout = "2006-01-02"
+
+var weekdayNames = []string{"周日", "周一", "周二", "周三", "周四", "周五", "周六"}
+
+// TimeRange 一天内的时段，左闭右开，结束时间可为24:00
+type TimeRange struct {
+	Start string `json:"start"` // HH:MM
+	End   string `json:"end"`   // HH:MM
+}
+
+// Window 每周重复的生效时段
+type Window struct {
+	Weekdays []int `json:"weekdays"` // 0=周日 ... 6=周六（7也表示周日），为空表示每天
+	TimeRange
+}
+
+// Exception 节假日等例外日期，当天以例外时段为准，覆盖每周时段
+type Exception struct {
+	Date    string      `json:"date"`    // YYYY-MM-DD
+	Windows []TimeRange `json:"windows"` // 为空表示当天全天不生效
+}
+
+// Schedule 每周重复的生效时段及例外日期
+//
+// Windows 为空时每周模式视为全天生效，此时可只配置例外日期（如节假日停用）。
+// 跨零点的时段需拆分为两段，如周五 18:00-24:00 和周六 00:00-02:00。
+type Schedule struct {
+	Windows    []Window    `json:"windows"`
+	Exceptions []Exception `json:"exceptions,omitempty"`
+}
+
+// Parse 解析JSON格式的时段配置，空值返回nil
+func Parse(value string) (*Schedule, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "null" {
+		return nil, nil
+	}
+
+	var s Schedule
+	if err := json.Unmarshal([]byte(value), &s); err != nil {
+		return nil, fmt.Errorf("时段配置格式错误: %v", err)
+	}
+	return &s, nil
+}
+
+// Validate 校验时段格式，并检查同一天内的时段是否重叠
+func (s *Schedule) Validate() error {
+	byWeekday := make([][]TimeRange, 7)
+	for _, w := range s.Windows {
+		if _, _, err := w.bounds(); err != nil {
+			return err
+		}
+		weekdays := w.Weekdays
+		if len(weekdays) == 0 {
+			weekdays = []int{0, 1, 2, 3, 4, 5, 6}
+		}
+		for _, day := range weekdays {
+			if day < 0 || day > 7 {
+				return fmt.Errorf("无效的星期: %d，应为0-6（0表示周日）", day)
+			}
+			byWeekday[day%7] = append(byWeekday[day%7], w.TimeRange)
+		}
+	}
+	for day, ranges := range byWeekday {
+		if err := checkOverlap(ranges); err != nil {
+			return fmt.Errorf("%s%v", weekdayNames[day], err)
+		}
+	}
+
+	dates := make(map[string]bool)
+	for _, e := range s.Exceptions {
+		if _, err := time.Parse(DateLayout, e.Date); err != nil {
+			return fmt.Errorf("无效的例外日期: %s，格式应为YYYY-MM-DD", e.Date)
+		}
+		if dates[e.Date] {
+			return fmt.Errorf("例外日期重复: %s", e.Date)
+		}
+		dates[e.Date] = true
+
+		for _, r := range e.Windows {
+			if _, _, err := r.bounds(); err != nil {
+				return err
+			}
+		}
+		if err := checkOverlap(e.Windows); err != nil {
+			return fmt.Errorf("%s%v", e.Date, err)
+		}
+	}
+
+	return nil
+}
+
+// IsActive 判断时间点是否处于生效时段，t 应已转换到活码所在时区
+func (s *Schedule) IsActive(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+
+	date := t.Format(DateLayout)
+	for _, e := range s.Exceptions {
+		if e.Date == date {
+			return containsMinute(e.Windows, minute)
+		}
+	}
+
+	if len(s.Windows) == 0 {
+		return true
+	}
+
+	weekday := int(t.Weekday())
+	for _, w := range s.Windows {
+		if !w.matchesWeekday(weekday) {
+			continue
+		}
+		if start, end, err := w.bounds(); err == nil && minute >= start && minute < end {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesWeekday 判断时段是否适用于指定星期
+func (w Window) matchesWeekday(weekday int) bool {
+	if len(w.Weekdays) == 0 {
+		return true
+	}
+	for _, day := range w.Weekdays {
+		if day%7 == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// bounds 返回时段的起止分钟数
+func (r TimeRange) bounds() (int, int, error) {
+	start, err := parseClock(r.Start)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := parseClock(r.End)
+	if err != nil {
+		return 0, 0, err
+	}
+	if start >= end {
+		return 0, 0, fmt.Errorf("时段 %s-%s 的开始时间必须早于结束时间，跨零点的时段请拆分为两段", r.Start, r.End)
+	}
+	return start, end, nil
+}
+
+// parseClock 解析HH:MM格式的时间为当天的分钟数
+func parseClock(value string) (int, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("无效的时间: %q，格式应为HH:MM", value)
+	}
+	hour, err1 := strconv.Atoi(parts[0])
+	minute, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || hour < 0 || minute < 0 || minute > 59 || hour > 24 || (hour == 24 && minute != 0) {
+		return 0, fmt.Errorf("无效的时间: %q，格式应为HH:MM", value)
+	}
+	return hour*60 + minute, nil
+}
+
+// checkOverlap 检查同一天内的时段是否重叠
+func checkOverlap(ranges []TimeRange) error {
+	sorted := make([]TimeRange, len(ranges))
+	copy(sorted, ranges)
+	sort.Slice(sorted, func(i, j int) bool {
+		a, _, _ := sorted[i].bounds()
+		b, _, _ := sorted[j].bounds()
+		return a < b
+	})
+
+	for i := 1; i < len(sorted); i++ {
+		_, prevEnd, _ := sorted[i-1].bounds()
+		start, _, _ := sorted[i].bounds()
+		if start < prevEnd {
+			return fmt.Errorf("时段 %s-%s 与 %s-%s 重叠", sorted[i-1].Start, sorted[i-1].End, sorted[i].Start, sorted[i].End)
+		}
+	}
+	return nil
+}
+
+// containsMinute 判断分钟数是否落在任一时段内
+func containsMinute(ranges []TimeRange, minute int) bool {
+	for _, r := range ranges {
+		if start, end, err := r.bounds(); err == nil && minute >= start && minute < end {
+			return true
+		}
+	}
+	return false
+}
+
+var locationCache sync.Map
+
+// LoadLocation 加载IANA时区并缓存，名称为空时使用服务器本地时区
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	if loc, ok := locationCache.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("无效的时区: %s", name)
+	}
+	locationCache.Store(name, loc)
+	return loc, nil
+}
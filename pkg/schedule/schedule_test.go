@@ -0,0 +1,226 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s unavailable: %v", name, err)
+	}
+	return loc
+}
+
+func TestIsActiveWeeklyWindows(t *testing.T) {
+	loc := mustLoad(t, "Asia/Shanghai")
+	s := &Schedule{Windows: []Window{
+		{Weekdays: []int{1, 2, 3, 4, 5}, TimeRange: TimeRange{"09:00", "18:00"}},
+		{Weekdays: []int{6, 7}, TimeRange: TimeRange{"10:00", "24:00"}},
+	}}
+	if err := s.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		time time.Time
+		want bool
+	}{
+		{"monday start inclusive", time.Date(2026, 10, 12, 9, 0, 0, 0, loc), true},
+		{"monday end exclusive", time.Date(2026, 10, 12, 18, 0, 0, 0, loc), false},
+		{"monday before start", time.Date(2026, 10, 12, 8, 59, 0, 0, loc), false},
+		{"saturday evening", time.Date(2026, 10, 17, 23, 59, 0, 0, loc), true},
+		{"sunday as 7", time.Date(2026, 10, 18, 10, 0, 0, 0, loc), true},
+		{"sunday morning", time.Date(2026, 10, 18, 9, 0, 0, 0, loc), false},
+	}
+	for _, tt := range tests {
+		if got := s.IsActive(tt.time); got != tt.want {
+			t.Errorf("%s: IsActive(%s) = %v, want %v", tt.name, tt.time, got, tt.want)
+		}
+	}
+}
+
+func TestIsActiveAcrossMidnight(t *testing.T) {
+	// 跨零点的时段不能写成一段，须拆分到两天
+	crossing := &Schedule{Windows: []Window{{Weekdays: []int{5}, TimeRange: TimeRange{"22:00", "02:00"}}}}
+	if err := crossing.Validate(); err == nil {
+		t.Error("Validate should reject a window that crosses midnight")
+	}
+
+	loc := mustLoad(t, "Asia/Shanghai")
+	s := &Schedule{Windows: []Window{
+		{Weekdays: []int{5}, TimeRange: TimeRange{"22:00", "24:00"}},
+		{Weekdays: []int{6}, TimeRange: TimeRange{"00:00", "02:00"}},
+	}}
+	if err := s.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	tests := []struct {
+		time time.Time
+		want bool
+	}{
+		{time.Date(2026, 10, 16, 21, 59, 0, 0, loc), false},
+		{time.Date(2026, 10, 16, 23, 59, 0, 0, loc), true},
+		{time.Date(2026, 10, 17, 0, 0, 0, 0, loc), true},
+		{time.Date(2026, 10, 17, 1, 59, 0, 0, loc), true},
+		{time.Date(2026, 10, 17, 2, 0, 0, 0, loc), false},
+		// 周六晚上不属于周五的时段
+		{time.Date(2026, 10, 17, 23, 0, 0, 0, loc), false},
+	}
+	for _, tt := range tests {
+		if got := s.IsActive(tt.time); got != tt.want {
+			t.Errorf("IsActive(%s) = %v, want %v", tt.time.Format("Mon 15:04"), got, tt.want)
+		}
+	}
+}
+
+func TestIsActiveExceptionDates(t *testing.T) {
+	loc := mustLoad(t, "Asia/Shanghai")
+	s := &Schedule{
+		Windows: []Window{{Weekdays: []int{1, 2, 3, 4, 5}, TimeRange: TimeRange{"09:00", "18:00"}}},
+		Exceptions: []Exception{
+			{Date: "2026-10-01"},
+			{Date: "2026-10-10", Windows: []TimeRange{{"10:00", "12:00"}}},
+		},
+	}
+	if err := s.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		time time.Time
+		want bool
+	}{
+		{"holiday closes a weekday", time.Date(2026, 10, 1, 10, 0, 0, 0, loc), false},
+		{"make-up day opens a saturday", time.Date(2026, 10, 10, 11, 0, 0, 0, loc), true},
+		{"make-up day uses its own hours", time.Date(2026, 10, 10, 15, 0, 0, 0, loc), false},
+		{"regular weekday", time.Date(2026, 10, 2, 10, 0, 0, 0, loc), true},
+	}
+	for _, tt := range tests {
+		if got := s.IsActive(tt.time); got != tt.want {
+			t.Errorf("%s: IsActive = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// 只配置例外日期时其余时间全天生效
+	holidays := &Schedule{Exceptions: []Exception{{Date: "2026-10-01"}}}
+	if !holidays.IsActive(time.Date(2026, 10, 2, 3, 0, 0, 0, loc)) {
+		t.Error("schedule with only exceptions should be active on other days")
+	}
+	if holidays.IsActive(time.Date(2026, 10, 1, 3, 0, 0, 0, loc)) {
+		t.Error("exception without windows should be inactive all day")
+	}
+}
+
+func TestIsActiveExceptionDateUsesLocalDate(t *testing.T) {
+	// 例外日期按活码时区的日期匹配，而不是UTC日期
+	loc := mustLoad(t, "Asia/Shanghai")
+	s := &Schedule{Exceptions: []Exception{{Date: "2026-10-01"}}}
+	utc := time.Date(2026, 9, 30, 17, 0, 0, 0, time.UTC) // 上海时间10月1日01:00
+	if s.IsActive(utc.In(loc)) {
+		t.Error("exception date should match the local date")
+	}
+	if !s.IsActive(utc) {
+		t.Error("the same instant in UTC is still September 30")
+	}
+}
+
+func TestIsActiveDST(t *testing.T) {
+	loc := mustLoad(t, "America/New_York")
+	s := &Schedule{Windows: []Window{{TimeRange: TimeRange{"09:00", "17:00"}}}}
+
+	// 2026-03-08 02:00 夏令时开始，前后两天的同一本地时段对应不同的UTC时间
+	before := time.Date(2026, 3, 7, 14, 30, 0, 0, time.UTC) // EST 09:30
+	after := time.Date(2026, 3, 9, 13, 30, 0, 0, time.UTC)  // EDT 09:30
+	if !s.IsActive(before.In(loc)) || !s.IsActive(after.In(loc)) {
+		t.Error("09:30 local time should be active on both sides of the DST change")
+	}
+	if s.IsActive(time.Date(2026, 3, 9, 12, 30, 0, 0, time.UTC).In(loc)) {
+		t.Error("08:30 EDT should be inactive")
+	}
+
+	// 跳过的一小时内不存在的本地时间被规范化为03:xx
+	night := &Schedule{Windows: []Window{{TimeRange: TimeRange{"02:00", "03:00"}}}}
+	if night.IsActive(time.Date(2026, 3, 8, 2, 30, 0, 0, loc)) {
+		t.Error("02:30 does not exist on the DST start day and should not be active")
+	}
+
+	// 2026-11-01 夏令时结束，01:00-02:00 出现两次，两次都在时段内
+	repeated := &Schedule{Windows: []Window{{TimeRange: TimeRange{"01:00", "02:00"}}}}
+	for _, instant := range []time.Time{
+		time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC), // EDT 01:30
+		time.Date(2026, 11, 1, 6, 30, 0, 0, time.UTC), // EST 01:30
+	} {
+		if !repeated.IsActive(instant.In(loc)) {
+			t.Errorf("%s should be active during the repeated hour", instant.In(loc))
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule Schedule
+		valid    bool
+	}{
+		{"empty", Schedule{}, true},
+		{"end of day", Schedule{Windows: []Window{{TimeRange: TimeRange{"18:00", "24:00"}}}}, true},
+		{"overlap on shared weekday", Schedule{Windows: []Window{
+			{Weekdays: []int{1, 2}, TimeRange: TimeRange{"09:00", "12:00"}},
+			{Weekdays: []int{2, 3}, TimeRange: TimeRange{"11:00", "13:00"}},
+		}}, false},
+		{"sunday as 0 and 7 overlap", Schedule{Windows: []Window{
+			{Weekdays: []int{0}, TimeRange: TimeRange{"09:00", "12:00"}},
+			{Weekdays: []int{7}, TimeRange: TimeRange{"10:00", "11:00"}},
+		}}, false},
+		{"adjacent windows", Schedule{Windows: []Window{
+			{TimeRange: TimeRange{"09:00", "12:00"}},
+			{TimeRange: TimeRange{"12:00", "13:00"}},
+		}}, true},
+		{"invalid weekday", Schedule{Windows: []Window{{Weekdays: []int{8}, TimeRange: TimeRange{"09:00", "12:00"}}}}, false},
+		{"invalid clock", Schedule{Windows: []Window{{TimeRange: TimeRange{"9", "12:00"}}}}, false},
+		{"past 24:00", Schedule{Windows: []Window{{TimeRange: TimeRange{"09:00", "24:30"}}}}, false},
+		{"invalid exception date", Schedule{Exceptions: []Exception{{Date: "2026/10/01"}}}, false},
+		{"duplicate exception date", Schedule{Exceptions: []Exception{{Date: "2026-10-01"}, {Date: "2026-10-01"}}}, false},
+		{"overlapping exception windows", Schedule{Exceptions: []Exception{{Date: "2026-10-01", Windows: []TimeRange{
+			{"09:00", "12:00"}, {"11:00", "13:00"},
+		}}}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.schedule.Validate()
+			if (err == nil) != tt.valid {
+				t.Errorf("Validate() error = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, value := range []string{"", " ", "null"} {
+		if s, err := Parse(value); s != nil || err != nil {
+			t.Errorf("Parse(%q) = %v, %v, want nil", value, s, err)
+		}
+	}
+	if _, err := Parse("{"); err == nil {
+		t.Error("Parse should reject malformed JSON")
+	}
+	s, err := Parse(`{"windows":[{"weekdays":[1],"start":"09:00","end":"18:00"}]}`)
+	if err != nil || len(s.Windows) != 1 || s.Windows[0].Start != "09:00" {
+		t.Errorf("Parse = %+v, %v", s, err)
+	}
+}
+
+func TestLoadLocation(t *testing.T) {
+	if loc, err := LoadLocation(""); err != nil || loc != time.Local {
+		t.Errorf("LoadLocation(\"\") = %v, %v, want time.Local", loc, err)
+	}
+	if _, err := LoadLocation("Mars/Olympus"); err == nil {
+		t.Error("LoadLocation should reject unknown time zones")
+	}
+}
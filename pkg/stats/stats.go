@@ -0,0 +1,47 @@
+// Package stats 提供A/B实验使用的转化率统计方法
+package stats
+
+import (
+	"math"
+)
+
+// ZScore 返回双侧置信水平对应的正态分布分位数，如 0.95 → 1.96
+func ZScore(confidence float64) float64 {
+	return math.Sqrt2 * math.Erfinv(confidence)
+}
+
+// WilsonInterval 计算转化率的Wilson置信区间，样本量为0时返回 [0, 1]
+//
+// 相比正态近似，Wilson区间在转化率接近0或1、样本量较小时仍然可靠。
+func WilsonInterval(successes, trials int64, confidence float64) (low, high float64) {
+	if trials <= 0 {
+		return 0, 1
+	}
+	z := ZScore(confidence)
+	n := float64(trials)
+	p := float64(successes) / n
+
+	denominator := 1 + z*z/n
+	center := (p + z*z/(2*n)) / denominator
+	margin := z * math.Sqrt(p*(1-p)/n+z*z/(4*n*n)) / denominator
+	return math.Max(0, center-margin), math.Min(1, center+margin)
+}
+
+// TwoProportionPValue 两比例z检验的双侧p值，用于判断两个转化率的差异是否显著
+//
+// 任一组样本量为0或合并转化率为0/1（无法计算方差）时返回1。
+func TwoProportionPValue(successesA, trialsA, successesB, trialsB int64) float64 {
+	if trialsA <= 0 || trialsB <= 0 {
+		return 1
+	}
+	nA, nB := float64(trialsA), float64(trialsB)
+	pA, pB := float64(successesA)/nA, float64(successesB)/nB
+	pooled := float64(successesA+successesB) / (nA + nB)
+
+	se := math.Sqrt(pooled * (1 - pooled) * (1/nA + 1/nB))
+	if se == 0 {
+		return 1
+	}
+	z := (pA - pB) / se
+	return math.Erfc(math.Abs(z) / math.Sqrt2)
+}
@@ -0,0 +1,88 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+const epsilon = 1e-6
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < epsilon
+}
+
+func TestZScore(t *testing.T) {
+	tests := []struct {
+		confidence float64
+		want       float64
+	}{
+		{0.95, 1.959964},
+		{0.99, 2.575829},
+		{0.5, 0.674490},
+	}
+	for _, tt := range tests {
+		if got := ZScore(tt.confidence); !almostEqual(got, tt.want) {
+			t.Errorf("ZScore(%v) = %v, want %v", tt.confidence, got, tt.want)
+		}
+	}
+}
+
+func TestWilsonInterval(t *testing.T) {
+	tests := []struct {
+		successes, trials int64
+		confidence        float64
+		low, high         float64
+	}{
+		// 样本量为0时不确定
+		{0, 0, 0.95, 0, 1},
+		// 转化率为0或1时区间仍有宽度，且不越界
+		{0, 10, 0.95, 0, 0.277533},
+		{10, 10, 0.95, 0.722467, 1},
+		{50, 100, 0.95, 0.403832, 0.596168},
+		{5, 20, 0.9, 0.127377, 0.432202},
+	}
+	for _, tt := range tests {
+		low, high := WilsonInterval(tt.successes, tt.trials, tt.confidence)
+		if !almostEqual(low, tt.low) || !almostEqual(high, tt.high) {
+			t.Errorf("WilsonInterval(%d, %d, %v) = [%v, %v], want [%v, %v]",
+				tt.successes, tt.trials, tt.confidence, low, high, tt.low, tt.high)
+		}
+	}
+}
+
+func TestWilsonIntervalNarrowsWithSamples(t *testing.T) {
+	low1, high1 := WilsonInterval(10, 100, 0.95)
+	low2, high2 := WilsonInterval(100, 1000, 0.95)
+	if high2-low2 >= high1-low1 {
+		t.Errorf("interval did not narrow: [%v, %v] vs [%v, %v]", low1, high1, low2, high2)
+	}
+	lowHigh, highHigh := WilsonInterval(10, 100, 0.99)
+	if lowHigh >= low1 || highHigh <= high1 {
+		t.Errorf("higher confidence should widen the interval: [%v, %v] vs [%v, %v]", low1, high1, lowHigh, highHigh)
+	}
+}
+
+func TestTwoProportionPValue(t *testing.T) {
+	tests := []struct {
+		name                                     string
+		successesA, trialsA, successesB, trialsB int64
+		want                                     float64
+	}{
+		{"same rate", 30, 100, 30, 100, 1},
+		{"borderline", 10, 100, 20, 100, 0.047670},
+		{"significant", 200, 1000, 250, 1000, 0.007420},
+		{"empty group", 10, 100, 0, 0, 1},
+		{"no conversions", 0, 100, 0, 100, 1},
+		{"all converted", 100, 100, 50, 50, 1},
+	}
+	for _, tt := range tests {
+		got := TwoProportionPValue(tt.successesA, tt.trialsA, tt.successesB, tt.trialsB)
+		if !almostEqual(got, tt.want) {
+			t.Errorf("%s: TwoProportionPValue = %v, want %v", tt.name, got, tt.want)
+		}
+		// 两组交换顺序结果相同
+		if swapped := TwoProportionPValue(tt.successesB, tt.trialsB, tt.successesA, tt.trialsA); !almostEqual(swapped, got) {
+			t.Errorf("%s: p-value not symmetric: %v vs %v", tt.name, got, swapped)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package useragent
+
+import (
+	"regexp"
+	"strings"
+)
+
+// 操作系统
+const (
+	OSiOS       = "iOS"
+	OSAndroid   = "Android"
+	OSHarmonyOS = "HarmonyOS"
+	OSWindows   = "Windows"
+	OSMacOS     = "macOS"
+	OSLinux     = "Linux"
+	OSOther     = "Other"
+)
+
+// 浏览器
+const (
+	BrowserChrome  = "Chrome"
+	BrowserSafari  = "Safari"
+	BrowserFirefox = "Firefox"
+	BrowserEdge    = "Edge"
+	BrowserOpera   = "Opera"
+	BrowserQQ      = "QQ Browser"
+	BrowserUC      = "UC Browser"
+	BrowserHuawei  = "Huawei Browser"
+	BrowserMIUI    = "MIUI Browser"
+	BrowserSamsung = "Samsung Browser"
+	BrowserWebView = "WebView" // App内置浏览器且UA中无浏览器标识
+	BrowserOther   = "Other"
+)
+
+// Info User-Agent解析结果
+type Info struct {
+	OS            string `json:"os"`
+	OSVersion     string `json:"os_version"`
+	Browser       string `json:"browser"`
+	Client        string `json:"client"` // App内置浏览器，见 Client*
+	ClientVersion string `json:"client_version"`
+}
+
+var (
+	harmonyVersionPattern = regexp.MustCompile(`(?i)(?:HarmonyOS|OpenHarmony)[ /]?([\d.]+)`)
+	iosVersionPattern     = regexp.MustCompile(`(?i)OS (\d+(?:_\d+)*) like Mac OS X`)
+	androidVersionPattern = regexp.MustCompile(`(?i)Android[ /]?([\d.]+)`)
+	windowsVersionPattern = regexp.MustCompile(`(?i)Windows NT ([\d.]+)`)
+	macVersionPattern     = regexp.MustCompile(`(?i)Mac OS X (\d+(?:[_.]\d+)*)`)
+)
+
+// windowsVersions Windows NT内核版本对应的系统版本（Windows 11同样为NT 10.0）
+var windowsVersions = map[string]string{
+	"10.0": "10",
+	"6.3":  "8.1",
+	"6.2":  "8",
+	"6.1":  "7",
+	"6.0":  "Vista",
+	"5.1":  "XP",
+}
+
+// browserRules 浏览器识别规则，按顺序匹配（Edge、Opera等的UA中同时包含Chrome/Safari）
+var browserRules = []struct {
+	token   string
+	browser string
+}{
+	{"edg/", BrowserEdge},
+	{"edge/", BrowserEdge},
+	{"edga/", BrowserEdge},
+	{"edgios/", BrowserEdge},
+	{"opr/", BrowserOpera},
+	{"opera", BrowserOpera},
+	{"mqqbrowser", BrowserQQ},
+	{"ucbrowser", BrowserUC},
+	{"huaweibrowser", BrowserHuawei},
+	{"miuibrowser", BrowserMIUI},
+	{"samsungbrowser", BrowserSamsung},
+	{"firefox", BrowserFirefox},
+	{"fxios", BrowserFirefox},
+	{"crios", BrowserChrome},
+	{"chrome", BrowserChrome},
+	{"safari", BrowserSafari},
+}
+
+// clientVersionPatterns 各App内置浏览器的版本号
+var clientVersionPatterns = map[string]*regexp.Regexp{
+	ClientWeChat: regexp.MustCompile(`(?i)MicroMessenger/([\d.]+)`),
+	ClientWeCom:  regexp.MustCompile(`(?i)wxwork/([\d.]+)`),
+	ClientAlipay: regexp.MustCompile(`(?i)AlipayClient/([\d.]+)`),
+	ClientQQ:     regexp.MustCompile(` QQ/([\d.]+)`),
+	ClientDouyin: regexp.MustCompile(`(?i)app_version/([\d.]+)`),
+}
+
+// Parse 解析User-Agent中的操作系统、浏览器及App内置浏览器信息，空的User-Agent归为其他
+func Parse(userAgent string) Info {
+	if strings.TrimSpace(userAgent) == "" {
+		return Info{OS: OSOther, Browser: BrowserOther, Client: ClientOther}
+	}
+
+	var info Info
+	info.OS, info.OSVersion = parseOS(userAgent)
+	info.Client = DetectClient(userAgent)
+	if pattern, ok := clientVersionPatterns[info.Client]; ok {
+		info.ClientVersion = firstMatch(pattern, userAgent)
+	}
+	info.Browser = parseBrowser(userAgent, info.Client)
+	return info
+}
+
+// parseOS 识别操作系统及版本
+func parseOS(userAgent string) (string, string) {
+	ua := strings.ToLower(userAgent)
+	switch {
+	// 鸿蒙的UA可能同时包含Android，需先判断
+	case strings.Contains(ua, "harmonyos") || strings.Contains(ua, "openharmony"):
+		return OSHarmonyOS, firstMatch(harmonyVersionPattern, userAgent)
+	case strings.Contains(ua, "iphone") || strings.Contains(ua, "ipad") || strings.Contains(ua, "ipod"):
+		return OSiOS, strings.ReplaceAll(firstMatch(iosVersionPattern, userAgent), "_", ".")
+	case strings.Contains(ua, "android"):
+		return OSAndroid, firstMatch(androidVersionPattern, userAgent)
+	case strings.Contains(ua, "windows"):
+		version := firstMatch(windowsVersionPattern, userAgent)
+		if name, ok := windowsVersions[version]; ok {
+			version = name
+		}
+		return OSWindows, version
+	case strings.Contains(ua, "macintosh") || strings.Contains(ua, "mac os x"):
+		return OSMacOS, strings.ReplaceAll(firstMatch(macVersionPattern, userAgent), "_", ".")
+	case strings.Contains(ua, "linux"):
+		return OSLinux, ""
+	}
+	return OSOther, ""
+}
+
+// parseBrowser 识别浏览器，App内置浏览器无浏览器标识时返回WebView
+func parseBrowser(userAgent, client string) string {
+	ua := strings.ToLower(userAgent)
+	for _, rule := range browserRules {
+		if strings.Contains(ua, rule.token) {
+			return rule.browser
+		}
+	}
+	if client != ClientOther {
+		return BrowserWebView
+	}
+	return BrowserOther
+}
+
+// firstMatch 返回正则第一个分组的匹配结果
+func firstMatch(pattern *regexp.Regexp, s string) string {
+	if m := pattern.FindStringSubmatch(s); len(m) > 1 {
+		return strings.TrimSuffix(m[1], ".")
+	}
+	return ""
+}
@@ -0,0 +1,57 @@
+package useragent
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		want      Info
+	}{
+		{
+			"wechat ios",
+			"Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 MicroMessenger/8.0.44(0x18002c2f) NetType/WIFI Language/zh_CN",
+			Info{OS: OSiOS, OSVersion: "17.1", Browser: BrowserWebView, Client: ClientWeChat, ClientVersion: "8.0.44"},
+		},
+		{
+			"wecom android",
+			"Mozilla/5.0 (Linux; Android 13; V2227A) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/107.0.5304.141 Mobile Safari/537.36 wxwork/4.1.16 MicroMessenger/7.0.1 NetType/WIFI Language/zh",
+			Info{OS: OSAndroid, OSVersion: "13", Browser: BrowserChrome, Client: ClientWeCom, ClientVersion: "4.1.16"},
+		},
+		{
+			"qq",
+			"Mozilla/5.0 (iPhone; CPU iPhone OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 QQ/8.9.80.613 V1_IPH_SQ_8.9.80_1_APP_A",
+			Info{OS: OSiOS, OSVersion: "16.6", Browser: BrowserWebView, Client: ClientQQ, ClientVersion: "8.9.80.613"},
+		},
+		{
+			"alipay",
+			"Mozilla/5.0 (Linux; U; Android 12; zh-CN; M2012K11AC Build/SKQ1.211006.001) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/69.0.3497.100 Mobile Safari/537.36 AliApp(AP/10.5.36.8000) AlipayClient/10.5.36.8000 Language/zh-Hans",
+			Info{OS: OSAndroid, OSVersion: "12", Browser: BrowserChrome, Client: ClientAlipay, ClientVersion: "10.5.36.8000"},
+		},
+		{
+			"harmonyos",
+			"Mozilla/5.0 (Linux; Android 10; HarmonyOS; NOH-AN00; HMSCore 6.11.0.302) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/99.0.4844.88 HuaweiBrowser/14.0.0.322 Mobile Safari/537.36",
+			Info{OS: OSHarmonyOS, Browser: BrowserHuawei, Client: ClientOther},
+		},
+		{
+			"windows edge",
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Safari/537.36 Edg/119.0.2151.58",
+			Info{OS: OSWindows, OSVersion: "10", Browser: BrowserEdge, Client: ClientOther},
+		},
+		{
+			"macos safari",
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Safari/605.1.15",
+			Info{OS: OSMacOS, OSVersion: "10.15.7", Browser: BrowserSafari, Client: ClientOther},
+		},
+		{
+			"empty",
+			"",
+			Info{OS: OSOther, Browser: BrowserOther, Client: ClientOther},
+		},
+	}
+	for _, tt := range tests {
+		if got := Parse(tt.userAgent); got != tt.want {
+			t.Errorf("Parse(%s) = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
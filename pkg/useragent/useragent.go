@@ -0,0 +1,48 @@
+package useragent
+
+import (
+	"strings"
+)
+
+// App内置浏览器（客户端）
+const (
+	ClientWeChat = "wechat" // 微信
+	ClientWeCom  = "wecom"  // 企业微信
+	ClientAlipay = "alipay" // 支付宝
+	ClientQQ     = "qq"     // 手机QQ
+	ClientDouyin = "douyin" // 抖音
+	ClientOther  = "other"  // 系统浏览器或其他App
+)
+
+// Clients 所有可识别的客户端
+var Clients = []string{ClientWeChat, ClientWeCom, ClientAlipay, ClientQQ, ClientDouyin, ClientOther}
+
+// IsClient 判断是否为可识别的客户端名称
+func IsClient(name string) bool {
+	for _, client := range Clients {
+		if client == name {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectClient 根据User-Agent识别App内置浏览器
+func DetectClient(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	switch {
+	// 企业微信的UA同时包含MicroMessenger，需先判断
+	case strings.Contains(ua, "wxwork"):
+		return ClientWeCom
+	case strings.Contains(ua, "micromessenger"):
+		return ClientWeChat
+	case strings.Contains(ua, "alipayclient") || strings.Contains(ua, "aliapp(ap"):
+		return ClientAlipay
+	// 手机QQ为" QQ/版本号"，QQ浏览器为"MQQBrowser/"，不属于App内置浏览器
+	case strings.Contains(userAgent, " QQ/"):
+		return ClientQQ
+	case strings.Contains(ua, "aweme") || strings.Contains(ua, "douyin"):
+		return ClientDouyin
+	}
+	return ClientOther
+}
@@ -0,0 +1,28 @@
+package useragent
+
+import "testing"
+
+func TestDetectClient(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		want      string
+	}{
+		{"wechat", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 MicroMessenger/8.0.44(0x18002c2f) NetType/WIFI Language/zh_CN", ClientWeChat},
+		{"wecom", "Mozilla/5.0 (Linux; Android 13; V2227A) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/107.0.5304.141 Mobile Safari/537.36 wxwork/4.1.16 MicroMessenger/7.0.1 NetType/WIFI Language/zh", ClientWeCom},
+		{"qq", "Mozilla/5.0 (iPhone; CPU iPhone OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 QQ/8.9.80.613 V1_IPH_SQ_8.9.80_1_APP_A Pixel/1170 SimpleUISwitch/0 QQTheme/1000 StudyMode/0 CurrentMode/0 CurrentFontScale/1.000000 GeoLocation/0 QBWebViewType/1 WKType/1", ClientQQ},
+		{"qq browser", "Mozilla/5.0 (Linux; U; Android 12; zh-cn; PFDM00 Build/SP1A.210812.016) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/89.0.4389.72 MQQBrowser/13.8 Mobile Safari/537.36", ClientOther},
+		{"alipay", "Mozilla/5.0 (Linux; U; Android 12; zh-CN; M2012K11AC Build/SKQ1.211006.001) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/69.0.3497.100 UWS/3.22.2.59 Mobile Safari/537.36 AlipayChannelId/5136 NebulaSDK/1.8.100112 Nebula AlipayDefined(nt:WIFI,ws:393|0|2.75) AliApp(AP/10.5.36.8000) AlipayClient/10.5.36.8000 Language/zh-Hans", ClientAlipay},
+		{"douyin", "Mozilla/5.0 (iPhone; CPU iPhone OS 16_3 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 aweme_27.2.0 JsSdk/2.0 NetType/WIFI Channel/App Store ByteLocale/zh Region/CN app_version/27.2.0", ClientDouyin},
+		// 钉钉和微博不是单独识别的客户端
+		{"dingtalk", "Mozilla/5.0 (Linux; U; Android 11; zh-CN; M2011K2C Build/RKQ1.200928.002) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/69.0.3497.100 UWS/3.22.2.9 Mobile Safari/537.36 AliApp(DingTalk/7.0.10) com.alibaba.android.rimet/29227421 Channel/700159 language/zh-CN abi/64 Hmos/1", ClientOther},
+		{"weibo", "Mozilla/5.0 (iPhone; CPU iPhone OS 15_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 Weibo (iPhone13,2__weibo__12.5.0__iphone__os15.4)", ClientOther},
+		{"browser", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Safari/537.36", ClientOther},
+		{"empty", "", ClientOther},
+	}
+	for _, tt := range tests {
+		if got := DetectClient(tt.userAgent); got != tt.want {
+			t.Errorf("DetectClient(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -238,6 +238,9 @@ function showMainApp() {
         const userData = JSON.parse(user);
         document.getElementById('currentUser').textContent = userData.username || '管理员';
     }
+    
+    // 加载切换规则选项
+    loadSwitchStrategies();
 }
 
 // 退出登录
@@ -613,6 +616,34 @@ async function loadActiveQROptions() {
     }
 }
 
+// 从服务端加载可用的切换规则到下拉列表
+async function loadSwitchStrategies() {
+    try {
+        const response = await apiRequest('/active-qrcodes/strategies');
+        const strategies = response.data || [];
+        
+        ['switchRule', 'editSwitchRule'].forEach(id => {
+            const select = document.getElementById(id);
+            if (!select) return;
+            
+            const currentValue = select.value;
+            select.innerHTML = '';
+            strategies.forEach(strategy => {
+                const option = document.createElement('option');
+                option.value = strategy.name;
+                option.textContent = strategy.label || strategy.name;
+                option.title = strategy.description || '';
+                select.appendChild(option);
+            });
+            
+            // 保留已选中的值，新建时默认按权重分配
+            select.value = currentValue || 'weight';
+        });
+    } catch (error) {
+        console.error('Failed to load switch strategies:', error);
+    }
+}
+
 // 创建静态码
 async function createStaticQR() {
     const activeQRId = document.getElementById('staticQRActiveQRId').value;